@@ -2,74 +2,489 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/razedwell/go-hand/internal/config"
+	"github.com/razedwell/go-hand/internal/event"
+	"github.com/razedwell/go-hand/internal/model"
 	"github.com/razedwell/go-hand/internal/platform/cache"
 	"github.com/razedwell/go-hand/internal/platform/db"
+	"github.com/razedwell/go-hand/internal/platform/health"
+	"github.com/razedwell/go-hand/internal/platform/lifecycle"
 	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/platform/mail"
+	"github.com/razedwell/go-hand/internal/platform/metrics"
+	"github.com/razedwell/go-hand/internal/platform/sms"
+	"github.com/razedwell/go-hand/internal/platform/tracing"
 	"github.com/razedwell/go-hand/internal/postgres"
+	orgrepo "github.com/razedwell/go-hand/internal/repository/organization"
+	"github.com/razedwell/go-hand/internal/repository/token"
+	userrepo "github.com/razedwell/go-hand/internal/repository/user"
 	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/service/audit"
 	authsrvc "github.com/razedwell/go-hand/internal/service/auth"
+	"github.com/razedwell/go-hand/internal/service/invitation"
+	"github.com/razedwell/go-hand/internal/service/organization"
+	"github.com/razedwell/go-hand/internal/service/passwordless"
+	"github.com/razedwell/go-hand/internal/service/phone"
+	"github.com/razedwell/go-hand/internal/service/scim"
+	"github.com/razedwell/go-hand/internal/service/sso"
 	"github.com/razedwell/go-hand/internal/service/user"
+	"github.com/razedwell/go-hand/internal/service/webhook"
 	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	adminhandler "github.com/razedwell/go-hand/internal/transport/http/handler/admin"
 	"github.com/razedwell/go-hand/internal/transport/http/handler/auth"
+	healthhandler "github.com/razedwell/go-hand/internal/transport/http/handler/health"
+	invitationhandler "github.com/razedwell/go-hand/internal/transport/http/handler/invitation"
+	metricshandler "github.com/razedwell/go-hand/internal/transport/http/handler/metrics"
+	orghandler "github.com/razedwell/go-hand/internal/transport/http/handler/organization"
+	passwordlesshandler "github.com/razedwell/go-hand/internal/transport/http/handler/passwordless"
+	phonehandler "github.com/razedwell/go-hand/internal/transport/http/handler/phone"
+	scimhandler "github.com/razedwell/go-hand/internal/transport/http/handler/scim"
+	ssohandler "github.com/razedwell/go-hand/internal/transport/http/handler/sso"
+	webhookhandler "github.com/razedwell/go-hand/internal/transport/http/handler/webhook"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
 	"github.com/razedwell/go-hand/internal/transport/http/middleware"
-	"github.com/redis/go-redis/v9"
 )
 
 func main() {
-	logger.Init()
-	cfg := config.LoadConfig()
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
+	}
+
+	loader := config.NewLoader()
+	storage := flag.String("storage", "postgres", "storage backend: postgres, or memory to run without Postgres and Redis")
+	listRoutes := flag.Bool("routes", false, "print the registered routes and exit")
+	loader.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := loader.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%s\n", err)
+		os.Exit(1)
+	}
+	if err := logger.Init(logger.Config{Level: cfg.LogLevel, Format: cfg.LogFormat}); err != nil {
+		logger.Fatal("Invalid logging configuration", "error", err)
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Exporter:    cfg.TracingExporter,
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+		ServiceName: cfg.TracingServiceName,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", "error", err)
+	}
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	dbUrl := db.BuildDBUrl(cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName, cfg.DBSSLMode, cfg.Timezone)
-	db, err := db.NewClient(dbUrl)
+	app := lifecycle.New(time.Second * time.Duration(cfg.ShutdownTimeoutSeconds))
+	app.Append(lifecycle.Hook{Name: "tracing", Stop: shutdownTracing})
+	checker := health.NewChecker(time.Second * time.Duration(cfg.HealthCheckTimeoutSeconds))
+
+	var registrars []transporthttp.RouteRegistrar
+	switch *storage {
+	case "postgres":
+		registrars = buildPostgres(ctx, cfg, app, checker)
+	case "memory":
+		registrars = buildMemory(cfg)
+	default:
+		logger.Fatal("Unknown storage backend", "storage", *storage)
+	}
+
+	registrars = append(registrars, healthhandler.NewHandler(checker))
+	if cfg.MetricsEnabled {
+		registrars = append(registrars, metricsRoutes(cfg))
+	}
+
+	router := transporthttp.NewRouter(registrars...)
+	if *listRoutes {
+		printRoutes(router)
+		return
+	}
+
+	server := transporthttp.NewServer(transporthttp.ServerConfig{
+		Addr:              ":" + cfg.Port,
+		ReadTimeout:       time.Second * time.Duration(cfg.HTTPReadTimeoutSeconds),
+		ReadHeaderTimeout: time.Second * time.Duration(cfg.HTTPReadHeaderTimeoutSeconds),
+		WriteTimeout:      time.Second * time.Duration(cfg.HTTPWriteTimeoutSeconds),
+		IdleTimeout:       time.Second * time.Duration(cfg.HTTPIdleTimeoutSeconds),
+	}, router, serverMiddleware(cfg))
+	app.Append(serverHook(server, stop, time.Second*time.Duration(cfg.ShutdownHTTPTimeoutSeconds)))
+
+	if err := app.Start(ctx); err != nil {
+		logger.Fatal("Failed to start", "error", err)
+	}
+	logger.Log.Info("Server started", "addr", server.Addr)
+
+	<-ctx.Done()
+	logger.Log.Info("Graceful shutdown initiated")
+
+	// Fail readiness first so load balancers stop routing here while the
+	// server still accepts the requests already on their way.
+	checker.SetReady(false)
+	time.Sleep(time.Second * time.Duration(cfg.ShutdownReadinessDelaySeconds))
+
+	if err := app.Stop(context.Background()); err != nil {
+		logger.Fatal("Shutdown incomplete", "error", err)
+	}
+	logger.Log.Info("Server exited properly")
+}
+
+// configCommand implements "config print [-redacted] [flags]", which shows
+// the configuration the server would run with and exits 1 if it is invalid.
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: api config print [-redacted] [flags]")
+		return 2
+	}
+	fs := flag.NewFlagSet("config print", flag.ExitOnError)
+	redact := fs.Bool("redacted", false, "mask secrets")
+	loader := config.NewLoader()
+	loader.RegisterFlags(fs)
+	fs.Parse(args[1:])
+
+	cfg, err := loader.Load()
+	if err := cfg.Print(os.Stdout, *redact); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
 	if err != nil {
-		logger.Log.Printf("Failed to connect to database: %s", err)
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serverHook listens when started and drains in-flight requests when
+// stopped, closing the remaining connections if that takes longer than
+// drainTimeout. A server that fails while running calls fail, which starts
+// the shutdown.
+func serverHook(server *http.Server, fail func(), drainTimeout time.Duration) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: "http",
+		Start: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+					logger.Log.Error("Server error", "error", err)
+					fail()
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if err := server.Shutdown(ctx); err != nil {
+				server.Close()
+				return err
+			}
+			return nil
+		},
+		StopTimeout: drainTimeout,
+	}
+}
+
+// buildPostgres wires every feature against Postgres and Redis, adds their
+// checks to checker and their connections and background jobs to app. It
+// exits when either cannot be reached.
+func buildPostgres(ctx context.Context, cfg *config.Config, app *lifecycle.Lifecycle, checker *health.Checker) []transporthttp.RouteRegistrar {
+	retry := health.RetryConfig{
+		Attempts:   cfg.StartupConnectAttempts,
+		Backoff:    time.Second * time.Duration(cfg.StartupRetryBackoffSeconds),
+		MaxBackoff: 30 * time.Second,
 	}
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPassword,
-		DB:       int(cfg.RedisDB),
+
+	dbUrl := db.BuildDBUrl(cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName, cfg.DBSSLMode, cfg.Timezone)
+	db, err := health.Retry(ctx, "postgres", retry, func() (*sql.DB, error) {
+		return db.NewClient(dbUrl)
 	})
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	metrics.RegisterDB(db, "postgres")
+	checker.Add("postgres", db.PingContext)
+	app.Append(lifecycle.Hook{Name: "postgres", Stop: func(context.Context) error { return db.Close() }})
 
-	rdb := &cache.RedisClient{Client: redisClient}
+	rdb, err := health.Retry(ctx, "redis", retry, func() (*cache.RedisClient, error) {
+		return cache.NewRedisClient(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	})
+	if err != nil {
+		logger.Fatal("Failed to connect to redis", "error", err)
+	}
+	checker.Add("redis", rdb.Ping)
+	app.Append(lifecycle.Hook{Name: "redis", Stop: func(context.Context) error { return rdb.Client.Close() }})
+	jobsTimeout := time.Second * time.Duration(cfg.ShutdownJobsTimeoutSeconds)
 
+	txManager := postgres.NewTxManager(db, postgres.TxOptions{MaxRetries: 3, RetryDelay: 10 * time.Millisecond})
 	tokenRepo := postgres.NewTokenRepo(db)
 
-	jwtManager := security.NewJWTManager(cfg.JWTAccessSecret, cfg.JWTRefreshSecret, time.Minute*time.Duration(cfg.JWTAccessExpiryMinutes), time.Hour*time.Duration(cfg.JWTRefreshExpiryHours), tokenRepo, rdb)
-	authMW := middleware.Auth(jwtManager)
+	var revocation security.Revocation
+	switch cfg.RevocationStore {
+	case "redis":
+		revocation = rdb
+	case "postgres":
+		revocation = postgres.NewRevocationRepo(db)
+	case "memory":
+		revocation = cache.NewMemoryStore()
+	default:
+		logger.Fatal("Unknown revocation store", "store", cfg.RevocationStore)
+	}
 
 	userRepo := postgres.NewUserRepo(db)
-	userService := user.NewService(userRepo)
-	authService := authsrvc.NewService(userRepo, jwtManager)
-	authHandler := auth.NewHandler(userService, authService, authMW)
+	orgRepo := postgres.NewOrganizationRepo(db)
 
-	server := transporthttp.NewServer(":"+cfg.Port, authHandler)
+	tokens, sessionManager := buildTokens(cfg, tokenRepo, revocation, rdb, userRepo, orgRepo)
+	authMW := middleware.Auth(tokens)
+	cookies := newCookies(cfg)
+	tenantMW := middleware.Tenant(orgRepo)
+
+	var mailer mail.Sender = mail.NewLogSender()
+	if cfg.MailDriver == "smtp" {
+		mailer = mail.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
 
-	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Log.Println("Server error:", err)
+	invitationRepo := postgres.NewInvitationRepo(db)
+	invitationService := invitation.NewService(txManager, invitationRepo, orgRepo, userRepo, mailer, invitation.Config{
+		AcceptURL: cfg.InvitationURL,
+		Expiry:    time.Hour * time.Duration(cfg.InvitationExpiryHours),
+	})
+	invitationHandler := invitationhandler.NewHandler(invitationService, authMW, tenantMW)
+
+	webhookService := webhook.NewService(postgres.NewWebhookRepo(db), webhook.Config{
+		Timeout:              time.Second * time.Duration(cfg.WebhookTimeoutSeconds),
+		MaxAttempts:          cfg.WebhookMaxAttempts,
+		BaseBackoff:          time.Second * time.Duration(cfg.WebhookBaseBackoffSeconds),
+		MaxBackoff:           time.Minute * time.Duration(cfg.WebhookMaxBackoffMinutes),
+		PollInterval:         time.Second * time.Duration(cfg.WebhookPollIntervalSeconds),
+		AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+		BatchSize:            50,
+	})
+	app.Go("webhooks", jobsTimeout, webhookService.Run)
+	webhookHandler := webhookhandler.NewHandler(webhookService)
+
+	// Domain events are written to the outbox with the change that caused
+	// them and handed to these subscribers by the dispatcher.
+	outboxRepo := postgres.NewOutboxRepo(db)
+	eventBus := event.NewBus()
+	eventBus.Subscribe(model.EventAll, "webhooks", event.Idempotent(outboxRepo, "webhooks", webhookService.HandleEvent))
+	if sessionManager != nil {
+		for _, eventType := range []string{
+			model.EventUserBanned, model.EventUserDeactivated, model.EventUserDeleted,
+			model.EventUserRoleChanged, model.EventOrgMemberRoleChanged, model.EventOrgMemberRemoved,
+		} {
+			eventBus.Subscribe(eventType, "sessions", event.Idempotent(outboxRepo, "sessions", sessionManager.HandleEvent))
 		}
-	}()
+	}
+	dispatcher := event.NewDispatcher(outboxRepo, eventBus, event.DispatcherConfig{
+		PollInterval: time.Second * time.Duration(cfg.OutboxPollIntervalSeconds),
+		BatchSize:    100,
+		Lease:        time.Minute,
+		MaxBackoff:   time.Hour,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		Retention:    time.Hour * 24 * 7,
+	})
+	app.Go("outbox", jobsTimeout, dispatcher.Run)
 
-	<-ctx.Done()
-	logger.Log.Println("Graceful shutdown initiated...")
+	auditService := audit.NewService(postgres.NewAuditRepo(db), cfg.AuditSigningKey)
+	app.Go("audit-checkpoints", jobsTimeout, func(ctx context.Context) {
+		auditService.RunCheckpoints(ctx, time.Minute*time.Duration(cfg.AuditCheckpointIntervalMinutes))
+	})
+
+	userService := user.NewService(txManager, userRepo, tokenRepo, invitationService, cfg.RegistrationMode == "invite_only", auditService)
+	authenticator := authsrvc.NewDomainAuthenticator(authsrvc.NewPasswordAuthenticator(userRepo))
+	if len(cfg.LDAPDomains) > 0 {
+		groupRoles := make(map[string]model.Role, len(cfg.LDAPGroupRoles))
+		for dn, role := range cfg.LDAPGroupRoles {
+			groupRoles[dn] = model.Role(role)
+		}
+		ldapAuthenticator := authsrvc.NewLDAPAuthenticator(authsrvc.LDAPConfig{
+			URL:                cfg.LDAPURL,
+			StartTLS:           cfg.LDAPStartTLS,
+			InsecureSkipVerify: cfg.LDAPInsecureSkipVerify,
+			BindDN:             cfg.LDAPBindDN,
+			BindPassword:       cfg.LDAPBindPassword,
+			BaseDN:             cfg.LDAPBaseDN,
+			UserFilter:         cfg.LDAPUserFilter,
+			EmailAttr:          cfg.LDAPEmailAttr,
+			FirstNameAttr:      cfg.LDAPFirstNameAttr,
+			LastNameAttr:       cfg.LDAPLastNameAttr,
+			GroupAttr:          cfg.LDAPGroupAttr,
+			GroupRoles:         groupRoles,
+			Timeout:            time.Second * time.Duration(cfg.LDAPTimeoutSeconds),
+		}, txManager, userRepo, postgres.NewIdentityRepo(db))
+		authenticator.Route(ldapAuthenticator, cfg.LDAPDomains...)
+	}
+
+	verificationRepo := postgres.NewVerificationRepo(db)
+
+	var smsSender sms.Sender = sms.NewLogSender()
+	switch cfg.SMSDriver {
+	case "file":
+		smsSender = sms.NewFileSender(cfg.SMSFilePath)
+	case "twilio":
+		smsSender = sms.NewTwilioSender(cfg.TwilioBaseURL, cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.SMSFrom)
+	}
+	phoneService := phone.NewService(userRepo, verificationRepo, smsSender, rdb, phone.Config{
+		DefaultCountryCode: cfg.SMSDefaultCountryCode,
+		CodeExpiry:         time.Minute * time.Duration(cfg.OTPExpiryMinutes),
+		MaxAttempts:        cfg.OTPMaxAttempts,
+		RateLimit:          cfg.SMSRateLimitPerHour,
+		RateWindow:         time.Hour,
+	})
+	phoneHandler := phonehandler.NewHandler(phoneService, authMW)
+
+	var secondFactor authsrvc.SecondFactor
+	if cfg.SMSLoginEnabled {
+		secondFactor = phoneService
+	}
+	authService := authsrvc.NewService(userRepo, tokens, authenticator, secondFactor, auditService, outboxRepo)
+	authHandler := auth.NewHandler(userService, authService, authMW, cookies)
+	adminHandler := adminhandler.NewHandler(userService, auditService)
+
+	magicLinkExpiry := time.Minute * time.Duration(cfg.MagicLinkExpiryMinutes)
+	passwordlessService := passwordless.NewService(userRepo, verificationRepo, authService, mailer, rdb, passwordless.Config{
+		LinkURL:     cfg.MagicLinkURL,
+		Expiry:      magicLinkExpiry,
+		MaxAttempts: cfg.MagicLinkMaxAttempts,
+		RateLimit:   cfg.MagicLinkRateLimitPerHour,
+		RateWindow:  time.Hour,
+	})
+	passwordlessHandler := passwordlesshandler.NewHandler(passwordlessService, magicLinkExpiry, cookies)
+
+	orgService := organization.NewService(orgRepo, userRepo, tokens)
+	orgHandler := orghandler.NewHandler(orgService, authMW, tenantMW, cookies)
+
+	bodyLimit := middleware.MaxBodySize(cfg.MaxBodyBytes)
+	registrars := []transporthttp.RouteRegistrar{
+		transporthttp.TestPage,
+		transporthttp.Mount("/v1", []func(http.Handler) http.Handler{bodyLimit}, authHandler, passwordlessHandler, phoneHandler, orgHandler, invitationHandler),
+		transporthttp.Mount("/admin", []func(http.Handler) http.Handler{bodyLimit, authMW, middleware.RequireRole(model.RoleAdmin)}, adminHandler, webhookHandler),
+	}
 
-	ctxShutDown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	if cfg.SAMLEnabled {
+		sp, err := sso.NewServiceProvider(ctx, sso.SPConfig{
+			RootURL:           cfg.SAMLRootURL,
+			EntityID:          cfg.SAMLEntityID,
+			CertFile:          cfg.SAMLCertFile,
+			KeyFile:           cfg.SAMLKeyFile,
+			IDPMetadataURL:    cfg.SAMLIDPMetadataURL,
+			IDPMetadataFile:   cfg.SAMLIDPMetadataFile,
+			AllowIDPInitiated: cfg.SAMLAllowIDPInitiated,
+			MetadataTimeout:   time.Second * time.Duration(cfg.SAMLMetadataTimeoutSeconds),
+		})
+		if err != nil {
+			logger.Fatal("Failed to configure SAML", "error", err)
+		}
+		ssoService := sso.NewService(sp, txManager, userRepo, postgres.NewIdentityRepo(db), authService, sso.AttributeMap{
+			Email:     cfg.SAMLAttrEmail,
+			FirstName: cfg.SAMLAttrFirstName,
+			LastName:  cfg.SAMLAttrLastName,
+			Phone:     cfg.SAMLAttrPhone,
+		})
+		// The IdP knows these URLs, so they stay outside the versioned API.
+		registrars = append(registrars, transporthttp.Mount("/saml", []func(http.Handler) http.Handler{bodyLimit}, ssohandler.NewHandler(ssoService, cookies)))
+	}
+
+	if cfg.SCIMEnabled {
+		groupRepo := postgres.NewGroupRepo(db)
+		scimService := scim.NewService(txManager, userRepo, groupRepo, tokenRepo, cfg.SCIMBaseURL)
+		scimHandler := scimhandler.NewHandler(scimService, middleware.StaticToken(cfg.SCIMToken))
+		registrars = append(registrars, transporthttp.Mount("/scim/v2", []func(http.Handler) http.Handler{middleware.MaxBodySize(cfg.SCIMMaxBodyBytes)}, scimHandler))
+	}
+
+	return registrars
+}
+
+// buildTokens picks the credentials handed to clients: JWTs (the default)
+// or opaque sessions. The session manager is returned separately so the
+// caller can end sessions on account events; it is nil in JWT mode.
+func buildTokens(cfg *config.Config, tokenRepo token.Repository, revocation security.Revocation, sessions security.SessionStore, users userrepo.Repository, orgs orgrepo.Repository) (security.TokenManager, *security.SessionManager) {
+	switch cfg.AuthMode {
+	case "jwt":
+		policy := security.RevocationPolicy(cfg.RevocationFailMode)
+		if !policy.Valid() {
+			logger.Fatal("Unknown revocation fail mode", "mode", cfg.RevocationFailMode)
+		}
+		return security.NewJWTManager(cfg.JWTAccessSecret, cfg.JWTRefreshSecret, time.Minute*time.Duration(cfg.JWTAccessExpiryMinutes), time.Hour*time.Duration(cfg.JWTRefreshExpiryHours), tokenRepo, revocation, policy, users, orgs), nil
+	case "session":
+		sessionManager := security.NewSessionManager(sessions, security.SessionConfig{
+			IdleTimeout:     time.Minute * time.Duration(cfg.SessionIdleTimeoutMinutes),
+			AbsoluteTimeout: time.Hour * time.Duration(cfg.SessionAbsoluteTimeoutHours),
+		})
+		return sessionManager, sessionManager
+	}
+	logger.Fatal("Unknown auth mode", "mode", cfg.AuthMode)
+	return nil, nil
+}
+
+func printRoutes(router *transporthttp.Router) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATTERN\tNAME")
+	for _, route := range router.Routes() {
+		method := route.Method
+		if method == "" {
+			method = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", method, route.Pattern, route.Name)
+	}
+	w.Flush()
+}
+
+// metricsRoutes serves /metrics outside the versioned API.
+func metricsRoutes(cfg *config.Config) transporthttp.RouteRegistrar {
+	var mws []func(http.Handler) http.Handler
+	if cfg.MetricsToken != "" {
+		mws = append(mws, middleware.StaticToken(cfg.MetricsToken))
+	}
+	return transporthttp.Mount("", mws, metricshandler.NewHandler())
+}
 
-	if err := server.Shutdown(ctxShutDown); err != nil {
-		logger.Log.Fatalf("Server forced to close: %s", err)
-		server.Close()
-	} else {
-		logger.Log.Println("Server exited properly.")
+// serverMiddleware runs on every request. Route-specific middleware, such as
+// body limits, is applied through groups instead.
+func serverMiddleware(cfg *config.Config) []func(http.Handler) http.Handler {
+	return []func(http.Handler) http.Handler{
+		middleware.SecurityHeaders(middleware.SecurityHeadersConfig{
+			HSTSMaxAge:            time.Second * time.Duration(cfg.HSTSMaxAgeSeconds),
+			ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "no-referrer",
+		}),
+		middleware.CORS(middleware.CORSConfig{
+			AllowedOrigins:   cfg.CORSAllowedOrigins,
+			AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+			AllowedHeaders:   []string{"Authorization", "Content-Type", helpers.CSRFHeader, middleware.RequestIDHeader, "traceparent", "tracestate"},
+			ExposedHeaders:   []string{middleware.RequestIDHeader},
+			AllowCredentials: cfg.CORSAllowCredentials,
+			MaxAge:           time.Second * time.Duration(cfg.CORSMaxAgeSeconds),
+		}),
+	}
+}
+
+func newCookies(cfg *config.Config) *helpers.Cookies {
+	cookies, err := helpers.NewCookies(helpers.CookieConfig{
+		Name:       cfg.CookieName,
+		Domain:     cfg.CookieDomain,
+		Path:       cfg.CookiePath,
+		Secure:     cfg.CookieSecure,
+		HostPrefix: cfg.CookieHostPrefix,
+		MaxAge:     time.Hour * time.Duration(cfg.JWTRefreshExpiryHours),
+		CSRFSecret: cfg.CSRFSecret,
+	})
+	if err != nil {
+		logger.Fatal("Invalid cookie configuration", "error", err)
 	}
-	//Graceful shutdown logic can be added here if needed
+	return cookies
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/razedwell/go-hand/internal/config"
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/cache"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	orgrepo "github.com/razedwell/go-hand/internal/repository/organization"
+	"github.com/razedwell/go-hand/internal/service/audit"
+	authsrvc "github.com/razedwell/go-hand/internal/service/auth"
+	"github.com/razedwell/go-hand/internal/service/invitation"
+	"github.com/razedwell/go-hand/internal/service/user"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/handler/auth"
+	"github.com/razedwell/go-hand/internal/transport/http/middleware"
+)
+
+// buildMemory wires registration, login, refresh and logout against
+// in-memory storage, for demos and local development. Everything is lost on
+// exit, and features that need the other Postgres tables are left out.
+func buildMemory(cfg *config.Config) []transporthttp.RouteRegistrar {
+	logger.Log.Warn("Using in-memory storage; data is not persisted and only the auth routes are served")
+
+	outbox := memory.NewOutbox()
+	userRepo := memory.NewUserRepo(outbox)
+	tokenRepo := memory.NewTokenRepo()
+
+	store := cache.NewMemoryStore()
+	tokens, _ := buildTokens(cfg, tokenRepo, store, store, userRepo, noOrganizations{})
+	authMW := middleware.Auth(tokens)
+	cookies := newCookies(cfg)
+
+	userService := user.NewService(memory.TxManager{}, userRepo, tokenRepo, noInvitations{}, cfg.RegistrationMode == "invite_only", audit.Discard)
+	authenticator := authsrvc.NewDomainAuthenticator(authsrvc.NewPasswordAuthenticator(userRepo))
+	authService := authsrvc.NewService(userRepo, tokens, authenticator, nil, audit.Discard, outbox)
+
+	authHandler := auth.NewHandler(userService, authService, authMW, cookies)
+	return []transporthttp.RouteRegistrar{
+		transporthttp.TestPage,
+		transporthttp.Mount("/v1", []func(http.Handler) http.Handler{middleware.MaxBodySize(cfg.MaxBodyBytes)}, authHandler),
+	}
+}
+
+// noInvitations rejects every invitation token, since invitations live in
+// Postgres.
+type noInvitations struct{}
+
+func (noInvitations) Validate(ctx context.Context, token string) (*model.Invitation, error) {
+	return nil, invitation.ErrInvalidInvitation
+}
+
+func (noInvitations) Redeem(ctx context.Context, inv *model.Invitation, userID int64) error {
+	return invitation.ErrInvalidInvitation
+}
+
+// noOrganizations has no memberships, since organizations live in Postgres.
+// Only FindMembership is reachable: the org routes aren't served.
+type noOrganizations struct {
+	orgrepo.Repository
+}
+
+func (noOrganizations) FindMembership(ctx context.Context, orgID int64, userID int64) (*model.Membership, error) {
+	return nil, orgrepo.ErrMembershipNotFound
+}
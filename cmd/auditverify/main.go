@@ -0,0 +1,68 @@
+// Command auditverify walks the audit log hash chain and its signed
+// checkpoints, and reports the first broken link.
+//
+//	go run ./cmd/auditverify -from 2026-01-01 -to 2026-01-31
+//
+// It exits with status 1 if the chain is broken and 2 on other errors.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/config"
+	"github.com/razedwell/go-hand/internal/platform/db"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/postgres"
+	"github.com/razedwell/go-hand/internal/service/audit"
+)
+
+func main() {
+	from := flag.String("from", "", "first segment to verify (YYYY-MM-DD)")
+	to := flag.String("to", "", "last segment to verify (YYYY-MM-DD)")
+	flag.Parse()
+
+	for _, v := range []string{*from, *to} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, v); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid date %q: expected YYYY-MM-DD\n", v)
+			os.Exit(2)
+		}
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%s\n", err)
+		os.Exit(2)
+	}
+	if err := logger.Init(logger.Config{Level: cfg.LogLevel, Format: cfg.LogFormat}); err != nil {
+		logger.Fatal("Invalid logging configuration", "error", err)
+	}
+
+	dbUrl := db.BuildDBUrl(cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName, cfg.DBSSLMode, cfg.Timezone)
+	conn, err := db.NewClient(dbUrl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %s\n", err)
+		os.Exit(2)
+	}
+	defer conn.Close()
+
+	auditService := audit.NewService(postgres.NewAuditRepo(conn), cfg.AuditSigningKey)
+	report, err := auditService.Verify(context.Background(), *from, *to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Verification failed: %s\n", err)
+		os.Exit(2)
+	}
+
+	fmt.Printf("Checked %d segments, %d events, %d checkpoints\n", report.Segments, report.Events, report.Checkpoints)
+	if report.Broken != nil {
+		fmt.Printf("BROKEN: %s\n", report.Broken)
+		os.Exit(1)
+	}
+	fmt.Println("OK: audit chain intact")
+}
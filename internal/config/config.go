@@ -1,75 +1,178 @@
+// Package config loads the service configuration. Every setting has an
+// environment variable name, given by its env tag, which is also its key in
+// config files and, lowercased with dashes, its command-line flag.
 package config
 
 import (
-	"os"
-	"strconv"
-
-	"github.com/joho/godotenv"
-	"github.com/razedwell/go-hand/internal/platform/logger"
+	"net"
 )
 
+// Config fields are filled by Load from their tags: env names the setting,
+// default is its value when no layer sets it, secret marks values that are
+// redacted when printed, may be read from a file named by <env>_FILE and
+// are not accepted as flags. oneof and min are checked by Validate.
 type Config struct {
-	Port                   string
-	DBHost                 string
-	DBPort                 string
-	DBUser                 string
-	DBPassword             string
-	DBName                 string
-	DBSSLMode              string
-	JWTAccessSecret        string
-	JWTRefreshSecret       string
-	JWTAccessExpiryMinutes int
-	JWTRefreshExpiryHours  int
-	Timezone               string
-	RedisAddr              string
-	RedisPort              string
-	RedisPassword          string
-	RedisDB                int
-}
+	// Production unless set, so a missing ENV can't switch off the checks
+	// on default secrets.
+	Env string `env:"ENV" default:"production" oneof:"development test staging production"`
 
-func LoadConfig() *Config {
-	err := godotenv.Load() // Loads .env file
-	if err != nil {
-		logger.Log.Println("No .env file found, using system environment variables")
-	}
+	Port                   string `env:"PORT" default:"8080"`
+	DBHost                 string `env:"DB_HOST" default:"localhost"`
+	DBPort                 string `env:"DB_PORT" default:"5432"`
+	DBUser                 string `env:"DB_USER" default:"user"`
+	DBPassword             string `env:"DB_PASSWORD" default:"password" secret:"true"`
+	DBName                 string `env:"DB_NAME" default:"backend_db"`
+	DBSSLMode              string `env:"DB_SSLMODE" default:"disable" oneof:"disable allow prefer require verify-ca verify-full"`
+	JWTAccessSecret        string `env:"JWT_ACCESS_SECRET" default:"default_access_secret" secret:"true"`
+	JWTRefreshSecret       string `env:"JWT_REFRESH_SECRET" default:"default_refresh_secret" secret:"true"`
+	JWTAccessExpiryMinutes int    `env:"JWT_ACCESS_EXPIRY_MINUTES" default:"5" min:"1"`
+	JWTRefreshExpiryHours  int    `env:"JWT_REFRESH_EXPIRY_HOURS" default:"24" min:"1"`
+	Timezone               string `env:"TIMEZONE" default:"UTC"`
+	RedisAddr              string `env:"REDIS_ADDR"` // host:port; defaults to REDIS_HOST and REDIS_PORT
+	RedisHost              string `env:"REDIS_HOST" default:"localhost"`
+	RedisPort              string `env:"REDIS_PORT" default:"6379"`
+	RedisPassword          string `env:"REDIS_PASSWORD" secret:"true"`
+	RedisDB                int    `env:"REDIS_DB" default:"0" min:"0"`
 
-	redisDB, err := strconv.Atoi(getEnv("REDIS_DB", "0"))
-	if err != nil {
-		redisDB = 0
-	}
+	LogLevel  string `env:"LOG_LEVEL" default:"info" oneof:"debug info warn error"`
+	LogFormat string `env:"LOG_FORMAT" default:"text" oneof:"text json"`
 
-	jwtAccessExpiryMinutes, err := strconv.Atoi(getEnv("JWT_ACCESS_EXPIRY_MINUTES", "5"))
-	if err != nil {
-		jwtAccessExpiryMinutes = 5
-	}
+	MetricsEnabled bool   `env:"METRICS_ENABLED" default:"true"`
+	MetricsToken   string `env:"METRICS_TOKEN" secret:"true"` // bearer token for /metrics; empty leaves it open
 
-	jwtRefreshExpiryHours, err := strconv.Atoi(getEnv("JWT_REFRESH_EXPIRY_HOURS", "24"))
-	if err != nil {
-		jwtRefreshExpiryHours = 24
-	}
+	HTTPReadTimeoutSeconds       int `env:"HTTP_READ_TIMEOUT_SECONDS" default:"15" min:"0"`
+	HTTPReadHeaderTimeoutSeconds int `env:"HTTP_READ_HEADER_TIMEOUT_SECONDS" default:"5" min:"0"`
+	HTTPWriteTimeoutSeconds      int `env:"HTTP_WRITE_TIMEOUT_SECONDS" default:"30" min:"0"`
+	HTTPIdleTimeoutSeconds       int `env:"HTTP_IDLE_TIMEOUT_SECONDS" default:"120" min:"0"`
 
-	return &Config{
-		Port:                   getEnv("PORT", "8080"),
-		DBHost:                 getEnv("DB_HOST", "localhost"),
-		DBPort:                 getEnv("DB_PORT", "5432"),
-		DBUser:                 getEnv("DB_USER", "user"),
-		DBPassword:             getEnv("DB_PASSWORD", "password"),
-		DBName:                 getEnv("DB_NAME", "backend_db"),
-		DBSSLMode:              getEnv("DB_SSLMODE", "disable"),
-		JWTAccessSecret:        getEnv("JWT_ACCESS_SECRET", "default_access_secret"),
-		JWTRefreshSecret:       getEnv("JWT_REFRESH_SECRET", "default_refresh_secret"),
-		JWTAccessExpiryMinutes: jwtAccessExpiryMinutes,
-		JWTRefreshExpiryHours:  jwtRefreshExpiryHours,
-		RedisAddr:              getEnv("REDIS_ADDR", "localhost:6379"),
-		RedisPassword:          getEnv("REDIS_PASSWORD", ""),
-		RedisDB:                redisDB,
-		Timezone:               getEnv("TIMEZONE", "UTC"),
-	}
+	// On SIGTERM the service reports not ready and waits
+	// ShutdownReadinessDelaySeconds before it stops taking requests, then
+	// drains HTTP and the background jobs within their timeouts.
+	// ShutdownTimeoutSeconds bounds each of the remaining steps.
+	ShutdownReadinessDelaySeconds int `env:"SHUTDOWN_READINESS_DELAY_SECONDS" default:"0" min:"0"`
+	ShutdownHTTPTimeoutSeconds    int `env:"SHUTDOWN_HTTP_TIMEOUT_SECONDS" default:"15" min:"1"`
+	ShutdownJobsTimeoutSeconds    int `env:"SHUTDOWN_JOBS_TIMEOUT_SECONDS" default:"10" min:"1"`
+	ShutdownTimeoutSeconds        int `env:"SHUTDOWN_TIMEOUT_SECONDS" default:"5" min:"1"`
+
+	HealthCheckTimeoutSeconds int `env:"HEALTH_CHECK_TIMEOUT_SECONDS" default:"2" min:"1"`
+	// Tries to reach Postgres and Redis at startup before giving up; 1
+	// fails fast. The wait between tries starts at the backoff and doubles.
+	StartupConnectAttempts     int `env:"STARTUP_CONNECT_ATTEMPTS" default:"1" min:"1"`
+	StartupRetryBackoffSeconds int `env:"STARTUP_RETRY_BACKOFF_SECONDS" default:"1" min:"1"`
+
+	TracingExporter    string  `env:"TRACING_EXPORTER" default:"none" oneof:"none stdout otlp"`
+	TracingEndpoint    string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"` // OTLP/HTTP collector host:port
+	TracingInsecure    bool    `env:"TRACING_INSECURE" default:"false"`
+	TracingServiceName string  `env:"TRACING_SERVICE_NAME" default:"go-hand"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" default:"1"`
+
+	RevocationStore    string `env:"REVOCATION_STORE" default:"redis" oneof:"redis postgres memory"`
+	RevocationFailMode string `env:"REVOCATION_FAIL_MODE" default:"closed" oneof:"closed open"` // when the store is unreachable
+
+	CookieName       string `env:"COOKIE_NAME" default:"refresh_token"`
+	CookieDomain     string `env:"COOKIE_DOMAIN"`
+	CookiePath       string `env:"COOKIE_PATH" default:"/"`
+	CookieSecure     bool   `env:"COOKIE_SECURE" default:"false"`
+	CookieHostPrefix bool   `env:"COOKIE_HOST_PREFIX" default:"false"` // __Host- prefix; needs Secure, path / and no domain
+	CSRFSecret       string `env:"CSRF_SECRET" default:"default_csrf_secret" secret:"true"`
+
+	CORSAllowedOrigins   []string `env:"CORS_ALLOWED_ORIGINS"` // exact origins or wildcards like https://*.example.com
+	CORSAllowCredentials bool     `env:"CORS_ALLOW_CREDENTIALS" default:"false"`
+	CORSMaxAgeSeconds    int      `env:"CORS_MAX_AGE_SECONDS" default:"600" min:"0"`
+
+	HSTSMaxAgeSeconds     int    `env:"HSTS_MAX_AGE_SECONDS" default:"0" min:"0"` // 0 disables HSTS
+	ContentSecurityPolicy string `env:"CONTENT_SECURITY_POLICY" default:"default-src 'none'; frame-ancestors 'none'"`
+	MaxBodyBytes          int64  `env:"MAX_BODY_BYTES" default:"1048576" min:"1"`
+	SCIMMaxBodyBytes      int64  `env:"SCIM_MAX_BODY_BYTES" default:"10485760" min:"1"` // SCIM bulk group updates can be large
+
+	AuthMode                    string `env:"AUTH_MODE" default:"jwt" oneof:"jwt session"`
+	SessionIdleTimeoutMinutes   int    `env:"SESSION_IDLE_TIMEOUT_MINUTES" default:"30" min:"1"`
+	SessionAbsoluteTimeoutHours int    `env:"SESSION_ABSOLUTE_TIMEOUT_HOURS" default:"24" min:"1"`
+
+	SAMLEnabled           bool   `env:"SAML_ENABLED" default:"false"`
+	SAMLRootURL           string `env:"SAML_ROOT_URL" default:"http://localhost:8080"`
+	SAMLEntityID          string `env:"SAML_ENTITY_ID"`
+	SAMLCertFile          string `env:"SAML_CERT_FILE"`
+	SAMLKeyFile           string `env:"SAML_KEY_FILE"`
+	SAMLIDPMetadataURL    string `env:"SAML_IDP_METADATA_URL"`
+	SAMLIDPMetadataFile   string `env:"SAML_IDP_METADATA_FILE"`
+	SAMLAllowIDPInitiated bool   `env:"SAML_ALLOW_IDP_INITIATED" default:"false"`
+	SAMLAttrEmail         string `env:"SAML_ATTR_EMAIL" default:"email"`
+	SAMLAttrFirstName     string `env:"SAML_ATTR_FIRST_NAME" default:"givenName"`
+	SAMLAttrLastName      string `env:"SAML_ATTR_LAST_NAME" default:"sn"`
+	SAMLAttrPhone         string `env:"SAML_ATTR_PHONE" default:"telephoneNumber"`
+	// Bounds the IdP metadata fetch at startup.
+	SAMLMetadataTimeoutSeconds int `env:"SAML_METADATA_TIMEOUT_SECONDS" default:"10" min:"1"`
+
+	SCIMEnabled bool   `env:"SCIM_ENABLED" default:"false"`
+	SCIMToken   string `env:"SCIM_TOKEN" secret:"true"`
+	SCIMBaseURL string `env:"SCIM_BASE_URL" default:"http://localhost:8080/scim/v2"`
+
+	LDAPDomains            []string          `env:"AUTH_LDAP_DOMAINS"`
+	LDAPURL                string            `env:"LDAP_URL" default:"ldap://localhost:389"`
+	LDAPStartTLS           bool              `env:"LDAP_START_TLS" default:"false"`
+	LDAPInsecureSkipVerify bool              `env:"LDAP_INSECURE_SKIP_VERIFY" default:"false"`
+	LDAPBindDN             string            `env:"LDAP_BIND_DN"`
+	LDAPBindPassword       string            `env:"LDAP_BIND_PASSWORD" secret:"true"`
+	LDAPBaseDN             string            `env:"LDAP_BASE_DN"`
+	LDAPUserFilter         string            `env:"LDAP_USER_FILTER" default:"(&(objectClass=person)(mail=%s))"`
+	LDAPEmailAttr          string            `env:"LDAP_EMAIL_ATTR" default:"mail"`
+	LDAPFirstNameAttr      string            `env:"LDAP_FIRST_NAME_ATTR" default:"givenName"`
+	LDAPLastNameAttr       string            `env:"LDAP_LAST_NAME_ATTR" default:"sn"`
+	LDAPGroupAttr          string            `env:"LDAP_GROUP_ATTR" default:"memberOf"`
+	LDAPGroupRoles         map[string]string `env:"LDAP_GROUP_ROLES"` // groupDN:role;groupDN:role
+	LDAPTimeoutSeconds     int               `env:"LDAP_TIMEOUT_SECONDS" default:"10" min:"1"`
+
+	MailDriver   string `env:"MAIL_DRIVER" default:"log" oneof:"log smtp"`
+	MailFrom     string `env:"MAIL_FROM" default:"no-reply@localhost"`
+	SMTPHost     string `env:"SMTP_HOST" default:"localhost"`
+	SMTPPort     string `env:"SMTP_PORT" default:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD" secret:"true"`
+
+	MagicLinkURL              string `env:"MAGIC_LINK_URL" default:"http://localhost:8080/test"`
+	MagicLinkExpiryMinutes    int    `env:"MAGIC_LINK_EXPIRY_MINUTES" default:"10" min:"1"`
+	MagicLinkMaxAttempts      int    `env:"MAGIC_LINK_MAX_ATTEMPTS" default:"5" min:"1"`
+	MagicLinkRateLimitPerHour int    `env:"MAGIC_LINK_RATE_LIMIT_PER_HOUR" default:"5" min:"1"`
+
+	SMSDriver             string `env:"SMS_DRIVER" default:"log" oneof:"log file twilio"`
+	SMSFilePath           string `env:"SMS_FILE_PATH" default:"sms.log"`
+	SMSFrom               string `env:"SMS_FROM"`
+	SMSDefaultCountryCode string `env:"SMS_DEFAULT_COUNTRY_CODE"`
+	SMSRateLimitPerHour   int    `env:"SMS_RATE_LIMIT_PER_HOUR" default:"5" min:"1"`
+	SMSLoginEnabled       bool   `env:"SMS_LOGIN_ENABLED" default:"false"`
+	TwilioBaseURL         string `env:"TWILIO_BASE_URL" default:"https://api.twilio.com"`
+	TwilioAccountSID      string `env:"TWILIO_ACCOUNT_SID"`
+	TwilioAuthToken       string `env:"TWILIO_AUTH_TOKEN" secret:"true"`
+	OTPExpiryMinutes      int    `env:"OTP_EXPIRY_MINUTES" default:"5" min:"1"`
+	OTPMaxAttempts        int    `env:"OTP_MAX_ATTEMPTS" default:"5" min:"1"`
+
+	AuditSigningKey                string `env:"AUDIT_SIGNING_KEY" default:"default_audit_signing_key" secret:"true"`
+	AuditCheckpointIntervalMinutes int    `env:"AUDIT_CHECKPOINT_INTERVAL_MINUTES" default:"60" min:"1"`
+
+	WebhookTimeoutSeconds       int  `env:"WEBHOOK_TIMEOUT_SECONDS" default:"10" min:"1"`
+	WebhookMaxAttempts          int  `env:"WEBHOOK_MAX_ATTEMPTS" default:"8" min:"1"`
+	WebhookBaseBackoffSeconds   int  `env:"WEBHOOK_BASE_BACKOFF_SECONDS" default:"30" min:"1"`
+	WebhookMaxBackoffMinutes    int  `env:"WEBHOOK_MAX_BACKOFF_MINUTES" default:"360" min:"1"`
+	WebhookPollIntervalSeconds  int  `env:"WEBHOOK_POLL_INTERVAL_SECONDS" default:"5" min:"1"`
+	WebhookAllowPrivateNetworks bool `env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS" default:"false"`
+
+	OutboxPollIntervalSeconds int `env:"OUTBOX_POLL_INTERVAL_SECONDS" default:"1" min:"1"`
+	OutboxMaxAttempts         int `env:"OUTBOX_MAX_ATTEMPTS" default:"10" min:"1"`
+
+	RegistrationMode      string `env:"REGISTRATION_MODE" default:"open" oneof:"open invite_only"`
+	InvitationURL         string `env:"INVITATION_URL" default:"http://localhost:8080/test"`
+	InvitationExpiryHours int    `env:"INVITATION_EXPIRY_HOURS" default:"168" min:"1"`
+}
+
+// IsDevelopment reports whether insecure defaults are acceptable.
+func (c *Config) IsDevelopment() bool {
+	return c.Env == "development"
 }
 
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
+// resolve fills settings derived from others once every layer is applied.
+func (c *Config) resolve() {
+	if c.RedisAddr == "" {
+		c.RedisAddr = net.JoinHostPort(c.RedisHost, c.RedisPort)
 	}
-	return fallback
 }
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Loader builds a Config from, in increasing precedence, the defaults, a
+// YAML or TOML file, the environment (including .env) and flags.
+type Loader struct {
+	file  string
+	flags map[string]string
+}
+
+func NewLoader() *Loader {
+	return &Loader{flags: map[string]string{}}
+}
+
+// RegisterFlags adds -config and one flag per setting to fs, e.g.
+// -db-host for DB_HOST. Secrets have no flags, since command lines are
+// visible to other users of the machine.
+func (l *Loader) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&l.file, "config", "", "YAML or TOML config file (default $CONFIG_FILE)")
+	for _, s := range settings(&Config{}) {
+		if s.secret {
+			continue
+		}
+		fs.Func(flagName(s.env), "overrides "+s.env, func(v string) error {
+			l.flags[s.env] = v
+			return nil
+		})
+	}
+}
+
+// Load builds and validates the configuration. The returned Config is
+// complete even when the error is not nil, so it can still be printed; the
+// error lists every problem found.
+func (l *Loader) Load() (*Config, error) {
+	// A missing .env file is fine; the environment is used as it is.
+	_ = godotenv.Load()
+
+	var errs []error
+	layers := []map[string]string{defaults()}
+
+	file := l.file
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	if file != "" {
+		values, err := readFile(file)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		layers = append(layers, values)
+	}
+
+	env, err := environment()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	layers = append(layers, env, l.flags)
+
+	cfg := &Config{}
+	for _, s := range settings(cfg) {
+		var raw string
+		for _, layer := range layers {
+			if v, ok := layer[s.env]; ok {
+				raw = v
+			}
+		}
+		if err := s.set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			// Keep validation from reporting the same setting again.
+			s.set(s.field.Tag.Get("default"))
+		}
+	}
+	cfg.resolve()
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// LoadConfig loads the configuration without flags.
+func LoadConfig() (*Config, error) {
+	return NewLoader().Load()
+}
+
+type setting struct {
+	env    string
+	secret bool
+	field  reflect.StructField
+	value  reflect.Value
+}
+
+// settings lists the fields of cfg in declaration order.
+func settings(cfg *Config) []setting {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	res := make([]setting, 0, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		res = append(res, setting{
+			env:    f.Tag.Get("env"),
+			secret: f.Tag.Get("secret") == "true",
+			field:  f,
+			value:  v.Field(i),
+		})
+	}
+	return res
+}
+
+func (s setting) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		s.value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		s.value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		s.value.SetBool(b)
+	case reflect.Slice:
+		s.value.Set(reflect.ValueOf(splitList(raw)))
+	case reflect.Map:
+		roles, err := parseGroupRoles(raw)
+		if err != nil {
+			return err
+		}
+		s.value.Set(reflect.ValueOf(roles))
+	default:
+		panic("config: unsupported field type " + s.field.Type.String())
+	}
+	return nil
+}
+
+func defaults() map[string]string {
+	values := map[string]string{}
+	for _, s := range settings(&Config{}) {
+		values[s.env] = s.field.Tag.Get("default")
+	}
+	return values
+}
+
+// environment reads the settings from the environment. A secret may
+// instead be read from the file named by <env>_FILE, such as a mounted
+// Kubernetes or Docker secret.
+func environment() (map[string]string, error) {
+	var errs []error
+	values := map[string]string{}
+	for _, s := range settings(&Config{}) {
+		value, ok := os.LookupEnv(s.env)
+		if s.secret {
+			if path, fromFile := os.LookupEnv(s.env + "_FILE"); fromFile {
+				if ok {
+					errs = append(errs, fmt.Errorf("%s: set only one of %s and %s_FILE", s.env, s.env, s.env))
+					values[s.env] = value
+					continue
+				}
+				b, err := os.ReadFile(path)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s_FILE: %w", s.env, err))
+					continue
+				}
+				value, ok = strings.TrimRight(string(b), "\r\n"), true
+			}
+		}
+		if ok {
+			values[s.env] = value
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+// readFile reads a YAML or TOML file, chosen by extension. Keys are the
+// environment names in any case, and nested tables are joined with
+// underscores, so db: {host: x} sets DB_HOST. Lists become comma-separated.
+func readFile(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &doc)
+	case ".toml":
+		err = toml.Unmarshal(b, &doc)
+	default:
+		return nil, fmt.Errorf("config file: unsupported format %q, use .yaml or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	known := defaults()
+	values := map[string]string{}
+	var errs []error
+	flatten("", doc, values)
+	for key := range values {
+		if _, ok := known[key]; !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown setting %s", path, key))
+			delete(values, key)
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+func flatten(prefix string, doc map[string]any, values map[string]string) {
+	for k, v := range doc {
+		key := strings.ToUpper(strings.ReplaceAll(k, "-", "_"))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(key, v, values)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+func flagName(env string) string {
+	return strings.ToLower(strings.ReplaceAll(env, "_", "-"))
+}
+
+// splitList parses a comma-separated list, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseGroupRoles parses "groupDN:role;groupDN:role". Group DNs contain
+// commas, so entries are separated by semicolons and split on the last colon.
+func parseGroupRoles(value string) (map[string]string, error) {
+	roles := map[string]string{}
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid group mapping %q, expected groupDN:role", entry)
+		}
+		roles[strings.TrimSpace(entry[:i])] = strings.TrimSpace(entry[i+1:])
+	}
+	return roles, nil
+}
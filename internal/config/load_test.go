@@ -0,0 +1,202 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// load runs a Loader in an empty directory, so no .env is picked up, with
+// env set on top of the process environment and args parsed as flags.
+// ENV defaults to development here; tests of the production checks set it.
+func load(t *testing.T, env map[string]string, args ...string) (*Config, error) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("ENV", "development")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	loader := NewLoader()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return loader.Load()
+}
+
+// writeFile writes content to name in a fresh directory and returns its path.
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", "db:\n  host: yaml-host\n  port: 6543\nlog-level: warn\ncors_allowed_origins: [https://a.example.com, https://b.example.com]\n")
+	tomlFile := writeFile(t, "config.toml", "DB_HOST = \"toml-host\"\n[log]\nlevel = \"debug\"\n")
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		host     string
+		port     string
+		logLevel string
+	}{
+		{"defaults", nil, nil, "localhost", "5432", "info"},
+		{"yaml file", nil, []string{"-config", yamlFile}, "yaml-host", "6543", "warn"},
+		{"toml file", nil, []string{"-config", tomlFile}, "toml-host", "5432", "debug"},
+		{"file from CONFIG_FILE", map[string]string{"CONFIG_FILE": yamlFile}, nil, "yaml-host", "6543", "warn"},
+		{"-config over CONFIG_FILE", map[string]string{"CONFIG_FILE": yamlFile}, []string{"-config", tomlFile}, "toml-host", "5432", "debug"},
+		{"env over file", map[string]string{"DB_HOST": "env-host"}, []string{"-config", yamlFile}, "env-host", "6543", "warn"},
+		{"empty env still overrides", map[string]string{"LOG_LEVEL": "error", "DB_HOST": ""}, []string{"-config", yamlFile}, "", "6543", "error"},
+		{"flag over env", map[string]string{"DB_HOST": "env-host"}, []string{"-config", yamlFile, "-db-host", "flag-host"}, "flag-host", "6543", "warn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, tt.env, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.DBHost != tt.host || cfg.DBPort != tt.port || cfg.LogLevel != tt.logLevel {
+				t.Errorf("DB_HOST %q, DB_PORT %q, LOG_LEVEL %q; want %q, %q, %q", cfg.DBHost, cfg.DBPort, cfg.LogLevel, tt.host, tt.port, tt.logLevel)
+			}
+		})
+	}
+
+	t.Run("lists from a file", func(t *testing.T) {
+		cfg, err := load(t, nil, "-config", yamlFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(cfg.CORSAllowedOrigins, " "); got != "https://a.example.com https://b.example.com" {
+			t.Errorf("CORS_ALLOWED_ORIGINS = %q", got)
+		}
+	})
+}
+
+func TestLoadDerivedSettings(t *testing.T) {
+	cfg, err := load(t, map[string]string{"REDIS_HOST": "cache", "REDIS_PORT": "6380"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RedisAddr != "cache:6380" {
+		t.Errorf("REDIS_ADDR = %q", cfg.RedisAddr)
+	}
+
+	cfg, err = load(t, map[string]string{"REDIS_ADDR": "redis.internal:6379", "REDIS_HOST": "cache"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RedisAddr != "redis.internal:6379" {
+		t.Errorf("REDIS_ADDR = %q", cfg.RedisAddr)
+	}
+}
+
+func TestLoadSecretFromFile(t *testing.T) {
+	secret := strings.Repeat("s", 40)
+	path := writeFile(t, "jwt_access_secret", secret+"\r\n")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr string
+	}{
+		{"read and trimmed", map[string]string{"JWT_ACCESS_SECRET_FILE": path}, secret, ""},
+		{"both set", map[string]string{"JWT_ACCESS_SECRET_FILE": path, "JWT_ACCESS_SECRET": "from-env"}, "from-env", "JWT_ACCESS_SECRET: set only one of JWT_ACCESS_SECRET and JWT_ACCESS_SECRET_FILE"},
+		{"missing file", map[string]string{"JWT_ACCESS_SECRET_FILE": filepath.Join(t.TempDir(), "missing")}, "default_access_secret", "JWT_ACCESS_SECRET_FILE: open"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, tt.env)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected %q, got %v", tt.wantErr, err)
+			}
+			if cfg.JWTAccessSecret != tt.want {
+				t.Errorf("JWT_ACCESS_SECRET = %q, want %q", cfg.JWTAccessSecret, tt.want)
+			}
+		})
+	}
+
+	t.Run("only for secrets", func(t *testing.T) {
+		cfg, err := load(t, map[string]string{"DB_HOST_FILE": writeFile(t, "db_host", "file-host")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.DBHost != "localhost" {
+			t.Errorf("DB_HOST = %q", cfg.DBHost)
+		}
+	})
+}
+
+func TestSecretsHaveNoFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	NewLoader().RegisterFlags(fs)
+	for _, s := range settings(&Config{}) {
+		if (fs.Lookup(flagName(s.env)) == nil) != s.secret {
+			t.Errorf("%s: flag registered %v, secret %v", s.env, fs.Lookup(flagName(s.env)) != nil, s.secret)
+		}
+	}
+}
+
+func TestLoadRejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr []string
+	}{
+		{
+			"unknown keys",
+			"config.yaml", "db:\n  hots: x\nport: 9090\nlog_levle: debug\n",
+			[]string{"unknown setting DB_HOTS", "unknown setting LOG_LEVLE"},
+		},
+		{"unknown toml key", "config.toml", "[smtp]\nusername = \"u\"\nserver = \"s\"\n", []string{"unknown setting SMTP_SERVER"}},
+		{"unsupported format", "config.json", "{}", []string{`unsupported format ".json"`}},
+		{"malformed yaml", "config.yaml", "db: [\n", []string{"config file"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, nil, "-config", writeFile(t, tt.file, tt.content))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+			// The known keys of a file with unknown ones still apply.
+			if tt.name == "unknown keys" && cfg.Port != "9090" {
+				t.Errorf("PORT = %q", cfg.Port)
+			}
+		})
+	}
+}
+
+func TestLoadReportsParseErrorsOnce(t *testing.T) {
+	_, err := load(t, map[string]string{"REDIS_DB": "two", "METRICS_ENABLED": "sometimes"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{`REDIS_DB: invalid integer "two"`, `METRICS_ENABLED: invalid boolean "sometimes"`} {
+		if strings.Count(err.Error(), want) != 1 {
+			t.Errorf("error %q should mention %q once", err, want)
+		}
+	}
+	if strings.Count(err.Error(), "REDIS_DB") != 1 {
+		t.Errorf("REDIS_DB reported more than once: %q", err)
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Print writes the configuration as KEY=value lines in .env format. With
+// redact, secrets that are set are masked.
+func (c *Config) Print(w io.Writer, redact bool) error {
+	for _, s := range settings(c) {
+		value := format(s.value)
+		if redact && s.secret && value != "" {
+			value = redacted
+		}
+		if strings.ContainsAny(value, " \t#'\"\\$") {
+			value = strconv.Quote(value)
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", s.env, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// format renders a value the way Load parses it.
+func format(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		return strings.Join(v.Interface().([]string), ",")
+	case reflect.Map:
+		roles := v.Interface().(map[string]string)
+		entries := make([]string, 0, len(roles))
+		for dn, role := range roles {
+			entries = append(entries, dn+":"+role)
+		}
+		sort.Strings(entries)
+		return strings.Join(entries, ";")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
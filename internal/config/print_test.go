@@ -0,0 +1,48 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintRedacted(t *testing.T) {
+	cfg, err := load(t, map[string]string{
+		"JWT_ACCESS_SECRET": "access-secret-value",
+		"SMTP_PASSWORD":     "p@ss word",
+		"DB_HOST":           "db.internal",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		"JWT_ACCESS_SECRET=[REDACTED]",
+		"SMTP_PASSWORD=[REDACTED]",
+		"DB_PASSWORD=[REDACTED]", // defaults are masked too
+		"REDIS_PASSWORD=\n",      // unset secrets show as unset
+		"DB_HOST=db.internal",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("missing %q", line)
+		}
+	}
+	for _, secret := range []string{"access-secret-value", "p@ss word", "default_refresh_secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("redacted output contains %q", secret)
+		}
+	}
+
+	buf.Reset()
+	if err := cfg.Print(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "JWT_ACCESS_SECRET=access-secret-value") || !strings.Contains(out, `SMTP_PASSWORD="p@ss word"`) {
+		t.Errorf("unredacted output:\n%s", out)
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// minSecretLength is the shortest JWT signing secret accepted outside
+// development; HS256 keys should carry at least 256 bits.
+const minSecretLength = 32
+
+// Validate checks the settings against their oneof and min tags and the
+// rules between settings, and returns all problems at once. Outside
+// development, secrets must not keep their built-in defaults.
+func (c *Config) Validate() error {
+	var errs []error
+	failed := map[string]bool{}
+	fail := func(env, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{env}, args...)...))
+		failed[env] = true
+	}
+
+	for _, s := range settings(c) {
+		if oneof := s.field.Tag.Get("oneof"); oneof != "" {
+			if allowed := strings.Fields(oneof); !slices.Contains(allowed, s.value.String()) {
+				fail(s.env, "must be one of %s, got %q", strings.Join(allowed, ", "), s.value.String())
+			}
+		}
+		if tag := s.field.Tag.Get("min"); tag != "" {
+			if min, _ := strconv.ParseInt(tag, 10, 64); s.value.Int() < min {
+				fail(s.env, "must be at least %d, got %d", min, s.value.Int())
+			}
+		}
+		if s.secret && !c.IsDevelopment() {
+			if def := s.field.Tag.Get("default"); def != "" && s.value.String() == def {
+				fail(s.env, "must be changed from its default outside development")
+			}
+		}
+	}
+
+	for _, port := range []struct{ env, value string }{{"PORT", c.Port}, {"DB_PORT", c.DBPort}, {"REDIS_PORT", c.RedisPort}, {"SMTP_PORT", c.SMTPPort}} {
+		if n, err := strconv.Atoi(port.value); err != nil || n < 1 || n > 65535 {
+			fail(port.env, "invalid port %q", port.value)
+		}
+	}
+
+	if !c.IsDevelopment() {
+		for _, secret := range []struct{ env, value string }{{"JWT_ACCESS_SECRET", c.JWTAccessSecret}, {"JWT_REFRESH_SECRET", c.JWTRefreshSecret}} {
+			if !failed[secret.env] && len(secret.value) < minSecretLength {
+				fail(secret.env, "must be at least %d characters outside development", minSecretLength)
+			}
+		}
+	}
+	if c.JWTAccessSecret == c.JWTRefreshSecret {
+		fail("JWT_REFRESH_SECRET", "must differ from JWT_ACCESS_SECRET")
+	}
+
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		fail("TRACING_SAMPLE_RATIO", "must be between 0 and 1, got %g", c.TracingSampleRatio)
+	}
+	if c.CookieHostPrefix && (!c.CookieSecure || c.CookiePath != "/" || c.CookieDomain != "") {
+		fail("COOKIE_HOST_PREFIX", "needs COOKIE_SECURE=true, COOKIE_PATH=/ and no COOKIE_DOMAIN")
+	}
+	// The middleware echoes the request origin, so "*" with credentials
+	// would let any site make credentialed requests.
+	if c.CORSAllowCredentials && slices.Contains(c.CORSAllowedOrigins, "*") {
+		fail("CORS_ALLOWED_ORIGINS", "must list the allowed origins when CORS_ALLOW_CREDENTIALS is true, not \"*\"")
+	}
+	if c.SAMLEnabled {
+		if c.SAMLCertFile == "" || c.SAMLKeyFile == "" {
+			fail("SAML_ENABLED", "needs SAML_CERT_FILE and SAML_KEY_FILE")
+		}
+		if c.SAMLIDPMetadataURL == "" && c.SAMLIDPMetadataFile == "" {
+			fail("SAML_ENABLED", "needs SAML_IDP_METADATA_URL or SAML_IDP_METADATA_FILE")
+		}
+	}
+	if c.SCIMEnabled && c.SCIMToken == "" {
+		fail("SCIM_TOKEN", "must be set when SCIM is enabled")
+	}
+	if len(c.LDAPDomains) > 0 && c.LDAPBaseDN == "" {
+		fail("LDAP_BASE_DN", "must be set when AUTH_LDAP_DOMAINS is")
+	}
+	if c.SMSDriver == "twilio" && (c.TwilioAccountSID == "" || c.TwilioAuthToken == "") {
+		fail("SMS_DRIVER", "twilio needs TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN")
+	}
+
+	return errors.Join(errs...)
+}
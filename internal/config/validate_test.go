@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	_, err := load(t, map[string]string{
+		"LOG_LEVEL":                 "verbose",
+		"DB_SSLMODE":                "sometimes",
+		"JWT_ACCESS_EXPIRY_MINUTES": "0",
+		"REDIS_DB":                  "-1",
+		"PORT":                      "70000",
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`LOG_LEVEL: must be one of debug, info, warn, error, got "verbose"`,
+		`DB_SSLMODE: must be one of disable, allow, prefer, require, verify-ca, verify-full, got "sometimes"`,
+		"JWT_ACCESS_EXPIRY_MINUTES: must be at least 1, got 0",
+		"REDIS_DB: must be at least 0, got -1",
+		`PORT: invalid port "70000"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%s", want, err)
+		}
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	long := func(c byte) string { return strings.Repeat(string(c), minSecretLength) }
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string // empty for a valid configuration
+	}{
+		{"development defaults", nil, ""},
+		{"same JWT secrets", map[string]string{"JWT_ACCESS_SECRET": "same", "JWT_REFRESH_SECRET": "same"}, "JWT_REFRESH_SECRET: must differ from JWT_ACCESS_SECRET"},
+		{"sample ratio", map[string]string{"TRACING_SAMPLE_RATIO": "1.5"}, "TRACING_SAMPLE_RATIO: must be between 0 and 1"},
+		{"host prefix without Secure", map[string]string{"COOKIE_HOST_PREFIX": "true"}, "COOKIE_HOST_PREFIX: needs COOKIE_SECURE=true"},
+		{"host prefix", map[string]string{"COOKIE_HOST_PREFIX": "true", "COOKIE_SECURE": "true"}, ""},
+		{"any origin with credentials", map[string]string{"CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"}, "CORS_ALLOWED_ORIGINS: must list the allowed origins"},
+		{"SCIM without a token", map[string]string{"SCIM_ENABLED": "true"}, "SCIM_TOKEN: must be set when SCIM is enabled"},
+		{"twilio without credentials", map[string]string{"SMS_DRIVER": "twilio"}, "SMS_DRIVER: twilio needs TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN"},
+		{"short secrets in production", map[string]string{
+			"ENV": "production", "JWT_ACCESS_SECRET": "short", "JWT_REFRESH_SECRET": long('r'),
+			"DB_PASSWORD": "db", "CSRF_SECRET": "csrf", "AUDIT_SIGNING_KEY": "audit",
+		}, "JWT_ACCESS_SECRET: must be at least 32 characters outside development"},
+		{"production", map[string]string{
+			"ENV": "production", "JWT_ACCESS_SECRET": long('a'), "JWT_REFRESH_SECRET": long('r'),
+			"DB_PASSWORD": "db", "CSRF_SECRET": "csrf", "AUDIT_SIGNING_KEY": "audit",
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.env)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDefaultSecretsRejectedWhenEnvUnset(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("ENV", "")
+	os.Unsetenv("ENV") // restored by Setenv
+
+	cfg, err := LoadConfig()
+	if cfg.Env != "production" {
+		t.Fatalf("ENV = %q, want production", cfg.Env)
+	}
+	if err == nil {
+		t.Fatal("default secrets accepted")
+	}
+	for _, env := range []string{"DB_PASSWORD", "JWT_ACCESS_SECRET", "JWT_REFRESH_SECRET", "CSRF_SECRET", "AUDIT_SIGNING_KEY"} {
+		want := env + ": must be changed from its default outside development"
+		if strings.Count(err.Error(), env+":") != 1 || !strings.Contains(err.Error(), want) {
+			t.Errorf("want exactly %q in:\n%s", want, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package event delivers domain events from the outbox to in-process
+// subscribers. Services record events with the repository write they
+// belong to (or through a Publisher); the Dispatcher then hands each event
+// to the Bus at least once.
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+// Handler reacts to an event. Returning an error makes the dispatcher retry
+// the event later, so handlers must be idempotent; see Idempotent.
+type Handler func(ctx context.Context, e *model.Event) error
+
+// Publisher records events that aren't tied to a repository write.
+// postgres.OutboxRepo implements it.
+type Publisher interface {
+	Publish(ctx context.Context, events ...*model.Event) error
+}
+
+type subscription struct {
+	name    string
+	handler Handler
+}
+
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string][]subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: map[string][]subscription{}}
+}
+
+// Subscribe registers h for eventType, or for every type with
+// model.EventAll. name identifies the subscriber in errors and in the
+// processed-event log of Idempotent.
+func (b *Bus) Subscribe(eventType string, name string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[eventType] = append(b.subs[eventType], subscription{name, h})
+}
+
+// Deliver runs every matching handler, even if an earlier one fails, and
+// returns their joined errors.
+func (b *Bus) Deliver(ctx context.Context, e *model.Event) error {
+	b.mu.RLock()
+	subs := append(append([]subscription{}, b.subs[e.Type]...), b.subs[model.EventAll]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if err := sub.handler(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sub.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/repository/outbox"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type DispatcherConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Lease        time.Duration // how long a claimed batch is hidden from other dispatchers
+	MaxBackoff   time.Duration
+	MaxAttempts  int           // failed deliveries before an event is dead
+	Retention    time.Duration // how long dispatched events are kept
+}
+
+// Dispatcher moves events from the outbox to the bus. An event is marked
+// dispatched only after every subscriber succeeded; otherwise it is retried
+// with exponential backoff, and marked dead after MaxAttempts. A retry
+// reaches every subscriber again, so subscribers with side effects should
+// be wrapped in Idempotent.
+type Dispatcher struct {
+	outbox outbox.Repository
+	bus    *Bus
+	cfg    DispatcherConfig
+}
+
+func NewDispatcher(outbox outbox.Repository, bus *Bus, cfg DispatcherConfig) *Dispatcher {
+	return &Dispatcher{outbox, bus, cfg}
+}
+
+// Run dispatches every PollInterval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			n, err := d.DispatchBatch(ctx)
+			if err != nil {
+				logger.Log.ErrorContext(ctx, "Outbox dispatch failed", "error", err)
+				break
+			}
+			// Keep draining while the batches are full.
+			if n < d.cfg.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			lastCleanup = time.Now()
+			cutoff := helpers.GetCurrentTimeStampUTC().Add(-d.cfg.Retention)
+			if err := d.outbox.DeleteDispatched(ctx, cutoff); err != nil {
+				logger.Log.ErrorContext(ctx, "Outbox cleanup failed", "error", err)
+			}
+		}
+	}
+}
+
+// DispatchBatch delivers one batch of due events and returns its size.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	entries, err := d.outbox.ClaimBatch(ctx, d.cfg.BatchSize, d.cfg.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	// An entry that can't be marked is claimed again once the lease expires;
+	// the rest of the batch goes on.
+	var errs []error
+	for _, entry := range entries {
+		if err := d.dispatch(ctx, entry); err != nil {
+			errs = append(errs, fmt.Errorf("event %d: %w", entry.Event.ID, err))
+		}
+	}
+	return len(entries), errors.Join(errs...)
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, entry *outbox.Entry) error {
+	e := entry.Event
+	err := d.bus.Deliver(ctx, e)
+	if err == nil {
+		return d.outbox.MarkDispatched(ctx, e.ID)
+	}
+
+	attempts := entry.Attempts + 1
+	if attempts >= d.cfg.MaxAttempts {
+		logger.Log.ErrorContext(ctx, "Event delivery failed for the last time, giving up", "event_id", e.ID, "event_type", e.Type, "attempt", attempts, "error", err)
+		return d.outbox.MarkDead(ctx, e.ID, attempts, err.Error())
+	}
+	logger.Log.WarnContext(ctx, "Event delivery failed", "event_id", e.ID, "event_type", e.Type, "attempt", attempts, "error", err)
+	next := helpers.GetCurrentTimeStampUTC().Add(d.backoff(attempts))
+	return d.outbox.MarkFailed(ctx, e.ID, attempts, next, err.Error())
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.PollInterval
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxBackoff)
+}
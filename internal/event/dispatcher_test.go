@@ -0,0 +1,107 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/outbox"
+)
+
+// memOutbox is an outbox.Repository for a fixed set of events that are
+// always due.
+type memOutbox struct {
+	outbox.Repository
+	entries    map[int64]*outbox.Entry
+	dispatched map[int64]bool
+	dead       map[int64]bool
+	processed  map[processedKey]bool
+}
+
+type processedKey struct {
+	handler string
+	eventID int64
+}
+
+func newMemOutbox(events ...*model.Event) *memOutbox {
+	o := &memOutbox{
+		entries:    map[int64]*outbox.Entry{},
+		dispatched: map[int64]bool{},
+		dead:       map[int64]bool{},
+		processed:  map[processedKey]bool{},
+	}
+	for _, e := range events {
+		o.entries[e.ID] = &outbox.Entry{Event: e}
+	}
+	return o
+}
+
+func (o *memOutbox) ClaimBatch(ctx context.Context, limit int, lease time.Duration) ([]*outbox.Entry, error) {
+	var batch []*outbox.Entry
+	for id, entry := range o.entries {
+		if !o.dispatched[id] && !o.dead[id] {
+			c := *entry
+			batch = append(batch, &c)
+		}
+	}
+	return batch, nil
+}
+
+func (o *memOutbox) MarkDispatched(ctx context.Context, id int64) error {
+	o.dispatched[id] = true
+	return nil
+}
+
+func (o *memOutbox) MarkFailed(ctx context.Context, id int64, attempts int, nextAttempt time.Time, lastErr string) error {
+	o.entries[id].Attempts = attempts
+	return nil
+}
+
+func (o *memOutbox) MarkDead(ctx context.Context, id int64, attempts int, lastErr string) error {
+	o.entries[id].Attempts = attempts
+	o.dead[id] = true
+	return nil
+}
+
+func (o *memOutbox) IsProcessed(ctx context.Context, handler string, eventID int64) (bool, error) {
+	return o.processed[processedKey{handler, eventID}], nil
+}
+
+func (o *memOutbox) MarkProcessed(ctx context.Context, handler string, eventID int64) error {
+	o.processed[processedKey{handler, eventID}] = true
+	return nil
+}
+
+func TestDispatcherGivesUpOnPoisonEvent(t *testing.T) {
+	ctx := context.Background()
+	store := newMemOutbox(&model.Event{ID: 1, Type: model.EventUserBanned})
+
+	sessionsRuns := 0
+	bus := NewBus()
+	bus.Subscribe(model.EventUserBanned, "sessions", Idempotent(store, "sessions", func(ctx context.Context, e *model.Event) error {
+		sessionsRuns++
+		return nil
+	}))
+	bus.Subscribe(model.EventAll, "broken", func(ctx context.Context, e *model.Event) error {
+		return errors.New("always fails")
+	})
+
+	d := NewDispatcher(store, bus, DispatcherConfig{PollInterval: time.Millisecond, BatchSize: 10, MaxBackoff: time.Millisecond, MaxAttempts: 3})
+	for range 5 {
+		if _, err := d.DispatchBatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !store.dead[1] || store.dispatched[1] {
+		t.Fatalf("expected the event to be dead, dead=%v dispatched=%v", store.dead[1], store.dispatched[1])
+	}
+	if attempts := store.entries[1].Attempts; attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	if sessionsRuns != 1 {
+		t.Fatalf("idempotent subscriber ran %d times", sessionsRuns)
+	}
+}
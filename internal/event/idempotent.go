@@ -0,0 +1,34 @@
+package event
+
+import (
+	"context"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+// ProcessedStore remembers which events a handler has completed.
+// postgres.OutboxRepo implements it.
+type ProcessedStore interface {
+	IsProcessed(ctx context.Context, handler string, eventID int64) (bool, error)
+	MarkProcessed(ctx context.Context, handler string, eventID int64) error
+}
+
+// Idempotent wraps h so an event it already handled is skipped when the
+// dispatcher retries the event for another failing subscriber. A crash
+// between h returning and the event being marked still runs h twice, so h
+// should also tolerate duplicates where it can (e.g. by keying on e.ID).
+func Idempotent(store ProcessedStore, name string, h Handler) Handler {
+	return func(ctx context.Context, e *model.Event) error {
+		done, err := store.IsProcessed(ctx, name, e.ID)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if err := h(ctx, e); err != nil {
+			return err
+		}
+		return store.MarkProcessed(ctx, name, e.ID)
+	}
+}
@@ -0,0 +1,5 @@
+// Package memory implements repositories in process memory. They follow the
+// not-found and uniqueness rules of the postgres package, so services can be
+// exercised in tests and in demo mode without a database. Nothing is
+// persisted and writes are not rolled back on failure.
+package memory
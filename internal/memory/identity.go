@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/razedwell/go-hand/internal/model"
+	identityrepo "github.com/razedwell/go-hand/internal/repository/identity"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type identityKey struct{ provider, subject string }
+
+type IdentityRepo struct {
+	mu         sync.RWMutex
+	identities map[identityKey]model.Identity
+	nextID     int64
+}
+
+var _ identityrepo.Repository = (*IdentityRepo)(nil)
+
+func NewIdentityRepo() *IdentityRepo {
+	return &IdentityRepo{identities: map[identityKey]model.Identity{}}
+}
+
+func (r *IdentityRepo) FindIdentity(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.identities[identityKey{provider, subject}]
+	if !ok {
+		return nil, identityrepo.ErrNotFound
+	}
+	return &i, nil
+}
+
+func (r *IdentityRepo) CreateIdentity(ctx context.Context, i *model.Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := identityKey{i.Provider, i.Subject}
+	if _, taken := r.identities[key]; taken {
+		return identityrepo.ErrTaken
+	}
+	r.nextID++
+	i.ID = r.nextID
+	i.CreatedAt = helpers.GetCurrentTimeStampUTC()
+	r.identities[key] = *i
+	return nil
+}
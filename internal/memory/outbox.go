@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/razedwell/go-hand/internal/event"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+// Outbox keeps the events recorded by the in-memory repositories and by
+// Publish, in order. Nothing dispatches them; tests inspect Events.
+type Outbox struct {
+	mu     sync.Mutex
+	events []model.Event
+}
+
+var _ event.Publisher = (*Outbox)(nil)
+
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+func (o *Outbox) Publish(ctx context.Context, events ...*model.Event) error {
+	o.append("", events)
+	return nil
+}
+
+// Events returns a copy of every recorded event, oldest first.
+func (o *Outbox) Events() []model.Event {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]model.Event(nil), o.events...)
+}
+
+// append assigns sequence numbers like the outbox table does and fills in
+// subjectID for events that don't name a subject.
+func (o *Outbox) append(subjectID string, events []*model.Event) {
+	if o == nil || len(events) == 0 {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, e := range events {
+		if e.OccurredAt.IsZero() {
+			e.OccurredAt = helpers.GetCurrentTimeStampUTC()
+		}
+		e.ID = int64(len(o.events) + 1)
+		stored := *e
+		if stored.SubjectID == "" {
+			stored.SubjectID = subjectID
+		}
+		o.events = append(o.events, stored)
+	}
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+var errUnsupportedFilter = errors.New("unsupported filter")
+
+// userFields mirrors the filterable columns of postgres.UserRepo. Text
+// fields are returned as *string so a missing phone behaves like NULL.
+var userFields = map[string]func(u *model.User) any{
+	"id":         func(u *model.User) any { return u.ID },
+	"email":      func(u *model.User) any { return &u.Email },
+	"first_name": func(u *model.User) any { return &u.FirstName },
+	"last_name":  func(u *model.User) any { return &u.LastName },
+	"phone":      func(u *model.User) any { return u.Phone },
+	"is_active":  func(u *model.User) any { return u.IsActive },
+	"role":       func(u *model.User) any { role := string(u.Role); return &role },
+	"created_at": func(u *model.User) any { return u.CreatedAt },
+	"updated_at": func(u *model.User) any { return u.UpdatedAt },
+}
+
+// matchUser reports whether u satisfies every condition, following the
+// rules of postgres.buildWhere: text compares case-insensitively and NULL
+// matches nothing but the absence of "pr".
+func matchUser(u *model.User, conditions []query.Condition) (bool, error) {
+	for _, c := range conditions {
+		get, ok := userFields[c.Field]
+		if !ok {
+			return false, fmt.Errorf("%w: unknown field %q", errUnsupportedFilter, c.Field)
+		}
+		ok, err := match(get(u), c)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func match(v any, c query.Condition) (bool, error) {
+	if s, isText := v.(*string); isText {
+		return matchText(s, c)
+	}
+
+	switch c.Op {
+	case query.OpPresent:
+		return true, nil
+	case query.OpContains, query.OpStartsWith, query.OpEndsWith:
+		return false, fmt.Errorf("%w: %s on non-text field %q", errUnsupportedFilter, c.Op, c.Field)
+	}
+
+	order, err := compare(v, c.Value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %q: %w", c.Field, err)
+	}
+	return compared(order, c.Op)
+}
+
+func matchText(s *string, c query.Condition) (bool, error) {
+	if c.Op == query.OpPresent {
+		return s != nil && *s != "", nil
+	}
+	if s == nil {
+		return false, nil
+	}
+
+	value, want := strings.ToLower(*s), strings.ToLower(c.Value)
+	switch c.Op {
+	case query.OpEq:
+		return value == want, nil
+	case query.OpNe:
+		return value != want, nil
+	case query.OpContains:
+		return strings.Contains(value, want), nil
+	case query.OpStartsWith:
+		return strings.HasPrefix(value, want), nil
+	case query.OpEndsWith:
+		return strings.HasSuffix(value, want), nil
+	}
+	return compared(strings.Compare(*s, c.Value), c.Op)
+}
+
+// compare orders a non-text field value against the filter's string value.
+func compare(v any, raw string) (int, error) {
+	switch v := v.(type) {
+	case int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(v, n), nil
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(boolInt(v), boolInt(b)), nil
+	case time.Time:
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return 0, err
+		}
+		return v.Compare(t), nil
+	}
+	return 0, errUnsupportedFilter
+}
+
+func compared(order int, op query.Op) (bool, error) {
+	switch op {
+	case query.OpEq:
+		return order == 0, nil
+	case query.OpNe:
+		return order != 0, nil
+	case query.OpGt:
+		return order > 0, nil
+	case query.OpGe:
+		return order >= 0, nil
+	case query.OpLt:
+		return order < 0, nil
+	case query.OpLe:
+		return order <= 0, nil
+	}
+	return false, fmt.Errorf("%w: operator %q", errUnsupportedFilter, op)
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// page applies a filter's offset and limit to sorted rows.
+func page[T any](rows []T, filter query.Filter) []T {
+	if filter.Offset > 0 {
+		if filter.Offset >= len(rows) {
+			return nil
+		}
+		rows = rows[filter.Offset:]
+	}
+	if filter.Limit > 0 && filter.Limit < len(rows) {
+		rows = rows[:filter.Limit]
+	}
+	return rows
+}
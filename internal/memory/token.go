@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/token"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+var errDuplicateToken = errors.New("refresh token already exists")
+
+type TokenRepo struct {
+	mu     sync.RWMutex
+	tokens map[string]*model.RefreshToken // by hash
+	nextID int64
+}
+
+var _ token.Repository = (*TokenRepo)(nil)
+
+func NewTokenRepo() *TokenRepo {
+	return &TokenRepo{tokens: map[string]*model.RefreshToken{}}
+}
+
+func (r *TokenRepo) CreateRefreshToken(ctx context.Context, t *model.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tokens[t.TokenHash]; exists {
+		return errDuplicateToken
+	}
+
+	r.nextID++
+	stored := *t
+	stored.ID = r.nextID
+	stored.RevokedAt = nil
+	stored.CreatedAt = helpers.GetCurrentTimeStampUTC()
+	r.tokens[t.TokenHash] = &stored
+	return nil
+}
+
+func (r *TokenRepo) GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, token.ErrNotFound
+	}
+	rt := *t
+	return &rt, nil
+}
+
+func (r *TokenRepo) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.tokens[tokenHash]; ok {
+		now := helpers.GetCurrentTimeStampUTC()
+		t.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r *TokenRepo) RevokeAllUserTokens(ctx context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := helpers.GetCurrentTimeStampUTC()
+	for _, t := range r.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			revokedAt := now
+			t.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *TokenRepo) DeleteExpiredTokens(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoff := helpers.GetCurrentTimeStampUTC().Add(-24 * time.Hour)
+	for hash, t := range r.tokens {
+		if t.ExpiresAt.Before(cutoff) {
+			delete(r.tokens, hash)
+		}
+	}
+	return nil
+}
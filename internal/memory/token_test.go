@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/token"
+)
+
+func TestTokenRepoNotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTokenRepo()
+
+	if _, err := repo.GetRefreshToken(ctx, "missing"); !errors.Is(err, token.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	// Like the UPDATE in Postgres, revoking an unknown token is a no-op.
+	if err := repo.RevokeRefreshToken(ctx, "missing"); err != nil {
+		t.Fatalf("revoke unknown token: %v", err)
+	}
+}
+
+func TestTokenRepoRevoke(t *testing.T) {
+	ctx := context.Background()
+	repo := NewTokenRepo()
+	expires := time.Now().Add(time.Hour)
+
+	for _, rt := range []*model.RefreshToken{
+		{UserID: 1, TokenHash: "a", ExpiresAt: expires},
+		{UserID: 1, TokenHash: "b", ExpiresAt: expires},
+		{UserID: 2, TokenHash: "c", ExpiresAt: expires},
+	} {
+		if err := repo.CreateRefreshToken(ctx, rt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateRefreshToken(ctx, &model.RefreshToken{UserID: 2, TokenHash: "a", ExpiresAt: expires}); err == nil {
+		t.Fatal("expected a duplicate token hash to be rejected")
+	}
+
+	if err := repo.RevokeRefreshToken(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if rt, _ := repo.GetRefreshToken(ctx, "a"); rt.RevokedAt == nil {
+		t.Fatal("token not revoked")
+	}
+
+	if err := repo.RevokeAllUserTokens(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	for hash, revoked := range map[string]bool{"a": true, "b": true, "c": false} {
+		rt, err := repo.GetRefreshToken(ctx, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (rt.RevokedAt != nil) != revoked {
+			t.Errorf("token %s: revoked = %v, want %v", hash, rt.RevokedAt != nil, revoked)
+		}
+	}
+}
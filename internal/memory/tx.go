@@ -0,0 +1,18 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/razedwell/go-hand/internal/repository/transaction"
+)
+
+// TxManager runs units of work directly. Each repository call is atomic on
+// its own, but a failed unit of work keeps the writes made before the
+// failure.
+type TxManager struct{}
+
+var _ transaction.Manager = TxManager{}
+
+func (TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
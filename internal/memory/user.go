@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/query"
+	userrepo "github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type UserRepo struct {
+	mu      sync.RWMutex
+	users   map[int64]*model.User
+	byEmail map[string]int64
+	nextID  int64
+	outbox  *Outbox
+}
+
+var _ userrepo.Repository = (*UserRepo)(nil)
+
+// NewUserRepo returns an empty repository. Events passed to the write
+// methods are recorded in outbox, which may be nil to drop them.
+func NewUserRepo(outbox *Outbox) *UserRepo {
+	return &UserRepo{
+		users:   map[int64]*model.User{},
+		byEmail: map[string]int64{},
+		outbox:  outbox,
+	}
+}
+
+// Stored users are copied on the way in and out, so callers can't change
+// them without going through UpdateUser.
+func cloneUser(u *model.User) *model.User {
+	c := *u
+	return &c
+}
+
+func (r *UserRepo) FindUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byEmail[email]
+	if !ok {
+		return nil, userrepo.ErrNotFound
+	}
+	return cloneUser(r.users[id]), nil
+}
+
+func (r *UserRepo) FindUserById(ctx context.Context, id int64) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, userrepo.ErrNotFound
+	}
+	return cloneUser(u), nil
+}
+
+func (r *UserRepo) CreateUser(ctx context.Context, user *model.User, events ...*model.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, taken := r.byEmail[user.Email]; taken {
+		return userrepo.ErrEmailTaken
+	}
+
+	r.nextID++
+	now := helpers.GetCurrentTimeStampUTC()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	r.users[user.ID] = cloneUser(user)
+	r.byEmail[user.Email] = user.ID
+	r.outbox.append(strconv.FormatInt(user.ID, 10), events)
+	return nil
+}
+
+func (r *UserRepo) ListUsers(ctx context.Context, filter query.Filter) ([]*model.User, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*model.User
+	for _, u := range r.users {
+		ok, err := matchUser(u, filter.Conditions)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			matched = append(matched, u)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID < matched[j].ID })
+
+	total := len(matched)
+	matched = page(matched, filter)
+
+	users := make([]*model.User, len(matched))
+	for i, u := range matched {
+		users[i] = cloneUser(u)
+	}
+	return users, total, nil
+}
+
+func (r *UserRepo) UpdateUser(ctx context.Context, user *model.User, events ...*model.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[user.ID]
+	if !ok {
+		return userrepo.ErrNotFound
+	}
+	if id, taken := r.byEmail[user.Email]; taken && id != user.ID {
+		return userrepo.ErrEmailTaken
+	}
+
+	user.CreatedAt = existing.CreatedAt
+	user.UpdatedAt = helpers.GetCurrentTimeStampUTC()
+
+	delete(r.byEmail, existing.Email)
+	r.users[user.ID] = cloneUser(user)
+	r.byEmail[user.Email] = user.ID
+	r.outbox.append(strconv.FormatInt(user.ID, 10), events)
+	return nil
+}
+
+func (r *UserRepo) DeleteUser(ctx context.Context, id int64, events ...*model.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[id]
+	if !ok {
+		return userrepo.ErrNotFound
+	}
+	delete(r.users, id)
+	delete(r.byEmail, existing.Email)
+	r.outbox.append(strconv.FormatInt(id, 10), events)
+	return nil
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/razedwell/go-hand/internal/model"
+	userrepo "github.com/razedwell/go-hand/internal/repository/user"
+)
+
+// The memory repositories stand in for the Postgres ones, so they have to
+// return the same sentinel errors the services check for.
+
+func TestUserRepoEmailIsUnique(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepo(nil)
+
+	ada := &model.User{Email: "ada@example.com"}
+	if err := repo.CreateUser(ctx, ada); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateUser(ctx, &model.User{Email: "ada@example.com"}); !errors.Is(err, userrepo.ErrEmailTaken) {
+		t.Fatalf("create with a taken email: expected ErrEmailTaken, got %v", err)
+	}
+
+	grace := &model.User{Email: "grace@example.com"}
+	if err := repo.CreateUser(ctx, grace); err != nil {
+		t.Fatal(err)
+	}
+	grace.Email = ada.Email
+	if err := repo.UpdateUser(ctx, grace); !errors.Is(err, userrepo.ErrEmailTaken) {
+		t.Fatalf("update to a taken email: expected ErrEmailTaken, got %v", err)
+	}
+	if stored, err := repo.FindUserById(ctx, grace.ID); err != nil || stored.Email != "grace@example.com" {
+		t.Fatalf("failed update changed the stored user: %+v, %v", stored, err)
+	}
+
+	// Changing your own email frees the old one.
+	ada.Email = "ada@lovelace.example.com"
+	if err := repo.UpdateUser(ctx, ada); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateUser(ctx, &model.User{Email: "ada@example.com"}); err != nil {
+		t.Fatalf("old email still taken: %v", err)
+	}
+}
+
+func TestUserRepoNotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepo(nil)
+
+	if _, err := repo.FindUserById(ctx, 42); !errors.Is(err, userrepo.ErrNotFound) {
+		t.Errorf("FindUserById: expected ErrNotFound, got %v", err)
+	}
+	if _, err := repo.FindUserByEmail(ctx, "nobody@example.com"); !errors.Is(err, userrepo.ErrNotFound) {
+		t.Errorf("FindUserByEmail: expected ErrNotFound, got %v", err)
+	}
+	if err := repo.UpdateUser(ctx, &model.User{ID: 42, Email: "nobody@example.com"}); !errors.Is(err, userrepo.ErrNotFound) {
+		t.Errorf("UpdateUser: expected ErrNotFound, got %v", err)
+	}
+	if err := repo.DeleteUser(ctx, 42); !errors.Is(err, userrepo.ErrNotFound) {
+		t.Errorf("DeleteUser: expected ErrNotFound, got %v", err)
+	}
+
+	u := &model.User{Email: "ada@example.com"}
+	if err := repo.CreateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteUser(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.FindUserByEmail(ctx, u.Email); !errors.Is(err, userrepo.ErrNotFound) {
+		t.Errorf("deleted user still found by email: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// Domain event types. Webhook subscribers receive the same names.
+const (
+	EventUserRegistered  = "user.registered"
+	EventUserBanned      = "user.banned"
+	EventUserUnbanned    = "user.unbanned"
+	EventUserRoleChanged = "user.role_changed"
+	EventUserDeactivated = "user.deactivated"
+	EventUserDeleted     = "user.deleted"
+	EventSessionRevoked  = "session.revoked"
+	EventPasswordChanged = "password.changed"
+
+	// Membership changes in an organization. The subject is the member.
+	EventOrgMemberRoleChanged = "org.member_role_changed"
+	EventOrgMemberRemoved     = "org.member_removed"
+
+	// EventAll subscribes to every event type.
+	EventAll = "*"
+)
+
+// Event is a domain event. It is written to the outbox together with the
+// change it describes and dispatched to subscribers after commit, at least
+// once, so subscribers must tolerate duplicates.
+type Event struct {
+	ID          int64 // outbox sequence number, assigned on insert
+	Type        string
+	SubjectType string // e.g. "user"
+	SubjectID   string // filled in by the repository for rows it creates
+	Data        map[string]any
+	OccurredAt  time.Time
+}
+
+func NewUserEvent(eventType string, userID int64, data map[string]any) *Event {
+	e := &Event{Type: eventType, SubjectType: "user", Data: data}
+	if userID != 0 {
+		e.SubjectID = strconv.FormatInt(userID, 10)
+	}
+	return e
+}
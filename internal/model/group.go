@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type Group struct {
+	ID         int64
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ExternalID *string
+
+	DisplayName string
+	Members     []GroupMember
+}
+
+type GroupMember struct {
+	UserID  int64
+	Display string
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Identity links a user to an account at an external identity provider.
+// Provider is scoped to the issuer, e.g. "saml:https://idp.example.com", and
+// Subject is the ID the provider gives the user, which unlike the email
+// address cannot be claimed by another account there.
+type Identity struct {
+	ID        int64
+	UserID    int64
+	Provider  string
+	Subject   string
+	CreatedAt time.Time
+}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+type OrgRole string
+
+const (
+	OrgRoleOwner  OrgRole = "owner"
+	OrgRoleAdmin  OrgRole = "admin"
+	OrgRoleMember OrgRole = "member"
+)
+
+// CanManage reports whether the role may manage members of the organization.
+func (r OrgRole) CanManage() bool {
+	return r == OrgRoleOwner || r == OrgRoleAdmin
+}
+
+func (r OrgRole) Valid() bool {
+	return r == OrgRoleOwner || r == OrgRoleAdmin || r == OrgRoleMember
+}
+
+type Organization struct {
+	ID        int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Name string
+	Slug string
+}
+
+type Membership struct {
+	OrgID     int64
+	UserID    int64
+	Role      OrgRole
+	CreatedAt time.Time
+
+	// Populated by listings for display
+	OrgName   string
+	UserEmail string
+}
+
+type Invitation struct {
+	ID        int64
+	OrgID     int64
+	Email     string
+	Role      OrgRole
+	TokenHash string
+	InvitedBy *int64
+
+	ExpiresAt  time.Time
+	AcceptedAt *time.Time
+	AcceptedBy *int64
+	RevokedAt  *time.Time
+	SentAt     time.Time
+	CreatedAt  time.Time
+}
+
+// Pending reports whether the invitation can still be accepted at now.
+func (i *Invitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Session is the server-side state behind an opaque session ID.
+type Session struct {
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"`
+
+	// Active organization, if the user has switched into one
+	OrgID   int64  `json:"org_id,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
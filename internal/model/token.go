@@ -19,14 +19,20 @@ const (
 	VerifyEmail   VerificationType = "email"
 	VerifyPhone   VerificationType = "phone"
 	PasswordReset VerificationType = "password_reset"
+	MagicLink     VerificationType = "magic_link"
+	LoginCode     VerificationType = "login_code"
+	LoginSMS      VerificationType = "login_sms"
 )
 
 type VerificationCode struct {
 	ID        int64
 	UserID    int64
 	CodeHash  string
-	Type      VerificationType // email, phone, password_reset
+	Type      VerificationType // email, phone, password_reset, magic_link, login_code, login_sms
 	ExpiresAt time.Time
 	UsedAt    *time.Time
 	CreatedAt time.Time
+
+	Attempts    int     // failed guesses, for short numeric codes
+	BindingHash *string // ties the code to the browser that requested it
 }
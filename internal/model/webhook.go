@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// WebhookEventTypes lists the event types a subscription may name.
+var WebhookEventTypes = []string{
+	EventUserRegistered, EventUserBanned, EventUserUnbanned, EventUserRoleChanged,
+	EventUserDeactivated, EventUserDeleted, EventSessionRevoked, EventPasswordChanged,
+	EventOrgMemberRoleChanged, EventOrgMemberRemoved,
+}
+
+type WebhookSubscription struct {
+	ID        int64
+	URL       string
+	Secret    string // HMAC key shared with the receiver
+	Events    []string
+	IsActive  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Subscribed reports whether the subscription wants eventType.
+func (s *WebhookSubscription) Subscribed(eventType string) bool {
+	for _, e := range s.Events {
+		if e == EventAll || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead" // gave up after the last retry
+)
+
+// WebhookDelivery is one event queued for one subscription.
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	EventID        string // stable across retries, for receiver deduplication
+	EventType      string
+	Payload        []byte
+
+	Status         DeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastError      *string
+	LastStatusCode *int
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/platform/metrics"
+	"github.com/razedwell/go-hand/internal/platform/tracing"
+	"github.com/redis/go-redis/v9"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Instrument traces every command sent through client and records its
+// latency.
+func Instrument(client *redis.Client) {
+	client.AddHook(instrumentHook{})
+}
+
+type instrumentHook struct{}
+
+func (instrumentHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (instrumentHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		return observe(ctx, cmd.Name(), func(ctx context.Context) error {
+			return next(ctx, cmd)
+		})
+	}
+}
+
+func (instrumentHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		return observe(ctx, "pipeline", func(ctx context.Context) error {
+			return next(ctx, cmds)
+		})
+	}
+}
+
+func observe(ctx context.Context, command string, fn func(ctx context.Context) error) error {
+	ctx, span := tracing.StartClient(ctx, "redis "+command, semconv.DBSystemRedis)
+	start := time.Now()
+	err := fn(ctx)
+
+	result := "ok"
+	switch {
+	case errors.Is(err, redis.Nil):
+		// A missing key is an answer, not a failure.
+		result = "nil"
+		span.End()
+	case err != nil:
+		result = "error"
+		tracing.End(span, err)
+	default:
+		span.End()
+	}
+	metrics.RedisDuration.WithLabelValues(command, result).Observe(time.Since(start).Seconds())
+	return err
+}
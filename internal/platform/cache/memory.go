@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/security"
+)
+
+// MemoryStore is an in-process stand-in for RedisClient, for tests and for
+// running without Redis. Entries live only as long as the process.
+type MemoryStore struct {
+	mu       sync.Mutex
+	revoked  map[string]time.Time // id -> expiry
+	sessions map[string]memorySession
+}
+
+var (
+	_ security.Revocation   = (*MemoryStore)(nil)
+	_ security.SessionStore = (*MemoryStore)(nil)
+)
+
+type memorySession struct {
+	session   model.Session
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		revoked:  map[string]time.Time{},
+		sessions: map[string]memorySession{},
+	}
+}
+
+func (m *MemoryStore) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	// Expired IDs are only dropped here, so the map stays bounded by the
+	// number of live tokens.
+	for key, expiry := range m.revoked {
+		if !now.Before(expiry) {
+			delete(m.revoked, key)
+		}
+	}
+	if now.Before(expiresAt) {
+		m.revoked[id] = expiresAt
+	}
+	return nil
+}
+
+func (m *MemoryStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiry, ok := m.revoked[id]
+	return ok && time.Now().Before(expiry), nil
+}
+
+func (m *MemoryStore) SaveSession(ctx context.Context, id string, session *model.Session, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for key, s := range m.sessions {
+		if !now.Before(s.expiresAt) {
+			delete(m.sessions, key)
+		}
+	}
+	if ttl > 0 {
+		m.sessions[id] = memorySession{session: *session, expiresAt: now.Add(ttl)}
+	}
+	return nil
+}
+
+func (m *MemoryStore) TouchSession(ctx context.Context, id string, session *model.Session, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	s, ok := m.sessions[id]
+	if !ok || !now.Before(s.expiresAt) {
+		return false, nil
+	}
+	if ttl > 0 {
+		m.sessions[id] = memorySession{session: *session, expiresAt: now.Add(ttl)}
+	} else {
+		delete(m.sessions, id)
+	}
+	return true, nil
+}
+
+func (m *MemoryStore) GetSession(ctx context.Context, id string) (*model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok || !time.Now().Before(s.expiresAt) {
+		return nil, security.ErrSessionNotFound
+	}
+	session := s.session
+	return &session, nil
+}
+
+func (m *MemoryStore) DeleteSession(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *MemoryStore) DeleteUserSessions(ctx context.Context, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, s := range m.sessions {
+		if s.session.UserID == userID {
+			delete(m.sessions, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Allow counts a hit against key in a fixed window and reports whether the
+// caller is still within limit.
+func (r *RedisClient) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	pipe := r.Client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return incr.Val() <= int64(limit), nil
+}
@@ -18,6 +18,7 @@ func NewRedisClient(addr string, password string, db int) (*RedisClient, error)
 		Password: password, // "" by default
 		DB:       db,       // 0 by default
 	})
+	Instrument(rdb)
 
 	// Verify connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -29,3 +30,8 @@ func NewRedisClient(addr string, password string, db int) (*RedisClient, error)
 
 	return &RedisClient{Client: rdb}, nil
 }
+
+// Ping checks that Redis answers. It is used as a readiness check.
+func (r *RedisClient) Ping(ctx context.Context) error {
+	return r.Client.Ping(ctx).Err()
+}
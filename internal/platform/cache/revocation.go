@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/security"
+)
+
+var _ security.Revocation = (*RedisClient)(nil)
+
+const revokedPrefix = "revoked:"
+
+func (r *RedisClient) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return r.Client.Set(ctx, revokedPrefix+id, 1, ttl).Err()
+}
+
+func (r *RedisClient) IsRevoked(ctx context.Context, id string) (bool, error) {
+	n, err := r.Client.Exists(ctx, revokedPrefix+id).Result()
+	return n > 0, err
+}
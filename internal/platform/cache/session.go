@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ security.SessionStore = (*RedisClient)(nil)
+
+const (
+	sessionPrefix     = "session:"
+	userSessionPrefix = "user_sessions:"
+)
+
+// SaveSession stores session under id and indexes it by user, so that
+// DeleteUserSessions can find it. The index outlives every session in it
+// and may list IDs that have already expired.
+func (r *RedisClient) SaveSession(ctx context.Context, id string, session *model.Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	userKey := userSessionPrefix + strconv.FormatInt(session.UserID, 10)
+
+	pipe := r.Client.TxPipeline()
+	pipe.Set(ctx, sessionPrefix+id, data, ttl)
+	pipe.SAdd(ctx, userKey, id)
+	pipe.ExpireNX(ctx, userKey, ttl)
+	pipe.ExpireGT(ctx, userKey, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisClient) TouchSession(ctx context.Context, id string, session *model.Session, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return false, err
+	}
+	userKey := userSessionPrefix + strconv.FormatInt(session.UserID, 10)
+
+	pipe := r.Client.TxPipeline()
+	set := pipe.SetXX(ctx, sessionPrefix+id, data, ttl)
+	pipe.ExpireGT(ctx, userKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+	return set.Val(), nil
+}
+
+func (r *RedisClient) GetSession(ctx context.Context, id string) (*model.Session, error) {
+	data, err := r.Client.Get(ctx, sessionPrefix+id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, security.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	var session model.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *RedisClient) DeleteSession(ctx context.Context, id string) error {
+	return r.Client.Del(ctx, sessionPrefix+id).Err()
+}
+
+func (r *RedisClient) DeleteUserSessions(ctx context.Context, userID int64) error {
+	userKey := userSessionPrefix + strconv.FormatInt(userID, 10)
+	ids, err := r.Client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{userKey}
+	for _, id := range ids {
+		keys = append(keys, sessionPrefix+id)
+	}
+	return r.Client.Del(ctx, keys...).Err()
+}
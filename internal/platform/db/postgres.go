@@ -1,8 +1,9 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"fmt"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -15,11 +16,15 @@ func BuildDBUrl(user, password, host, port, dbname, sslmode, timezone string) st
 func NewClient(url string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", url)
 	if err != nil {
-		return nil, errors.New("failed to open database connection")
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, errors.New("failed to ping database")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	db.SetMaxOpenConns(25)                 // Max total connections
@@ -0,0 +1,103 @@
+// Package health tracks whether the service and its dependencies are able
+// to serve traffic.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckFunc reports whether a dependency is usable. It must respect ctx.
+type CheckFunc func(ctx context.Context) error
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs the registered dependency checks and holds the readiness
+// flag that is cleared during shutdown.
+type Checker struct {
+	timeout time.Duration
+	checks  []check
+	ready   atomic.Bool
+}
+
+// NewChecker returns a ready Checker that gives each check timeout to
+// answer.
+func NewChecker(timeout time.Duration) *Checker {
+	c := &Checker{timeout: timeout}
+	c.ready.Store(true)
+	return c
+}
+
+// Add registers a dependency check. Call it before serving traffic.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name, fn})
+}
+
+// SetReady flips readiness. Clear it when shutdown starts so load
+// balancers stop sending traffic while requests drain.
+func (c *Checker) SetReady(ready bool) {
+	c.ready.Store(ready)
+}
+
+type Result struct {
+	Status   Status `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status       Status            `json:"status"`
+	ShuttingDown bool              `json:"shutting_down,omitempty"`
+	Checks       map[string]Result `json:"checks"`
+}
+
+// Check runs every dependency check concurrently. The report is up only
+// when all checks pass and the service is not shutting down.
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := chk.fn(ctx)
+			res := Result{Status: StatusUp, Duration: time.Since(start).String()}
+			if err != nil {
+				res.Status = StatusDown
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[chk.name] = res
+			if err != nil {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !c.ready.Load() {
+		report.Status = StatusDown
+		report.ShuttingDown = true
+	}
+	return report
+}
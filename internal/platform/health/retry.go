@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+)
+
+type RetryConfig struct {
+	Attempts   int           // tries in total; 1 or less fails fast
+	Backoff    time.Duration // wait after the first failure, doubled each time
+	MaxBackoff time.Duration
+}
+
+// Retry calls connect until it succeeds, the attempts run out or ctx is
+// cancelled, so the service can start before its dependencies are up.
+func Retry[T any](ctx context.Context, name string, cfg RetryConfig, connect func() (T, error)) (T, error) {
+	backoff := cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		v, err := connect()
+		if err == nil || attempt >= cfg.Attempts {
+			return v, err
+		}
+		logger.Log.WarnContext(ctx, "Dependency unavailable, retrying", "dependency", name, "attempt", attempt, "retry_in", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, fmt.Errorf("%s: %w", name, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, cfg.MaxBackoff)
+	}
+}
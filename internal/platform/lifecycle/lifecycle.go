@@ -0,0 +1,109 @@
+// Package lifecycle starts and stops the parts of the application in
+// dependency order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+)
+
+// Hook is one component. Either function may be nil; Stop gets a context
+// that expires after StopTimeout.
+type Hook struct {
+	Name        string
+	Start       func(ctx context.Context) error
+	Stop        func(ctx context.Context) error
+	StopTimeout time.Duration // 0 uses the lifecycle's default
+}
+
+// Lifecycle starts hooks in the order they were appended and stops them in
+// reverse, so append a component after the ones it depends on.
+type Lifecycle struct {
+	stopTimeout time.Duration
+	hooks       []Hook
+	started     int
+}
+
+func New(stopTimeout time.Duration) *Lifecycle {
+	return &Lifecycle{stopTimeout: stopTimeout}
+}
+
+func (l *Lifecycle) Append(h Hook) {
+	l.hooks = append(l.hooks, h)
+}
+
+// Go runs job in the background between Start and Stop. Stop cancels the
+// job's context and waits up to stopTimeout for it to return.
+func (l *Lifecycle) Go(name string, stopTimeout time.Duration, job func(ctx context.Context)) {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	l.Append(Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			ctx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+			go func() {
+				defer close(done)
+				job(ctx)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+		StopTimeout: stopTimeout,
+	})
+}
+
+// Start runs the Start hooks in order. If one fails, the hooks already
+// started are stopped and the error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	for _, h := range l.hooks {
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				err = fmt.Errorf("failed to start %s: %w", h.Name, err)
+				return errors.Join(err, l.Stop(ctx))
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop runs the Stop hooks of the started components in reverse order,
+// each within its own timeout. A hook that fails or times out does not keep
+// the rest from stopping; all errors are returned together.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.Stop == nil {
+			continue
+		}
+		timeout := h.StopTimeout
+		if timeout == 0 {
+			timeout = l.stopTimeout
+		}
+
+		stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		start := time.Now()
+		err := h.Stop(stopCtx)
+		cancel()
+		if err != nil {
+			logger.Log.ErrorContext(ctx, "Failed to stop component", "component", h.Name, "error", err)
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", h.Name, err))
+			continue
+		}
+		logger.Log.InfoContext(ctx, "Stopped component", "component", h.Name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
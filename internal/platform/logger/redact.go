@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never logged. Keys are
+// matched case-insensitively and by suffix, so "refresh_token" and
+// "db_password" are covered too.
+var sensitiveKeys = []string{
+	"password", "secret", "token", "authorization", "cookie", "csrf", "otp",
+}
+
+// sensitiveHeaders are dropped from logged http.Header values.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Csrf-Token"}
+
+var (
+	// Bearer credentials and JWTs can end up inside error messages.
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`)
+	jwtPattern    = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.MessageKey && sensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case http.Header:
+			return slog.Any(a.Key, redactHeader(v))
+		case error:
+			return slog.String(a.Key, Redact(v.Error()))
+		}
+	}
+	return a
+}
+
+// Redact masks bearer credentials and JWTs in free text.
+func Redact(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	return jwtPattern.ReplaceAllString(s, redacted)
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if key == k || strings.HasSuffix(key, "_"+k) || strings.HasSuffix(key, "."+k) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeader(h http.Header) http.Header {
+	clone := h.Clone()
+	for _, name := range sensitiveHeaders {
+		if _, ok := clone[name]; ok {
+			clone[name] = []string{redacted}
+		}
+	}
+	return clone
+}
@@ -0,0 +1,44 @@
+package sms
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// NormalizeE164 converts a user-entered number to E.164 (+<country><number>).
+// Numbers without an international prefix are assumed to belong to
+// defaultCountryCode, with a leading trunk "0" dropped.
+func NormalizeE164(raw string, defaultCountryCode string) (string, error) {
+	s := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '\t':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+
+	var digits string
+	switch {
+	case strings.HasPrefix(s, "+"):
+		digits = s[1:]
+	case strings.HasPrefix(s, "00"):
+		digits = s[2:]
+	default:
+		if defaultCountryCode == "" {
+			return "", ErrInvalidPhone
+		}
+		digits = strings.TrimPrefix(defaultCountryCode, "+") + strings.TrimPrefix(s, "0")
+	}
+
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidPhone
+		}
+	}
+	return "+" + digits, nil
+}
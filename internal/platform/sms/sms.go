@@ -0,0 +1,53 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+)
+
+// Sender delivers a text message to an E.164 phone number.
+type Sender interface {
+	Send(ctx context.Context, to string, body string) error
+}
+
+// LogSender writes messages to the application log. Development only.
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(ctx context.Context, to string, body string) error {
+	logger.Log.InfoContext(ctx, "SMS", "to", to, "body", body)
+	return nil
+}
+
+// FileSender appends messages to a file, which is handy for end-to-end tests
+// that need to read the code back.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (s *FileSender) Send(ctx context.Context, to string, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open sms file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().UTC().Format(time.RFC3339), to, body)
+	return err
+}
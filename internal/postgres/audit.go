@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/audit"
+	"github.com/razedwell/go-hand/internal/repository/query"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type AuditRepo struct {
+	db *sql.DB
+}
+
+var _ audit.Repository = (*AuditRepo)(nil)
+
+func NewAuditRepo(db *sql.DB) *AuditRepo {
+	return &AuditRepo{db: db}
+}
+
+// auditChainLock is the advisory lock key that serializes appends, so two
+// events can't claim the same position in a segment.
+const auditChainLock = 0x61756469 // "audi"
+
+// AppendEvent deliberately ignores a transaction bound to ctx: an audit
+// record must survive the caller rolling back, e.g. after a failed login.
+func (r *AuditRepo) AppendEvent(ctx context.Context, event *model.AuditEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.New("failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return errors.New("failed to lock audit chain")
+	}
+
+	// Timestamp under the lock so chain order and time order agree.
+	event.CreatedAt = helpers.GetCurrentTimeStampUTC().Truncate(time.Microsecond)
+	event.Segment = event.CreatedAt.Format(time.DateOnly)
+
+	var prevSeq int64
+	var prevHash string
+	err = tx.QueryRowContext(ctx,
+		`SELECT seq, hash FROM audit_events WHERE segment = $1 ORDER BY seq DESC LIMIT 1`, event.Segment,
+	).Scan(&prevSeq, &prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return errors.New("failed to read audit chain head")
+	}
+	event.Seq = prevSeq + 1
+	event.PrevHash = prevHash
+	event.Hash = event.ChainHash()
+
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil || event.Metadata == nil {
+		metadata = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO audit_events (
+			created_at, actor_id, action, target_type, target_id, ip, user_agent, metadata,
+			segment, seq, prev_hash, hash
+		)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, $11, $12)
+		RETURNING id
+	`
+	err = tx.QueryRowContext(ctx, query,
+		event.CreatedAt, event.ActorID, event.Action, event.TargetType, event.TargetID, event.IP, event.UserAgent, metadata,
+		event.Segment, event.Seq, event.PrevHash, event.Hash,
+	).Scan(&event.ID)
+	if err != nil {
+		return errors.New("failed to append audit event")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New("failed to commit audit event")
+	}
+	return nil
+}
+
+const auditEventColumns = `
+	id, created_at, actor_id, action,
+	COALESCE(target_type, ''), COALESCE(target_id, ''),
+	COALESCE(ip, ''), COALESCE(user_agent, ''), metadata,
+	COALESCE(segment::text, ''), COALESCE(seq, 0), COALESCE(prev_hash, ''), COALESCE(hash, '')
+`
+
+func scanAuditEvents(rows *sql.Rows) ([]*model.AuditEvent, error) {
+	var events []*model.AuditEvent
+	for rows.Next() {
+		event := &model.AuditEvent{}
+		var metadata []byte
+		err := rows.Scan(
+			&event.ID, &event.CreatedAt, &event.ActorID, &event.Action,
+			&event.TargetType, &event.TargetID,
+			&event.IP, &event.UserAgent, &metadata,
+			&event.Segment, &event.Seq, &event.PrevHash, &event.Hash,
+		)
+		if err != nil {
+			return nil, errors.New("failed to scan audit event")
+		}
+		if err := json.Unmarshal(metadata, &event.Metadata); err != nil {
+			return nil, errors.New("failed to decode audit metadata")
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("failed to list audit events")
+	}
+	return events, nil
+}
+
+var auditColumns = map[string]column{
+	"id":          {name: "id"},
+	"created_at":  {name: "created_at"},
+	"actor_id":    {name: "actor_id"},
+	"action":      {name: "action", text: true},
+	"target_type": {name: "target_type", text: true},
+	"target_id":   {name: "target_id", text: true},
+	"ip":          {name: "ip", text: true},
+}
+
+func (r *AuditRepo) ListEvents(ctx context.Context, filter query.Filter) ([]*model.AuditEvent, int, error) {
+	var args []any
+	where, err := buildWhere(filter.Conditions, auditColumns, &args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_events`+where, args...).Scan(&total); err != nil {
+		return nil, 0, errors.New("failed to count audit events")
+	}
+
+	query := `SELECT ` + auditEventColumns + ` FROM audit_events` + where + ` ORDER BY id DESC` + buildPage(filter, &args)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, errors.New("failed to list audit events")
+	}
+	defer rows.Close()
+
+	events, err := scanAuditEvents(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+func (r *AuditRepo) ListSegments(ctx context.Context, from string, to string) ([]string, error) {
+	query := `
+		SELECT DISTINCT segment::text FROM audit_events
+		WHERE segment IS NOT NULL
+			AND segment >= COALESCE(NULLIF($1, '')::date, segment)
+			AND segment <= COALESCE(NULLIF($2, '')::date, segment)
+		ORDER BY 1
+	`
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, errors.New("failed to list audit segments")
+	}
+	defer rows.Close()
+
+	var segments []string
+	for rows.Next() {
+		var segment string
+		if err := rows.Scan(&segment); err != nil {
+			return nil, errors.New("failed to scan audit segment")
+		}
+		segments = append(segments, segment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("failed to list audit segments")
+	}
+	return segments, nil
+}
+
+func (r *AuditRepo) ListSegmentEvents(ctx context.Context, segment string, afterSeq int64, limit int) ([]*model.AuditEvent, error) {
+	query := `SELECT ` + auditEventColumns + ` FROM audit_events
+		WHERE segment = $1 AND seq > $2 ORDER BY seq LIMIT $3`
+	rows, err := r.db.QueryContext(ctx, query, segment, afterSeq, limit)
+	if err != nil {
+		return nil, errors.New("failed to list audit segment")
+	}
+	defer rows.Close()
+	return scanAuditEvents(rows)
+}
+
+func (r *AuditRepo) SegmentHeads(ctx context.Context, since string) ([]*model.AuditEvent, error) {
+	query := `SELECT DISTINCT ON (segment) ` + auditEventColumns + ` FROM audit_events
+		WHERE segment >= $1::date ORDER BY segment, seq DESC`
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, errors.New("failed to list audit segment heads")
+	}
+	defer rows.Close()
+	return scanAuditEvents(rows)
+}
+
+const auditCheckpointColumns = `id, segment::text, seq, hash, key_id, signature, created_at`
+
+func scanAuditCheckpoints(rows *sql.Rows) ([]*model.AuditCheckpoint, error) {
+	var checkpoints []*model.AuditCheckpoint
+	for rows.Next() {
+		cp := &model.AuditCheckpoint{}
+		if err := rows.Scan(&cp.ID, &cp.Segment, &cp.Seq, &cp.Hash, &cp.KeyID, &cp.Signature, &cp.CreatedAt); err != nil {
+			return nil, errors.New("failed to scan audit checkpoint")
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("failed to list audit checkpoints")
+	}
+	return checkpoints, nil
+}
+
+func (r *AuditRepo) CreateCheckpoint(ctx context.Context, cp *model.AuditCheckpoint) error {
+	query := `
+		INSERT INTO audit_checkpoints (segment, seq, hash, key_id, signature)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, cp.Segment, cp.Seq, cp.Hash, cp.KeyID, cp.Signature).
+		Scan(&cp.ID, &cp.CreatedAt)
+	if err != nil {
+		return errors.New("failed to create audit checkpoint")
+	}
+	return nil
+}
+
+func (r *AuditRepo) ListCheckpoints(ctx context.Context, segment string) ([]*model.AuditCheckpoint, error) {
+	query := `SELECT ` + auditCheckpointColumns + ` FROM audit_checkpoints WHERE segment = $1 ORDER BY seq`
+	rows, err := r.db.QueryContext(ctx, query, segment)
+	if err != nil {
+		return nil, errors.New("failed to list audit checkpoints")
+	}
+	defer rows.Close()
+	return scanAuditCheckpoints(rows)
+}
+
+func (r *AuditRepo) LatestCheckpoints(ctx context.Context, since string) ([]*model.AuditCheckpoint, error) {
+	query := `SELECT DISTINCT ON (segment) ` + auditCheckpointColumns + ` FROM audit_checkpoints
+		WHERE segment >= $1::date ORDER BY segment, seq DESC`
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, errors.New("failed to list audit checkpoints")
+	}
+	defer rows.Close()
+	return scanAuditCheckpoints(rows)
+}
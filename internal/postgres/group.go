@@ -0,0 +1,258 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/razedwell/go-hand/internal/model"
+	grouprepo "github.com/razedwell/go-hand/internal/repository/group"
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+type GroupRepo struct {
+	db *sql.DB
+}
+
+var _ grouprepo.Repository = (*GroupRepo)(nil)
+
+func NewGroupRepo(db *sql.DB) *GroupRepo {
+	return &GroupRepo{db: db}
+}
+
+var groupColumns = map[string]column{
+	"id":           {name: "id"},
+	"display_name": {name: "display_name", text: true},
+	"external_id":  {name: "external_id", text: true},
+	"created_at":   {name: "created_at"},
+	"updated_at":   {name: "updated_at"},
+}
+
+// memberField filters groups by the ID of one of their members.
+const memberField = "member_id"
+
+func (r *GroupRepo) CreateGroup(ctx context.Context, group *model.Group) error {
+	const query = `
+		INSERT INTO groups (display_name, external_id)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		err := conn(ctx, r.db).QueryRowContext(ctx, query, group.DisplayName, group.ExternalID).
+			Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return grouprepo.ErrNameTaken
+			}
+			return errors.New("failed to create group")
+		}
+		return insertMembers(ctx, conn(ctx, r.db), group.ID, memberIDs(group.Members))
+	})
+}
+
+func (r *GroupRepo) FindGroupById(ctx context.Context, id int64) (*model.Group, error) {
+	const query = `
+		SELECT id, created_at, updated_at, external_id, display_name
+		FROM groups
+		WHERE id = $1
+	`
+
+	group := &model.Group{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+		&group.ID, &group.CreatedAt, &group.UpdatedAt, &group.ExternalID, &group.DisplayName,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, grouprepo.ErrNotFound
+		}
+		return nil, errors.New("failed to query group by id")
+	}
+
+	if err := r.loadMembers(ctx, []*model.Group{group}); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (r *GroupRepo) ListGroups(ctx context.Context, filter query.Filter) ([]*model.Group, int, error) {
+	var (
+		args       []any
+		conditions []query.Condition
+		clauses    []string
+	)
+	for _, c := range filter.Conditions {
+		if c.Field != memberField {
+			conditions = append(conditions, c)
+			continue
+		}
+		if c.Op != query.OpEq {
+			return nil, 0, fmt.Errorf("%w: %s on %q", errUnsupportedFilter, c.Op, c.Field)
+		}
+		args = append(args, c.Value)
+		clauses = append(clauses, fmt.Sprintf("id IN (SELECT group_id FROM group_members WHERE user_id = $%d)", len(args)))
+	}
+
+	where, err := buildWhere(conditions, groupColumns, &args)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(clauses) > 0 {
+		if where == "" {
+			where = " WHERE "
+		} else {
+			where += " AND "
+		}
+		where += strings.Join(clauses, " AND ")
+	}
+
+	var total int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM groups`+where, args...).Scan(&total); err != nil {
+		return nil, 0, errors.New("failed to count groups")
+	}
+
+	query := `SELECT id, created_at, updated_at, external_id, display_name FROM groups` +
+		where + ` ORDER BY id` + buildPage(filter, &args)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, errors.New("failed to list groups")
+	}
+	defer rows.Close()
+
+	var groups []*model.Group
+	for rows.Next() {
+		group := &model.Group{}
+		if err := rows.Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt, &group.ExternalID, &group.DisplayName); err != nil {
+			return nil, 0, errors.New("failed to scan group")
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.New("failed to list groups")
+	}
+
+	if err := r.loadMembers(ctx, groups); err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+func (r *GroupRepo) UpdateGroup(ctx context.Context, group *model.Group) error {
+	const query = `
+		UPDATE groups SET display_name = $1, external_id = $2
+		WHERE id = $3
+		RETURNING updated_at
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, group.DisplayName, group.ExternalID, group.ID).Scan(&group.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return grouprepo.ErrNotFound
+		}
+		if isUniqueViolation(err) {
+			return grouprepo.ErrNameTaken
+		}
+		return errors.New("failed to update group")
+	}
+	return nil
+}
+
+func (r *GroupRepo) DeleteGroup(ctx context.Context, id int64) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM groups WHERE id = $1`, id)
+	if err != nil {
+		return errors.New("failed to delete group")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return grouprepo.ErrNotFound
+	}
+	return nil
+}
+
+func (r *GroupRepo) AddMembers(ctx context.Context, groupID int64, userIDs []int64) error {
+	return insertMembers(ctx, conn(ctx, r.db), groupID, userIDs)
+}
+
+func (r *GroupRepo) RemoveMembers(ctx context.Context, groupID int64, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	const query = `DELETE FROM group_members WHERE group_id = $1 AND user_id = ANY($2)`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, groupID, pq.Array(userIDs)); err != nil {
+		return errors.New("failed to remove group members")
+	}
+	return nil
+}
+
+func (r *GroupRepo) ReplaceMembers(ctx context.Context, groupID int64, userIDs []int64) error {
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		if _, err := q.ExecContext(ctx, `DELETE FROM group_members WHERE group_id = $1`, groupID); err != nil {
+			return errors.New("failed to replace group members")
+		}
+		return insertMembers(ctx, q, groupID, userIDs)
+	})
+}
+
+func insertMembers(ctx context.Context, db dbtx, groupID int64, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	const query = `
+		INSERT INTO group_members (group_id, user_id)
+		SELECT $1, unnest($2::bigint[])
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := db.ExecContext(ctx, query, groupID, pq.Array(userIDs)); err != nil {
+		return errors.New("failed to add group members")
+	}
+	return nil
+}
+
+func (r *GroupRepo) loadMembers(ctx context.Context, groups []*model.Group) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(groups))
+	byID := make(map[int64]*model.Group, len(groups))
+	for i, g := range groups {
+		ids[i] = g.ID
+		byID[g.ID] = g
+	}
+
+	const query = `
+		SELECT gm.group_id, u.id, u.email
+		FROM group_members gm
+		JOIN users u ON u.id = gm.user_id
+		WHERE gm.group_id = ANY($1)
+		ORDER BY gm.group_id, u.id
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return errors.New("failed to load group members")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var groupID int64
+		var m model.GroupMember
+		if err := rows.Scan(&groupID, &m.UserID, &m.Display); err != nil {
+			return errors.New("failed to scan group member")
+		}
+		byID[groupID].Members = append(byID[groupID].Members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return errors.New("failed to load group members")
+	}
+	return nil
+}
+
+func memberIDs(members []model.GroupMember) []int64 {
+	ids := make([]int64, len(members))
+	for i, m := range members {
+		ids[i] = m.UserID
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/identity"
+)
+
+type IdentityRepo struct {
+	db *sql.DB
+}
+
+var _ identity.Repository = (*IdentityRepo)(nil)
+
+func NewIdentityRepo(db *sql.DB) *IdentityRepo {
+	return &IdentityRepo{db: db}
+}
+
+func (r *IdentityRepo) FindIdentity(ctx context.Context, provider string, subject string) (*model.Identity, error) {
+	query := `SELECT id, user_id, provider, subject, created_at FROM user_identities WHERE provider = $1 AND subject = $2`
+	i := &model.Identity{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, provider, subject).
+		Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, identity.ErrNotFound
+		}
+		return nil, errors.New("failed to find identity")
+	}
+	return i, nil
+}
+
+func (r *IdentityRepo) CreateIdentity(ctx context.Context, i *model.Identity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, i.UserID, i.Provider, i.Subject).Scan(&i.ID, &i.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return identity.ErrTaken
+		}
+		return errors.New("failed to create identity")
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/invitation"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type InvitationRepo struct {
+	db *sql.DB
+}
+
+var _ invitation.Repository = (*InvitationRepo)(nil)
+
+func NewInvitationRepo(db *sql.DB) *InvitationRepo {
+	return &InvitationRepo{db: db}
+}
+
+const invitationColumns = `
+	id, org_id, email, role, token_hash, invited_by,
+	expires_at, accepted_at, accepted_by, revoked_at, sent_at, created_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanInvitation(row rowScanner) (*model.Invitation, error) {
+	inv := &model.Invitation{}
+	err := row.Scan(
+		&inv.ID, &inv.OrgID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy,
+		&inv.ExpiresAt, &inv.AcceptedAt, &inv.AcceptedBy, &inv.RevokedAt, &inv.SentAt, &inv.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, invitation.ErrNotFound
+		}
+		return nil, errors.New("failed to scan invitation")
+	}
+	return inv, nil
+}
+
+func (r *InvitationRepo) CreateInvitation(ctx context.Context, inv *model.Invitation) error {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO org_invitations (org_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, sent_at, created_at
+	`
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, orgID, inv.Email, inv.Role, inv.TokenHash, inv.InvitedBy, inv.ExpiresAt).
+		Scan(&inv.ID, &inv.SentAt, &inv.CreatedAt)
+	if err != nil {
+		return errors.New("failed to create invitation")
+	}
+	inv.OrgID = orgID
+	return nil
+}
+
+func (r *InvitationRepo) ListInvitations(ctx context.Context) ([]*model.Invitation, error) {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + invitationColumns + ` FROM org_invitations WHERE org_id = $1 ORDER BY created_at DESC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, errors.New("failed to list invitations")
+	}
+	defer rows.Close()
+
+	var invitations []*model.Invitation
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("failed to list invitations")
+	}
+	return invitations, nil
+}
+
+func (r *InvitationRepo) FindInvitationById(ctx context.Context, id int64) (*model.Invitation, error) {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + invitationColumns + ` FROM org_invitations WHERE id = $1 AND org_id = $2`
+	return scanInvitation(conn(ctx, r.db).QueryRowContext(ctx, query, id, orgID))
+}
+
+func (r *InvitationRepo) RenewInvitation(ctx context.Context, id int64, tokenHash string, expiresAt time.Time) error {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := helpers.GetCurrentTimeStampUTC()
+	query := `
+		UPDATE org_invitations SET token_hash = $1, expires_at = $2, sent_at = $3
+		WHERE id = $4 AND org_id = $5 AND accepted_at IS NULL AND revoked_at IS NULL
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, tokenHash, expiresAt, now, id, orgID)
+	if err != nil {
+		return errors.New("failed to renew invitation")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return invitation.ErrNotFound
+	}
+	return nil
+}
+
+func (r *InvitationRepo) RevokeInvitation(ctx context.Context, id int64) error {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := helpers.GetCurrentTimeStampUTC()
+	query := `
+		UPDATE org_invitations SET revoked_at = $1
+		WHERE id = $2 AND org_id = $3 AND accepted_at IS NULL AND revoked_at IS NULL
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, now, id, orgID)
+	if err != nil {
+		return errors.New("failed to revoke invitation")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return invitation.ErrNotFound
+	}
+	return nil
+}
+
+func (r *InvitationRepo) RevokePendingInvitations(ctx context.Context, email string) error {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := helpers.GetCurrentTimeStampUTC()
+	query := `
+		UPDATE org_invitations SET revoked_at = $1
+		WHERE org_id = $2 AND LOWER(email) = LOWER($3) AND accepted_at IS NULL AND revoked_at IS NULL
+	`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, now, orgID, email); err != nil {
+		return errors.New("failed to revoke invitations")
+	}
+	return nil
+}
+
+func (r *InvitationRepo) FindInvitationByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM org_invitations WHERE token_hash = $1`
+	return scanInvitation(conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash))
+}
+
+func (r *InvitationRepo) MarkInvitationAccepted(ctx context.Context, id int64, userID int64) error {
+	now := helpers.GetCurrentTimeStampUTC()
+	query := `
+		UPDATE org_invitations SET accepted_at = $1, accepted_by = $2
+		WHERE id = $3 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > $1
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, now, userID, id)
+	if err != nil {
+		return errors.New("failed to accept invitation")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return invitation.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/organization"
+)
+
+type OrganizationRepo struct {
+	db *sql.DB
+}
+
+var _ organization.Repository = (*OrganizationRepo)(nil)
+
+func NewOrganizationRepo(db *sql.DB) *OrganizationRepo {
+	return &OrganizationRepo{db: db}
+}
+
+func (r *OrganizationRepo) CreateOrganization(ctx context.Context, org *model.Organization, ownerID int64) error {
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+
+		query := `INSERT INTO organizations (name, slug) VALUES ($1, $2) RETURNING id, created_at, updated_at`
+		err := q.QueryRowContext(ctx, query, org.Name, org.Slug).Scan(&org.ID, &org.CreatedAt, &org.UpdatedAt)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return organization.ErrSlugTaken
+			}
+			return errors.New("failed to create organization")
+		}
+
+		query = `INSERT INTO org_memberships (org_id, user_id, role) VALUES ($1, $2, $3)`
+		if _, err := q.ExecContext(ctx, query, org.ID, ownerID, model.OrgRoleOwner); err != nil {
+			return errors.New("failed to create organization")
+		}
+		return nil
+	})
+}
+
+func (r *OrganizationRepo) FindOrganizationById(ctx context.Context, id int64) (*model.Organization, error) {
+	query := `SELECT id, created_at, updated_at, name, slug FROM organizations WHERE id = $1`
+
+	org := &model.Organization{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&org.ID, &org.CreatedAt, &org.UpdatedAt, &org.Name, &org.Slug)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, organization.ErrNotFound
+		}
+		return nil, errors.New("failed to query organization by id")
+	}
+	return org, nil
+}
+
+func (r *OrganizationRepo) FindMembership(ctx context.Context, orgID int64, userID int64) (*model.Membership, error) {
+	query := `
+		SELECT m.org_id, m.user_id, m.role, m.created_at, o.name
+		FROM org_memberships m
+		JOIN organizations o ON o.id = m.org_id
+		WHERE m.org_id = $1 AND m.user_id = $2
+	`
+
+	m := &model.Membership{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, orgID, userID).Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt, &m.OrgName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, organization.ErrMembershipNotFound
+		}
+		return nil, errors.New("failed to query membership")
+	}
+	return m, nil
+}
+
+func (r *OrganizationRepo) ListUserMemberships(ctx context.Context, userID int64) ([]*model.Membership, error) {
+	query := `
+		SELECT m.org_id, m.user_id, m.role, m.created_at, o.name
+		FROM org_memberships m
+		JOIN organizations o ON o.id = m.org_id
+		WHERE m.user_id = $1
+		ORDER BY o.name
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errors.New("failed to list memberships")
+	}
+	defer rows.Close()
+
+	var memberships []*model.Membership
+	for rows.Next() {
+		m := &model.Membership{}
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt, &m.OrgName); err != nil {
+			return nil, errors.New("failed to scan membership")
+		}
+		memberships = append(memberships, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("failed to list memberships")
+	}
+	return memberships, nil
+}
+
+func (r *OrganizationRepo) AddMember(ctx context.Context, userID int64, role model.OrgRole) error {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO org_memberships (org_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (org_id, user_id) DO NOTHING
+	`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, orgID, userID, role); err != nil {
+		return errors.New("failed to add member")
+	}
+	return nil
+}
+
+func (r *OrganizationRepo) ListMembers(ctx context.Context) ([]*model.Membership, error) {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT m.org_id, m.user_id, m.role, m.created_at, u.email
+		FROM org_memberships m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1
+		ORDER BY u.email
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, errors.New("failed to list members")
+	}
+	defer rows.Close()
+
+	var members []*model.Membership
+	for rows.Next() {
+		m := &model.Membership{}
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt, &m.UserEmail); err != nil {
+			return nil, errors.New("failed to scan member")
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("failed to list members")
+	}
+	return members, nil
+}
+
+func (r *OrganizationRepo) UpdateMemberRole(ctx context.Context, userID int64, role model.OrgRole, events ...*model.Event) error {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.withEvents(ctx, userID, events, func(q dbtx) error {
+		query := `UPDATE org_memberships SET role = $1 WHERE org_id = $2 AND user_id = $3`
+		res, err := q.ExecContext(ctx, query, role, orgID, userID)
+		if err != nil {
+			return errors.New("failed to update member role")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return organization.ErrMembershipNotFound
+		}
+		return nil
+	})
+}
+
+func (r *OrganizationRepo) RemoveMember(ctx context.Context, userID int64, events ...*model.Event) error {
+	orgID, err := orgScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.withEvents(ctx, userID, events, func(q dbtx) error {
+		query := `DELETE FROM org_memberships WHERE org_id = $1 AND user_id = $2`
+		res, err := q.ExecContext(ctx, query, orgID, userID)
+		if err != nil {
+			return errors.New("failed to remove member")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return organization.ErrMembershipNotFound
+		}
+		return nil
+	})
+}
+
+// withEvents runs write and stores events in the same transaction, with
+// the member as their subject.
+func (r *OrganizationRepo) withEvents(ctx context.Context, userID int64, events []*model.Event, write func(q dbtx) error) error {
+	if len(events) == 0 {
+		return write(conn(ctx, r.db))
+	}
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		if err := write(q); err != nil {
+			return err
+		}
+		return insertEvents(ctx, q, strconv.FormatInt(userID, 10), events)
+	})
+}
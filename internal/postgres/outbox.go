@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/outbox"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type OutboxRepo struct {
+	db *sql.DB
+}
+
+var _ outbox.Repository = (*OutboxRepo)(nil)
+
+func NewOutboxRepo(db *sql.DB) *OutboxRepo {
+	return &OutboxRepo{db: db}
+}
+
+// insertEvents writes events to the outbox through q, which must be the
+// transaction that made the change. subjectID fills in events created
+// before the subject had an ID.
+func insertEvents(ctx context.Context, q dbtx, subjectID string, events []*model.Event) error {
+	const query = `
+		INSERT INTO outbox (event_type, subject_type, subject_id, data, occurred_at)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5)
+		RETURNING id
+	`
+	for _, e := range events {
+		// Not written back: a retried transaction may assign another ID.
+		subject := e.SubjectID
+		if subject == "" {
+			subject = subjectID
+		}
+		if e.OccurredAt.IsZero() {
+			e.OccurredAt = helpers.GetCurrentTimeStampUTC()
+		}
+		data, err := json.Marshal(e.Data)
+		if err != nil || e.Data == nil {
+			data = []byte("{}")
+		}
+		err = q.QueryRowContext(ctx, query, e.Type, e.SubjectType, subject, data, e.OccurredAt).Scan(&e.ID)
+		if err != nil {
+			return errors.New("failed to write outbox event")
+		}
+	}
+	return nil
+}
+
+func (r *OutboxRepo) Publish(ctx context.Context, events ...*model.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		return insertEvents(ctx, conn(ctx, r.db), "", events)
+	})
+}
+
+func (r *OutboxRepo) ClaimBatch(ctx context.Context, limit int, lease time.Duration) ([]*outbox.Entry, error) {
+	query := `
+		UPDATE outbox SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE dispatched_at IS NULL AND dead_at IS NULL AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, COALESCE(subject_type, ''), COALESCE(subject_id, ''), data, occurred_at, attempts
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, errors.New("failed to claim outbox events")
+	}
+	defer rows.Close()
+
+	var entries []*outbox.Entry
+	for rows.Next() {
+		e := &model.Event{}
+		entry := &outbox.Entry{Event: e}
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.SubjectType, &e.SubjectID, &data, &e.OccurredAt, &entry.Attempts); err != nil {
+			return nil, errors.New("failed to scan outbox event")
+		}
+		if err := json.Unmarshal(data, &e.Data); err != nil {
+			return nil, errors.New("failed to decode outbox event")
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("failed to claim outbox events")
+	}
+	// UPDATE ... RETURNING doesn't keep the subquery order.
+	slices.SortFunc(entries, func(a, b *outbox.Entry) int { return cmp.Compare(a.Event.ID, b.Event.ID) })
+	return entries, nil
+}
+
+func (r *OutboxRepo) MarkDispatched(ctx context.Context, id int64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE outbox SET dispatched_at = NOW(), last_error = NULL WHERE id = $1`, id)
+	if err != nil {
+		return errors.New("failed to mark outbox event dispatched")
+	}
+	return nil
+}
+
+func (r *OutboxRepo) MarkFailed(ctx context.Context, id int64, attempts int, nextAttempt time.Time, lastErr string) error {
+	query := `UPDATE outbox SET attempts = $1, next_attempt_at = $2, last_error = $3 WHERE id = $4`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, attempts, nextAttempt, lastErr, id); err != nil {
+		return errors.New("failed to record outbox failure")
+	}
+	return nil
+}
+
+func (r *OutboxRepo) MarkDead(ctx context.Context, id int64, attempts int, lastErr string) error {
+	query := `UPDATE outbox SET attempts = $1, last_error = $2, dead_at = NOW() WHERE id = $3`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, attempts, lastErr, id); err != nil {
+		return errors.New("failed to mark outbox event dead")
+	}
+	return nil
+}
+
+func (r *OutboxRepo) DeleteDispatched(ctx context.Context, before time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM outbox WHERE dispatched_at IS NOT NULL AND dispatched_at < $1`, before)
+	if err != nil {
+		return errors.New("failed to delete dispatched outbox events")
+	}
+	return nil
+}
+
+func (r *OutboxRepo) IsProcessed(ctx context.Context, handler string, eventID int64) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM processed_events WHERE handler = $1 AND event_id = $2)`, handler, eventID,
+	).Scan(&exists)
+	if err != nil {
+		return false, errors.New("failed to check processed event")
+	}
+	return exists, nil
+}
+
+func (r *OutboxRepo) MarkProcessed(ctx context.Context, handler string, eventID int64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO processed_events (handler, event_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, handler, eventID,
+	)
+	if err != nil {
+		return errors.New("failed to mark event processed")
+	}
+	return nil
+}
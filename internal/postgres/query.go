@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+// column describes a filterable column. Text columns compare
+// case-insensitively and support the substring operators.
+type column struct {
+	name string
+	text bool
+}
+
+var errUnsupportedFilter = errors.New("unsupported filter")
+
+// buildWhere renders filter conditions as a WHERE clause, appending the
+// bind parameters to args. Only fields present in columns are accepted.
+func buildWhere(conditions []query.Condition, columns map[string]column, args *[]any) (string, error) {
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	clauses := make([]string, 0, len(conditions))
+	for _, c := range conditions {
+		col, ok := columns[c.Field]
+		if !ok {
+			return "", fmt.Errorf("%w: unknown field %q", errUnsupportedFilter, c.Field)
+		}
+		clause, err := buildCondition(col, c, args)
+		if err != nil {
+			return "", err
+		}
+		clauses = append(clauses, clause)
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), nil
+}
+
+func buildCondition(col column, c query.Condition, args *[]any) (string, error) {
+	if c.Op == query.OpPresent {
+		if col.text {
+			return fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", col.name, col.name), nil
+		}
+		return col.name + " IS NOT NULL", nil
+	}
+
+	placeholder := func(v any) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	switch c.Op {
+	case query.OpEq, query.OpNe:
+		op := "="
+		if c.Op == query.OpNe {
+			op = "<>"
+		}
+		if col.text {
+			return fmt.Sprintf("LOWER(%s) %s LOWER(%s)", col.name, op, placeholder(c.Value)), nil
+		}
+		return fmt.Sprintf("%s %s %s", col.name, op, placeholder(c.Value)), nil
+	case query.OpContains, query.OpStartsWith, query.OpEndsWith:
+		if !col.text {
+			return "", fmt.Errorf("%w: %s on non-text field %q", errUnsupportedFilter, c.Op, c.Field)
+		}
+		pattern := escapeLike(c.Value)
+		switch c.Op {
+		case query.OpContains:
+			pattern = "%" + pattern + "%"
+		case query.OpStartsWith:
+			pattern = pattern + "%"
+		case query.OpEndsWith:
+			pattern = "%" + pattern
+		}
+		return fmt.Sprintf("%s ILIKE %s", col.name, placeholder(pattern)), nil
+	case query.OpGt, query.OpGe, query.OpLt, query.OpLe:
+		ops := map[query.Op]string{query.OpGt: ">", query.OpGe: ">=", query.OpLt: "<", query.OpLe: "<="}
+		return fmt.Sprintf("%s %s %s", col.name, ops[c.Op], placeholder(c.Value)), nil
+	}
+	return "", fmt.Errorf("%w: operator %q", errUnsupportedFilter, c.Op)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// buildPage renders LIMIT/OFFSET for a filter.
+func buildPage(filter query.Filter, args *[]any) string {
+	var b strings.Builder
+	if filter.Limit > 0 {
+		*args = append(*args, filter.Limit)
+		fmt.Fprintf(&b, " LIMIT $%d", len(*args))
+	}
+	if filter.Offset > 0 {
+		*args = append(*args, filter.Offset)
+		fmt.Fprintf(&b, " OFFSET $%d", len(*args))
+	}
+	return b.String()
+}
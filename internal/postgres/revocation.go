@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/security"
+)
+
+type RevocationRepo struct {
+	db *sql.DB
+}
+
+var _ security.Revocation = (*RevocationRepo)(nil)
+
+func NewRevocationRepo(db *sql.DB) *RevocationRepo {
+	return &RevocationRepo{db: db}
+}
+
+// Revoke records id and purges entries that have expired, which keeps the
+// table as small as the set of live revoked tokens.
+func (r *RevocationRepo) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	const query = `
+		WITH purged AS (DELETE FROM revoked_tokens WHERE expires_at < NOW())
+		INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, id, expiresAt); err != nil {
+		return errors.New("failed to revoke token")
+	}
+	return nil
+}
+
+func (r *RevocationRepo) IsRevoked(ctx context.Context, id string) (bool, error) {
+	const query = `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1 AND expires_at > NOW())`
+
+	var revoked bool
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&revoked); err != nil {
+		return false, errors.New("failed to check token revocation")
+	}
+	return revoked, nil
+}
@@ -0,0 +1,24 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/razedwell/go-hand/internal/tenant"
+)
+
+// orgScope returns the organization every query of an org-scoped repository
+// method must filter on. There is deliberately no way to pass an org ID in.
+func orgScope(ctx context.Context) (int64, error) {
+	t, err := tenant.FromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return t.OrgID, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
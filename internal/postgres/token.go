@@ -3,8 +3,11 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/tracing"
+	"github.com/razedwell/go-hand/internal/repository/token"
 	"github.com/razedwell/go-hand/internal/transport/http/helpers"
 )
 
@@ -12,25 +15,36 @@ type TokenRepo struct {
 	db *sql.DB
 }
 
+var _ token.Repository = (*TokenRepo)(nil)
+
 func NewTokenRepo(db *sql.DB) *TokenRepo {
 	return &TokenRepo{db: db}
 }
 
-func (r *TokenRepo) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+func (r *TokenRepo) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) (err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.CreateRefreshToken")
+	defer func() { tracing.End(span, err) }()
+
 	query := `INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
-	_, err := r.db.ExecContext(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt)
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt)
 	return err
 }
 
-func (r *TokenRepo) GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+func (r *TokenRepo) GetRefreshToken(ctx context.Context, tokenHash string) (_ *model.RefreshToken, err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.GetRefreshToken")
+	defer func() { tracing.End(span, err) }()
+
 	query := `SELECT id, user_id, token_hash, revoked_at, expires_at, created_at FROM refresh_tokens WHERE token_hash = $1`
-	row := r.db.QueryRowContext(ctx, query, tokenHash)
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash)
 
 	var rt model.RefreshToken
 	var revokedAt sql.NullTime
 
-	err := row.Scan(&rt.ID, &rt.UserID, &rt.TokenHash, &revokedAt, &rt.ExpiresAt, &rt.CreatedAt)
+	err = row.Scan(&rt.ID, &rt.UserID, &rt.TokenHash, &revokedAt, &rt.ExpiresAt, &rt.CreatedAt)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, token.ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -43,22 +57,31 @@ func (r *TokenRepo) GetRefreshToken(ctx context.Context, tokenHash string) (*mod
 	return &rt, nil
 }
 
-func (r *TokenRepo) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+func (r *TokenRepo) RevokeRefreshToken(ctx context.Context, tokenHash string) (err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.RevokeRefreshToken")
+	defer func() { tracing.End(span, err) }()
+
 	now := helpers.GetCurrentTimeStampUTC()
 	query := `UPDATE refresh_tokens SET revoked_at = $1 WHERE token_hash = $2`
-	_, err := r.db.ExecContext(ctx, query, now, tokenHash)
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, now, tokenHash)
 	return err
 }
 
-func (r *TokenRepo) RevokeAllUserTokens(ctx context.Context, userID int64) error {
+func (r *TokenRepo) RevokeAllUserTokens(ctx context.Context, userID int64) (err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.RevokeAllUserTokens")
+	defer func() { tracing.End(span, err) }()
+
 	now := helpers.GetCurrentTimeStampUTC()
 	query := `UPDATE refresh_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
-	_, err := r.db.ExecContext(ctx, query, now, userID)
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, now, userID)
 	return err
 }
 
-func (r *TokenRepo) DeleteExpiredTokens(ctx context.Context) error {
+func (r *TokenRepo) DeleteExpiredTokens(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "TokenRepo.DeleteExpiredTokens")
+	defer func() { tracing.End(span, err) }()
+
 	query := `DELETE FROM refresh_tokens WHERE expires_at < NOW() - INTERVAL '1 day'`
-	_, err := r.db.ExecContext(ctx, query)
+	_, err = conn(ctx, r.db).ExecContext(ctx, query)
 	return err
 }
@@ -0,0 +1,15 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/razedwell/go-hand/internal/platform/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a span around a repository method; end it with
+// tracing.End.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracing.StartClient(ctx, name, semconv.DBSystemPostgreSQL)
+}
@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/razedwell/go-hand/internal/repository/transaction"
+)
+
+// dbtx is the query surface shared by *sql.DB and *sql.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// txState is the transaction bound to a context by inTx.
+type txState struct {
+	tx         *sql.Tx
+	savepoints int
+	// retryable remembers a serialization failure or deadlock seen on the
+	// transaction, since repositories return opaque errors.
+	retryable error
+}
+
+func (s *txState) note(err error) {
+	if isRetryable(err) && s.retryable == nil {
+		s.retryable = err
+	}
+}
+
+func (s *txState) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := s.tx.ExecContext(ctx, query, args...)
+	s.note(err)
+	return res, err
+}
+
+func (s *txState) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := s.tx.QueryContext(ctx, query, args...)
+	s.note(err)
+	return rows, err
+}
+
+func (s *txState) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	row := s.tx.QueryRowContext(ctx, query, args...)
+	s.note(row.Err())
+	return row
+}
+
+// conn returns the transaction bound to ctx, or db outside a transaction.
+// Repositories use it for every query so they join a caller's unit of work.
+func conn(ctx context.Context, db *sql.DB) dbtx {
+	if s, ok := ctx.Value(txKey{}).(*txState); ok {
+		return s
+	}
+	return db
+}
+
+type TxOptions struct {
+	Isolation  sql.IsolationLevel
+	MaxRetries int           // extra attempts after a serialization failure or deadlock
+	RetryDelay time.Duration // doubled after each retry
+}
+
+var defaultTxOptions = TxOptions{MaxRetries: 3, RetryDelay: 10 * time.Millisecond}
+
+// TxManager implements transaction.Manager on top of *sql.DB.
+type TxManager struct {
+	db   *sql.DB
+	opts TxOptions
+}
+
+var _ transaction.Manager = (*TxManager)(nil)
+
+func NewTxManager(db *sql.DB, opts TxOptions) *TxManager {
+	return &TxManager{db: db, opts: opts}
+}
+
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runTx(ctx, m.db, m.opts, fn)
+}
+
+// inTx is WithinTx with the default options, for repository methods that
+// need several statements to apply together.
+func inTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	return runTx(ctx, db, defaultTxOptions, fn)
+}
+
+func runTx(ctx context.Context, db *sql.DB, opts TxOptions, fn func(ctx context.Context) error) error {
+	if s, ok := ctx.Value(txKey{}).(*txState); ok {
+		return runSavepoint(ctx, s, fn)
+	}
+
+	delay := opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := runOnce(ctx, db, opts, fn)
+		var retry *retryError
+		if !errors.As(err, &retry) {
+			return err
+		}
+		if attempt >= opts.MaxRetries {
+			return retry.err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// retryError marks an attempt that failed with a serialization failure or
+// deadlock and can be run again.
+type retryError struct{ err error }
+
+func (e *retryError) Error() string { return e.err.Error() }
+
+func runOnce(ctx context.Context, db *sql.DB, opts TxOptions, fn func(ctx context.Context) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: opts.Isolation})
+	if err != nil {
+		return errors.New("failed to begin transaction")
+	}
+	s := &txState{tx: tx}
+
+	if err := fn(context.WithValue(ctx, txKey{}, s)); err != nil {
+		tx.Rollback()
+		if s.retryable != nil || isRetryable(err) {
+			return &retryError{err}
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		if isRetryable(err) {
+			return &retryError{err}
+		}
+		return errors.New("failed to commit transaction")
+	}
+	return nil
+}
+
+func runSavepoint(ctx context.Context, s *txState, fn func(ctx context.Context) error) error {
+	s.savepoints++
+	name := fmt.Sprintf("sp_%d", s.savepoints)
+
+	if _, err := s.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return errors.New("failed to create savepoint")
+	}
+	if err := fn(ctx); err != nil {
+		// After a serialization failure only a full retry helps; leave the
+		// transaction for the outermost call to roll back.
+		if s.retryable == nil {
+			if _, rerr := s.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rerr != nil {
+				return errors.Join(err, errors.New("failed to roll back savepoint"))
+			}
+		}
+		return err
+	}
+	if _, err := s.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return errors.New("failed to release savepoint")
+	}
+	return nil
+}
+
+// isRetryable reports serialization failures and deadlocks, which succeed
+// when the whole transaction is run again.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
@@ -4,22 +4,28 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strconv"
 
 	"github.com/razedwell/go-hand/internal/model"
-	"github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/platform/tracing"
+	"github.com/razedwell/go-hand/internal/repository/query"
+	userrepo "github.com/razedwell/go-hand/internal/repository/user"
 )
 
 type UserRepo struct {
 	db *sql.DB
 }
 
-var _ user.Repository = (*UserRepo)(nil)
+var _ userrepo.Repository = (*UserRepo)(nil)
 
 func NewUserRepo(db *sql.DB) *UserRepo {
 	return &UserRepo{db: db}
 }
 
-func (r *UserRepo) FindUserByEmail(ctx context.Context, email string) (*model.User, error) {
+func (r *UserRepo) FindUserByEmail(ctx context.Context, email string) (_ *model.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.FindUserByEmail")
+	defer func() { tracing.End(span, err) }()
+
 	const query = `
 		SELECT
 			id, created_at, updated_at,
@@ -33,7 +39,7 @@ func (r *UserRepo) FindUserByEmail(ctx context.Context, email string) (*model.Us
 
 	user := &model.User{}
 
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt,
 		&user.FirstName, &user.LastName, &user.Email, &user.Phone,
 		&user.IsActive, &user.IsEmailVerified, &user.IsPhoneVerified,
@@ -42,7 +48,7 @@ func (r *UserRepo) FindUserByEmail(ctx context.Context, email string) (*model.Us
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, userrepo.ErrNotFound
 		}
 		return nil, errors.New("failed to query user by email")
 	}
@@ -50,7 +56,10 @@ func (r *UserRepo) FindUserByEmail(ctx context.Context, email string) (*model.Us
 	return user, nil
 }
 
-func (r *UserRepo) FindUserById(ctx context.Context, id int64) (*model.User, error) {
+func (r *UserRepo) FindUserById(ctx context.Context, id int64) (_ *model.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.FindUserById")
+	defer func() { tracing.End(span, err) }()
+
 	const query = `
 		SELECT
 			id, created_at, updated_at,
@@ -64,7 +73,7 @@ func (r *UserRepo) FindUserById(ctx context.Context, id int64) (*model.User, err
 
 	user := &model.User{}
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt,
 		&user.FirstName, &user.LastName, &user.Email, &user.Phone,
 		&user.IsActive, &user.IsEmailVerified, &user.IsPhoneVerified,
@@ -73,7 +82,7 @@ func (r *UserRepo) FindUserById(ctx context.Context, id int64) (*model.User, err
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, userrepo.ErrNotFound
 		}
 		return nil, errors.New("failed to query user by id")
 	}
@@ -81,7 +90,28 @@ func (r *UserRepo) FindUserById(ctx context.Context, id int64) (*model.User, err
 	return user, nil
 }
 
-func (r *UserRepo) CreateUser(ctx context.Context, user *model.User) error {
+// withEvents runs write in a transaction and appends events to the outbox
+// before committing, so the events exist if and only if the change does.
+// write returns the subject ID for events that don't have one yet.
+func (r *UserRepo) withEvents(ctx context.Context, events []*model.Event, write func(q dbtx) (string, error)) error {
+	if len(events) == 0 {
+		_, err := write(conn(ctx, r.db))
+		return err
+	}
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		subjectID, err := write(q)
+		if err != nil {
+			return err
+		}
+		return insertEvents(ctx, q, subjectID, events)
+	})
+}
+
+func (r *UserRepo) CreateUser(ctx context.Context, user *model.User, events ...*model.Event) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.CreateUser")
+	defer func() { tracing.End(span, err) }()
+
 	const query = `
 		INSERT INTO users (
 			first_name, last_name, email, phone,
@@ -92,15 +122,134 @@ func (r *UserRepo) CreateUser(ctx context.Context, user *model.User) error {
 		RETURNING id, created_at, updated_at
 	`
 
-	err := r.db.QueryRowContext(ctx, query,
-		user.FirstName, user.LastName, user.Email, user.Phone,
-		user.IsActive, user.IsEmailVerified, user.IsPhoneVerified,
-		user.IsBanned, user.BannedAt, user.BanReason,
-		user.PasswordHash, user.LastLoginAt, user.Role,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	return r.withEvents(ctx, events, func(q dbtx) (string, error) {
+		err := q.QueryRowContext(ctx, query,
+			user.FirstName, user.LastName, user.Email, user.Phone,
+			user.IsActive, user.IsEmailVerified, user.IsPhoneVerified,
+			user.IsBanned, user.BannedAt, user.BanReason,
+			user.PasswordHash, user.LastLoginAt, user.Role,
+		).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return "", userrepo.ErrEmailTaken
+			}
+			return "", errors.New("failed to create user")
+		}
+		return strconv.FormatInt(user.ID, 10), nil
+	})
+}
+
+var userColumns = map[string]column{
+	"id":         {name: "id"},
+	"email":      {name: "email", text: true},
+	"first_name": {name: "first_name", text: true},
+	"last_name":  {name: "last_name", text: true},
+	"phone":      {name: "phone", text: true},
+	"is_active":  {name: "is_active"},
+	"role":       {name: "role::text", text: true},
+	"created_at": {name: "created_at"},
+	"updated_at": {name: "updated_at"},
+}
+
+func (r *UserRepo) ListUsers(ctx context.Context, filter query.Filter) (_ []*model.User, _ int, err error) {
+	ctx, span := startSpan(ctx, "UserRepo.ListUsers")
+	defer func() { tracing.End(span, err) }()
+
+	var args []any
+	where, err := buildWhere(filter.Conditions, userColumns, &args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, errors.New("failed to count users")
+	}
+
+	query := `
+		SELECT
+			id, created_at, updated_at,
+			first_name, last_name, email, phone,
+			is_active, is_email_verified, is_phone_verified,
+			is_banned, banned_at, ban_reason,
+			password_hash, last_login_at, role
+		FROM users` + where + ` ORDER BY id` + buildPage(filter, &args)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
-		return errors.New("failed to create user")
+		return nil, 0, errors.New("failed to list users")
 	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user := &model.User{}
+		err := rows.Scan(
+			&user.ID, &user.CreatedAt, &user.UpdatedAt,
+			&user.FirstName, &user.LastName, &user.Email, &user.Phone,
+			&user.IsActive, &user.IsEmailVerified, &user.IsPhoneVerified,
+			&user.IsBanned, &user.BannedAt, &user.BanReason,
+			&user.PasswordHash, &user.LastLoginAt, &user.Role,
+		)
+		if err != nil {
+			return nil, 0, errors.New("failed to scan user")
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, errors.New("failed to list users")
+	}
+
+	return users, total, nil
+}
 
-	return nil
+func (r *UserRepo) UpdateUser(ctx context.Context, user *model.User, events ...*model.Event) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.UpdateUser")
+	defer func() { tracing.End(span, err) }()
+
+	const query = `
+		UPDATE users SET
+			first_name = $1, last_name = $2, email = $3, phone = $4,
+			is_active = $5, is_email_verified = $6, is_phone_verified = $7,
+			is_banned = $8, banned_at = $9, ban_reason = $10,
+			password_hash = $11, last_login_at = $12, role = $13
+		WHERE id = $14
+		RETURNING updated_at
+	`
+
+	return r.withEvents(ctx, events, func(q dbtx) (string, error) {
+		err := q.QueryRowContext(ctx, query,
+			user.FirstName, user.LastName, user.Email, user.Phone,
+			user.IsActive, user.IsEmailVerified, user.IsPhoneVerified,
+			user.IsBanned, user.BannedAt, user.BanReason,
+			user.PasswordHash, user.LastLoginAt, user.Role,
+			user.ID,
+		).Scan(&user.UpdatedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return "", userrepo.ErrNotFound
+			}
+			if isUniqueViolation(err) {
+				return "", userrepo.ErrEmailTaken
+			}
+			return "", errors.New("failed to update user")
+		}
+		return strconv.FormatInt(user.ID, 10), nil
+	})
+}
+
+func (r *UserRepo) DeleteUser(ctx context.Context, id int64, events ...*model.Event) (err error) {
+	ctx, span := startSpan(ctx, "UserRepo.DeleteUser")
+	defer func() { tracing.End(span, err) }()
+
+	return r.withEvents(ctx, events, func(q dbtx) (string, error) {
+		res, err := q.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+		if err != nil {
+			return "", errors.New("failed to delete user")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return "", userrepo.ErrNotFound
+		}
+		return strconv.FormatInt(id, 10), nil
+	})
 }
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/verification"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type VerificationRepo struct {
+	db *sql.DB
+}
+
+var _ verification.Repository = (*VerificationRepo)(nil)
+
+func NewVerificationRepo(db *sql.DB) *VerificationRepo {
+	return &VerificationRepo{db: db}
+}
+
+func (r *VerificationRepo) CreateVerificationCode(ctx context.Context, code *model.VerificationCode) error {
+	query := `
+		INSERT INTO verification_codes (user_id, code_hash, type, binding_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, code.UserID, code.CodeHash, code.Type, code.BindingHash, code.ExpiresAt).
+		Scan(&code.ID, &code.CreatedAt)
+	if err != nil {
+		return errors.New("failed to create verification code")
+	}
+	return nil
+}
+
+func (r *VerificationRepo) GetVerificationCode(ctx context.Context, codeHash string, codeType model.VerificationType) (*model.VerificationCode, error) {
+	query := `
+		SELECT id, user_id, code_hash, type, expires_at, used_at, created_at, attempts, binding_hash
+		FROM verification_codes
+		WHERE code_hash = $1 AND type = $2 AND used_at IS NULL
+	`
+	return r.scanCode(conn(ctx, r.db).QueryRowContext(ctx, query, codeHash, codeType))
+}
+
+func (r *VerificationRepo) GetVerificationCodeByBinding(ctx context.Context, bindingHash string, codeType model.VerificationType) (*model.VerificationCode, error) {
+	query := `
+		SELECT id, user_id, code_hash, type, expires_at, used_at, created_at, attempts, binding_hash
+		FROM verification_codes
+		WHERE binding_hash = $1 AND type = $2 AND used_at IS NULL
+	`
+	return r.scanCode(conn(ctx, r.db).QueryRowContext(ctx, query, bindingHash, codeType))
+}
+
+func (r *VerificationRepo) GetLatestVerificationCode(ctx context.Context, userID int64, codeType model.VerificationType) (*model.VerificationCode, error) {
+	query := `
+		SELECT id, user_id, code_hash, type, expires_at, used_at, created_at, attempts, binding_hash
+		FROM verification_codes
+		WHERE user_id = $1 AND type = $2 AND used_at IS NULL
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+	return r.scanCode(conn(ctx, r.db).QueryRowContext(ctx, query, userID, codeType))
+}
+
+func (r *VerificationRepo) scanCode(row *sql.Row) (*model.VerificationCode, error) {
+	var vc model.VerificationCode
+	err := row.Scan(&vc.ID, &vc.UserID, &vc.CodeHash, &vc.Type, &vc.ExpiresAt, &vc.UsedAt, &vc.CreatedAt, &vc.Attempts, &vc.BindingHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, verification.ErrNotFound
+		}
+		return nil, errors.New("failed to query verification code")
+	}
+	return &vc, nil
+}
+
+func (r *VerificationRepo) IncrementAttempts(ctx context.Context, id int64) (int, error) {
+	query := `UPDATE verification_codes SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+	var attempts int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&attempts); err != nil {
+		return 0, errors.New("failed to record verification attempt")
+	}
+	return attempts, nil
+}
+
+// MarkVerificationCodeUsed fails with ErrNotFound if the code was already
+// used, so concurrent redemptions of the same code can't both succeed.
+func (r *VerificationRepo) MarkVerificationCodeUsed(ctx context.Context, id int64) error {
+	now := helpers.GetCurrentTimeStampUTC()
+	query := `UPDATE verification_codes SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, now, id)
+	if err != nil {
+		return errors.New("failed to mark verification code used")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return verification.ErrNotFound
+	}
+	return nil
+}
+
+func (r *VerificationRepo) InvalidateUserCodes(ctx context.Context, userID int64, codeTypes ...model.VerificationType) error {
+	types := make([]string, len(codeTypes))
+	for i, t := range codeTypes {
+		types[i] = string(t)
+	}
+	now := helpers.GetCurrentTimeStampUTC()
+	query := `UPDATE verification_codes SET used_at = $1 WHERE user_id = $2 AND type = ANY($3) AND used_at IS NULL`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, now, userID, pq.Array(types))
+	return err
+}
+
+func (r *VerificationRepo) DeleteExpiredVerificationCodes(ctx context.Context) error {
+	query := `DELETE FROM verification_codes WHERE expires_at < NOW() - INTERVAL '1 day'`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query)
+	return err
+}
@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/webhook"
+)
+
+type WebhookRepo struct {
+	db *sql.DB
+}
+
+var _ webhook.Repository = (*WebhookRepo)(nil)
+
+func NewWebhookRepo(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+const subscriptionColumns = `id, url, secret, events, is_active, created_at, updated_at`
+
+func scanSubscription(row rowScanner) (*model.WebhookSubscription, error) {
+	sub := &model.WebhookSubscription{}
+	err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, pq.Array(&sub.Events), &sub.IsActive, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webhook.ErrNotFound
+		}
+		return nil, errors.New("failed to scan webhook subscription")
+	}
+	return sub, nil
+}
+
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, events, is_active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, sub.URL, sub.Secret, pq.Array(sub.Events), sub.IsActive).
+		Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return errors.New("failed to create webhook subscription")
+	}
+	return nil
+}
+
+func (r *WebhookRepo) FindSubscriptionById(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+	return scanSubscription(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+}
+
+func (r *WebhookRepo) ListSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions ORDER BY id`
+	return r.listSubscriptions(ctx, query)
+}
+
+func (r *WebhookRepo) ListActiveSubscriptions(ctx context.Context, eventType string) ([]*model.WebhookSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions
+		WHERE is_active AND ($1 = ANY(events) OR $2 = ANY(events)) ORDER BY id`
+	return r.listSubscriptions(ctx, query, eventType, model.EventAll)
+}
+
+func (r *WebhookRepo) listSubscriptions(ctx context.Context, query string, args ...any) ([]*model.WebhookSubscription, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.New("failed to list webhook subscriptions")
+	}
+	defer rows.Close()
+
+	var subs []*model.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("failed to list webhook subscriptions")
+	}
+	return subs, nil
+}
+
+func (r *WebhookRepo) UpdateSubscription(ctx context.Context, sub *model.WebhookSubscription) error {
+	query := `
+		UPDATE webhook_subscriptions SET url = $1, secret = $2, events = $3, is_active = $4
+		WHERE id = $5
+		RETURNING updated_at
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, sub.URL, sub.Secret, pq.Array(sub.Events), sub.IsActive, sub.ID).
+		Scan(&sub.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return webhook.ErrNotFound
+		}
+		return errors.New("failed to update webhook subscription")
+	}
+	return nil
+}
+
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, id int64) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return errors.New("failed to delete webhook subscription")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return webhook.ErrNotFound
+	}
+	return nil
+}
+
+const deliveryColumns = `
+	id, subscription_id, event_id, event_type, payload,
+	status, attempts, next_attempt_at, last_error, last_status_code, delivered_at, created_at
+`
+
+func scanDelivery(row rowScanner) (*model.WebhookDelivery, error) {
+	d := &model.WebhookDelivery{}
+	err := row.Scan(
+		&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload,
+		&d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError, &d.LastStatusCode, &d.DeliveredAt, &d.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webhook.ErrDeliveryNotFound
+		}
+		return nil, errors.New("failed to scan webhook delivery")
+	}
+	return d, nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("failed to list webhook deliveries")
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepo) CreateDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+		RETURNING id, status, next_attempt_at, created_at
+	`
+	return inTx(ctx, r.db, func(ctx context.Context) error {
+		for _, d := range deliveries {
+			err := conn(ctx, r.db).QueryRowContext(ctx, query, d.SubscriptionID, d.EventID, d.EventType, d.Payload).
+				Scan(&d.ID, &d.Status, &d.NextAttemptAt, &d.CreatedAt)
+			// No row means the event was already queued for this subscription.
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return errors.New("failed to create webhook delivery")
+			}
+		}
+		return nil
+	})
+}
+
+func (r *WebhookRepo) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, errors.New("failed to claim webhook deliveries")
+	}
+	defer rows.Close()
+	return scanDeliveries(rows)
+}
+
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, d *model.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries SET
+			status = $1, attempts = $2, next_attempt_at = $3,
+			last_error = $4, last_status_code = $5, delivered_at = $6
+		WHERE id = $7
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query,
+		d.Status, d.Attempts, d.NextAttemptAt, d.LastError, d.LastStatusCode, d.DeliveredAt, d.ID,
+	)
+	if err != nil {
+		return errors.New("failed to record webhook attempt")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return webhook.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepo) FindDeliveryById(ctx context.Context, id int64) (*model.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	return scanDelivery(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+}
+
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, status model.DeliveryStatus, offset int, limit int) ([]*model.WebhookDelivery, int, error) {
+	var total int
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM webhook_deliveries WHERE ($1 = '' OR status = $1)`, status,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, errors.New("failed to count webhook deliveries")
+	}
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+		WHERE ($1 = '' OR status = $1) ORDER BY id DESC LIMIT $2 OFFSET $3`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, 0, errors.New("failed to list webhook deliveries")
+	}
+	defer rows.Close()
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+func (r *WebhookRepo) Redeliver(ctx context.Context, id int64) error {
+	query := `
+		UPDATE webhook_deliveries SET
+			status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+		WHERE id = $1
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return errors.New("failed to requeue webhook delivery")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return webhook.ErrDeliveryNotFound
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+// Repository stores audit events. There is deliberately no update or delete.
+type Repository interface {
+	// AppendEvent links event to the head of its day's segment, filling in
+	// CreatedAt, Segment, Seq, PrevHash and Hash. Appends are serialized.
+	AppendEvent(ctx context.Context, event *model.AuditEvent) error
+	// ListEvents returns matching events newest first, with the total count.
+	ListEvents(ctx context.Context, filter query.Filter) ([]*model.AuditEvent, int, error)
+
+	// ListSegments returns the segments between from and to (inclusive,
+	// YYYY-MM-DD; empty means unbounded) in ascending order.
+	ListSegments(ctx context.Context, from string, to string) ([]string, error)
+	// ListSegmentEvents returns up to limit events of segment with a
+	// sequence number above afterSeq, in chain order.
+	ListSegmentEvents(ctx context.Context, segment string, afterSeq int64, limit int) ([]*model.AuditEvent, error)
+	// SegmentHeads returns the last event of every segment from since onwards.
+	SegmentHeads(ctx context.Context, since string) ([]*model.AuditEvent, error)
+
+	CreateCheckpoint(ctx context.Context, cp *model.AuditCheckpoint) error
+	ListCheckpoints(ctx context.Context, segment string) ([]*model.AuditCheckpoint, error)
+	// LatestCheckpoints returns the newest checkpoint of every segment from
+	// since onwards.
+	LatestCheckpoints(ctx context.Context, since string) ([]*model.AuditCheckpoint, error)
+}
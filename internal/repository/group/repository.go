@@ -0,0 +1,25 @@
+package group
+
+import (
+	"context"
+	"errors"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+var (
+	ErrNotFound  = errors.New("group not found")
+	ErrNameTaken = errors.New("group display name already taken")
+)
+
+type Repository interface {
+	CreateGroup(ctx context.Context, group *model.Group) error
+	FindGroupById(ctx context.Context, id int64) (*model.Group, error)
+	ListGroups(ctx context.Context, filter query.Filter) ([]*model.Group, int, error)
+	UpdateGroup(ctx context.Context, group *model.Group) error
+	DeleteGroup(ctx context.Context, id int64) error
+	AddMembers(ctx context.Context, groupID int64, userIDs []int64) error
+	RemoveMembers(ctx context.Context, groupID int64, userIDs []int64) error
+	ReplaceMembers(ctx context.Context, groupID int64, userIDs []int64) error
+}
@@ -0,0 +1,20 @@
+package identity
+
+import (
+	"context"
+	"errors"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+var (
+	ErrNotFound = errors.New("identity not found")
+	ErrTaken    = errors.New("identity is already linked")
+)
+
+type Repository interface {
+	FindIdentity(ctx context.Context, provider string, subject string) (*model.Identity, error)
+	// CreateIdentity fails with ErrTaken if the provider subject is linked
+	// to a user already.
+	CreateIdentity(ctx context.Context, identity *model.Identity) error
+}
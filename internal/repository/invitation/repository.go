@@ -0,0 +1,27 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+var ErrNotFound = errors.New("invitation not found")
+
+type Repository interface {
+	// Scoped to the tenant in ctx.
+	CreateInvitation(ctx context.Context, inv *model.Invitation) error
+	ListInvitations(ctx context.Context) ([]*model.Invitation, error)
+	FindInvitationById(ctx context.Context, id int64) (*model.Invitation, error)
+	RenewInvitation(ctx context.Context, id int64, tokenHash string, expiresAt time.Time) error
+	RevokeInvitation(ctx context.Context, id int64) error
+	RevokePendingInvitations(ctx context.Context, email string) error
+
+	// Unscoped: the token itself identifies the organization.
+	FindInvitationByTokenHash(ctx context.Context, tokenHash string) (*model.Invitation, error)
+	// MarkInvitationAccepted fails with ErrNotFound unless the invitation is
+	// still pending, so a token can only be redeemed once.
+	MarkInvitationAccepted(ctx context.Context, id int64, userID int64) error
+}
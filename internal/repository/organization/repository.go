@@ -0,0 +1,29 @@
+package organization
+
+import (
+	"context"
+	"errors"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+var (
+	ErrNotFound           = errors.New("organization not found")
+	ErrMembershipNotFound = errors.New("membership not found")
+	ErrSlugTaken          = errors.New("organization slug already taken")
+)
+
+type Repository interface {
+	// CreateOrganization creates the organization with ownerID as its owner.
+	CreateOrganization(ctx context.Context, org *model.Organization, ownerID int64) error
+	FindOrganizationById(ctx context.Context, id int64) (*model.Organization, error)
+	FindMembership(ctx context.Context, orgID int64, userID int64) (*model.Membership, error)
+	ListUserMemberships(ctx context.Context, userID int64) ([]*model.Membership, error)
+
+	// Scoped to the tenant in ctx. Events are stored in the outbox
+	// atomically with the change; see model.Event.
+	AddMember(ctx context.Context, userID int64, role model.OrgRole) error
+	ListMembers(ctx context.Context) ([]*model.Membership, error)
+	UpdateMemberRole(ctx context.Context, userID int64, role model.OrgRole, events ...*model.Event) error
+	RemoveMember(ctx context.Context, userID int64, events ...*model.Event) error
+}
@@ -0,0 +1,33 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+// Entry is an undispatched outbox row.
+type Entry struct {
+	Event    *model.Event
+	Attempts int
+}
+
+type Repository interface {
+	// Publish appends events to the outbox on their own, for changes that
+	// have no row of their own to commit with.
+	Publish(ctx context.Context, events ...*model.Event) error
+	// ClaimBatch returns up to limit due entries in order and pushes their
+	// next attempt back by lease, so concurrent dispatchers don't overlap.
+	ClaimBatch(ctx context.Context, limit int, lease time.Duration) ([]*Entry, error)
+	MarkDispatched(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, attempts int, nextAttempt time.Time, lastErr string) error
+	// MarkDead stops retrying an entry that has used up its attempts. Dead
+	// entries are kept for inspection and never claimed again.
+	MarkDead(ctx context.Context, id int64, attempts int, lastErr string) error
+	// DeleteDispatched removes entries dispatched before the cutoff.
+	DeleteDispatched(ctx context.Context, before time.Time) error
+
+	IsProcessed(ctx context.Context, handler string, eventID int64) (bool, error)
+	MarkProcessed(ctx context.Context, handler string, eventID int64) error
+}
@@ -0,0 +1,31 @@
+package query
+
+// Op is a comparison operator understood by repository list queries.
+type Op string
+
+const (
+	OpEq         Op = "eq"
+	OpNe         Op = "ne"
+	OpContains   Op = "co"
+	OpStartsWith Op = "sw"
+	OpEndsWith   Op = "ew"
+	OpPresent    Op = "pr"
+	OpGt         Op = "gt"
+	OpGe         Op = "ge"
+	OpLt         Op = "lt"
+	OpLe         Op = "le"
+)
+
+// Condition compares a repository field (e.g. "email", "is_active") with a value.
+type Condition struct {
+	Field string
+	Op    Op
+	Value string
+}
+
+// Filter selects a page of rows. Conditions are AND-ed together.
+type Filter struct {
+	Conditions []Condition
+	Offset     int
+	Limit      int
+}
@@ -2,10 +2,13 @@ package token
 
 import (
 	"context"
+	"errors"
 
 	"github.com/razedwell/go-hand/internal/model"
 )
 
+var ErrNotFound = errors.New("refresh token not found")
+
 type Repository interface {
 	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
 	GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
@@ -0,0 +1,12 @@
+package transaction
+
+import "context"
+
+// Manager runs a unit of work in a database transaction. Repository calls
+// made with the context passed to fn join the transaction; calling
+// WithinTx again inside fn opens a savepoint, so a failed inner step can be
+// rolled back on its own. fn may run more than once if the database asks
+// for a retry, so it must not have side effects outside the transaction.
+type Manager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
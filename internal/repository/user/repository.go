@@ -2,12 +2,24 @@ package user
 
 import (
 	"context"
+	"errors"
 
 	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/query"
 )
 
+var (
+	ErrNotFound   = errors.New("user not found")
+	ErrEmailTaken = errors.New("email is already registered")
+)
+
+// Events passed to the write methods are stored in the outbox atomically
+// with the change; see model.Event.
 type Repository interface {
 	FindUserByEmail(ctx context.Context, email string) (*model.User, error)
 	FindUserById(ctx context.Context, id int64) (*model.User, error)
-	CreateUser(ctx context.Context, user *model.User) error
+	CreateUser(ctx context.Context, user *model.User, events ...*model.Event) error
+	ListUsers(ctx context.Context, filter query.Filter) ([]*model.User, int, error)
+	UpdateUser(ctx context.Context, user *model.User, events ...*model.Event) error
+	DeleteUser(ctx context.Context, id int64, events ...*model.Event) error
 }
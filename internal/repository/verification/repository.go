@@ -0,0 +1,25 @@
+package verification
+
+import (
+	"context"
+	"errors"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+var ErrNotFound = errors.New("verification code not found")
+
+type Repository interface {
+	CreateVerificationCode(ctx context.Context, code *model.VerificationCode) error
+	// GetVerificationCode looks up an unused code by hash and type.
+	GetVerificationCode(ctx context.Context, codeHash string, codeType model.VerificationType) (*model.VerificationCode, error)
+	// GetVerificationCodeByBinding looks up an unused code by binding hash and type.
+	GetVerificationCodeByBinding(ctx context.Context, bindingHash string, codeType model.VerificationType) (*model.VerificationCode, error)
+	// GetLatestVerificationCode returns the newest unused code of a type for a user.
+	GetLatestVerificationCode(ctx context.Context, userID int64, codeType model.VerificationType) (*model.VerificationCode, error)
+	IncrementAttempts(ctx context.Context, id int64) (int, error)
+	MarkVerificationCodeUsed(ctx context.Context, id int64) error
+	// InvalidateUserCodes marks every unused code of the given types as used.
+	InvalidateUserCodes(ctx context.Context, userID int64, codeTypes ...model.VerificationType) error
+	DeleteExpiredVerificationCodes(ctx context.Context) error
+}
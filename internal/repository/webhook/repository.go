@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+var (
+	ErrNotFound         = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+type Repository interface {
+	CreateSubscription(ctx context.Context, sub *model.WebhookSubscription) error
+	FindSubscriptionById(ctx context.Context, id int64) (*model.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error)
+	// ListActiveSubscriptions returns the active subscriptions for eventType,
+	// including wildcard ones.
+	ListActiveSubscriptions(ctx context.Context, eventType string) ([]*model.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, sub *model.WebhookSubscription) error
+	DeleteSubscription(ctx context.Context, id int64) error
+
+	// CreateDeliveries queues deliveries, skipping any whose subscription
+	// already has a delivery with the same event ID.
+	CreateDeliveries(ctx context.Context, deliveries []*model.WebhookDelivery) error
+	// ClaimDueDeliveries returns up to limit pending deliveries that are due
+	// and pushes their next attempt back by lease, so concurrent dispatchers
+	// don't send the same delivery twice.
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error)
+	// RecordAttempt stores the outcome of a delivery attempt.
+	RecordAttempt(ctx context.Context, d *model.WebhookDelivery) error
+	FindDeliveryById(ctx context.Context, id int64) (*model.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, status model.DeliveryStatus, offset int, limit int) ([]*model.WebhookDelivery, int, error)
+	// Redeliver requeues a delivery for immediate sending with a fresh
+	// attempt budget.
+	Redeliver(ctx context.Context, id int64) error
+}
@@ -2,23 +2,28 @@ package security
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
 	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/razedwell/go-hand/internal/model"
-	"github.com/razedwell/go-hand/internal/platform/cache"
 	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/platform/metrics"
+	"github.com/razedwell/go-hand/internal/repository/organization"
 	"github.com/razedwell/go-hand/internal/repository/token"
+	"github.com/razedwell/go-hand/internal/repository/user"
 	"github.com/razedwell/go-hand/internal/transport/http/helpers"
 )
 
 type JWTClaims struct {
 	UserID int64  `json:"user_id"`
 	Role   string `json:"role"`
+
+	// Active organization, if the user has switched into one
+	OrgID   int64  `json:"org_id,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
@@ -27,30 +32,51 @@ type JWTManager struct {
 	refreshSecret []byte
 	accessExpiry  time.Duration
 	refreshExpiry time.Duration
-	repo          token.Repository   // Your Postgres Repo
-	redis         *cache.RedisClient // For Logout Blacklist
+	repo          token.Repository        // Your Postgres Repo
+	revocation    Revocation              // For Logout Blacklist
+	policy        RevocationPolicy        // when revocation is unreachable
+	users         user.Repository         // reloaded on refresh
+	orgs          organization.Repository // reloaded on refresh
 }
 
-func NewJWTManager(accessSecret, refreshSecret string, accessExpiry, refreshExpiry time.Duration, repo token.Repository, rdb *cache.RedisClient) *JWTManager {
+func NewJWTManager(accessSecret, refreshSecret string, accessExpiry, refreshExpiry time.Duration, repo token.Repository, revocation Revocation, policy RevocationPolicy, users user.Repository, orgs organization.Repository) *JWTManager {
 	return &JWTManager{
 		accessSecret:  []byte(accessSecret),
 		refreshSecret: []byte(refreshSecret),
 		accessExpiry:  accessExpiry,
 		refreshExpiry: refreshExpiry,
 		repo:          repo,
-		redis:         rdb,
+		revocation:    revocation,
+		policy:        policy,
+		users:         users,
+		orgs:          orgs,
 	}
 }
 
 func (j *JWTManager) hashToken(token string) string {
-	h := sha256.New()
-	h.Write([]byte(token))
-	return hex.EncodeToString(h.Sum(nil))
+	return HashToken(token)
+}
+
+func (j *JWTManager) Authenticate(ctx context.Context, accessTokenStr string) (*JWTClaims, error) {
+	claims, err := j.Verify(accessTokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if j.isRevoked(ctx, accessTokenStr, claims) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
 }
 
-func (j *JWTManager) IsBlacklisted(ctx context.Context, accessTokenStr string) bool {
-	n, _ := j.redis.Client.Exists(ctx, accessTokenStr).Result()
-	return n > 0
+// isRevoked reports whether a verified access token has been logged out.
+// If the revocation store fails, the policy decides.
+func (j *JWTManager) isRevoked(ctx context.Context, accessTokenStr string, claims *JWTClaims) bool {
+	revoked, err := j.revocation.IsRevoked(ctx, revocationID(claims, accessTokenStr))
+	if err != nil {
+		logger.Log.ErrorContext(ctx, "Failed to check token revocation", "policy", j.policy, "error", err)
+		return j.policy != FailOpen
+	}
+	return revoked
 }
 
 func (j *JWTManager) Verify(tokenStr string) (*JWTClaims, error) {
@@ -67,25 +93,40 @@ func (j *JWTManager) Verify(tokenStr string) (*JWTClaims, error) {
 }
 
 func (j *JWTManager) GenerateTokenPair(userID int64, role string) (string, string, error) {
-	accessClaims := &JWTClaims{
-		UserID: userID,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(helpers.GetCurrentTimeStampUTC().Add(j.accessExpiry)),
-			IssuedAt:  jwt.NewNumericDate(helpers.GetCurrentTimeStampUTC()),
-		},
-	}
-	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims).SignedString(j.accessSecret)
+	return j.generateTokenPair(JWTClaims{UserID: userID, Role: role})
+}
+
+// GenerateOrgTokenPair issues a token pair with orgID as the active
+// organization. The caller must have checked the membership.
+func (j *JWTManager) GenerateOrgTokenPair(userID int64, role string, orgID int64, orgRole string) (string, string, error) {
+	return j.generateTokenPair(JWTClaims{UserID: userID, Role: role, OrgID: orgID, OrgRole: orgRole})
+}
+
+func (j *JWTManager) generateTokenPair(claims JWTClaims) (string, string, error) {
+	accessToken, err := j.generateAccessToken(claims)
 	if err != nil {
 		return "", "", err
 	}
 
 	// 2. Generate Refresh Token
+	// It carries the active organization to refresh into. Roles are
+	// reloaded on refresh, so the ones captured here are informational.
+	jti, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
 	refreshExpiryTime := helpers.GetCurrentTimeStampUTC().Add(j.refreshExpiry)
-	refreshClaims := &jwt.RegisteredClaims{
-		Subject:   strconv.FormatInt(userID, 10),
-		ExpiresAt: jwt.NewNumericDate(refreshExpiryTime),
-		IssuedAt:  jwt.NewNumericDate(helpers.GetCurrentTimeStampUTC()),
+	refreshClaims := &JWTClaims{
+		UserID:  claims.UserID,
+		Role:    claims.Role,
+		OrgID:   claims.OrgID,
+		OrgRole: claims.OrgRole,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatInt(claims.UserID, 10),
+			ExpiresAt: jwt.NewNumericDate(refreshExpiryTime),
+			IssuedAt:  jwt.NewNumericDate(helpers.GetCurrentTimeStampUTC()),
+		},
 	}
 	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString(j.refreshSecret)
 	if err != nil {
@@ -93,23 +134,30 @@ func (j *JWTManager) GenerateTokenPair(userID int64, role string) (string, strin
 	}
 
 	hash := j.hashToken(refreshToken)
-	if err != nil {
-		return "", "", err
-	}
 	err = j.repo.CreateRefreshToken(context.Background(), &model.RefreshToken{
-		UserID:    userID,
+		UserID:    claims.UserID,
 		TokenHash: hash,
 		ExpiresAt: refreshExpiryTime,
 	})
+	if err == nil {
+		metrics.Tokens.WithLabelValues("issued", "jwt").Inc()
+	}
 
 	return accessToken, refreshToken, err
 }
 
-func (j *JWTManager) generateAccessToken(userID int64, role string) (string, error) {
+func (j *JWTManager) generateAccessToken(claims JWTClaims) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
 	accessClaims := &JWTClaims{
-		UserID: userID,
-		Role:   role,
+		UserID:  claims.UserID,
+		Role:    claims.Role,
+		OrgID:   claims.OrgID,
+		OrgRole: claims.OrgRole,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(helpers.GetCurrentTimeStampUTC().Add(j.accessExpiry)),
 			IssuedAt:  jwt.NewNumericDate(helpers.GetCurrentTimeStampUTC()),
 		},
@@ -117,53 +165,120 @@ func (j *JWTManager) generateAccessToken(userID int64, role string) (string, err
 	return jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims).SignedString(j.accessSecret)
 }
 
-func (j *JWTManager) RefreshAccessToken(ctx context.Context, refreshTokenStr string) (string, error) {
+// refreshToken verifies a refresh token and returns its claims and the
+// stored record, which must be neither revoked nor expired.
+func (j *JWTManager) refreshToken(ctx context.Context, refreshTokenStr string) (*JWTClaims, *model.RefreshToken, error) {
 	// 1. Verify Refresh Token Signature
-	token, err := jwt.Parse(refreshTokenStr, func(t *jwt.Token) (interface{}, error) {
+	refreshClaims := &JWTClaims{}
+	token, err := jwt.ParseWithClaims(refreshTokenStr, refreshClaims, func(t *jwt.Token) (interface{}, error) {
 		return j.refreshSecret, nil
 	})
 	if err != nil || !token.Valid {
-		return "", errors.New("invalid refresh token")
+		return nil, nil, errors.New("invalid refresh token")
 	}
 
 	// 2. Check DB for the hash
 	hash := j.hashToken(refreshTokenStr)
 	storedToken, err := j.repo.GetRefreshToken(ctx, hash)
 	if err != nil {
-		return "", errors.New("refresh token not found")
+		return nil, nil, errors.New("refresh token not found")
 	}
 
 	// 3. Security Checks
 	if storedToken.RevokedAt != nil {
-		return "", errors.New("refresh token was revoked")
+		return nil, nil, errors.New("refresh token was revoked")
 	}
 	if helpers.GetCurrentTimeStampUTC().After(storedToken.ExpiresAt) {
-		return "", errors.New("refresh token expired")
+		return nil, nil, errors.New("refresh token expired")
+	}
+	return refreshClaims, storedToken, nil
+}
+
+// RefreshTokenOwner returns the user a valid refresh token was issued to.
+func (j *JWTManager) RefreshTokenOwner(ctx context.Context, refreshTokenStr string) (int64, error) {
+	_, storedToken, err := j.refreshToken(ctx, refreshTokenStr)
+	if err != nil {
+		return 0, err
+	}
+	return storedToken.UserID, nil
+}
+
+func (j *JWTManager) RefreshAccessToken(ctx context.Context, refreshTokenStr string) (string, error) {
+	refreshClaims, storedToken, err := j.refreshToken(ctx, refreshTokenStr)
+	if err != nil {
+		return "", err
 	}
 
 	// 4. Generate NEW Access Token (Keep the user logged in)
-	// You might want to fetch the latest Role from UserRepo here
-	return j.generateAccessToken(storedToken.UserID, "user")
+	// The claims come from the current user and membership, so bans, role
+	// changes and removals apply from the next refresh.
+	u, err := j.users.FindUserById(ctx, storedToken.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return "", errors.New("refresh token user not found")
+		}
+		return "", err
+	}
+	if u.IsBanned || !u.IsActive {
+		return "", errors.New("account disabled")
+	}
+	claims := JWTClaims{UserID: u.ID, Role: string(u.Role)}
+	if refreshClaims.OrgID != 0 {
+		m, err := j.orgs.FindMembership(ctx, refreshClaims.OrgID, u.ID)
+		switch {
+		case err == nil:
+			claims.OrgID, claims.OrgRole = m.OrgID, string(m.Role)
+		case errors.Is(err, organization.ErrMembershipNotFound):
+			// Removed from the organization: refresh without one.
+		default:
+			return "", err
+		}
+	}
+
+	accessToken, err := j.generateAccessToken(claims)
+	if err == nil {
+		metrics.Tokens.WithLabelValues("refreshed", "jwt").Inc()
+	}
+	return accessToken, err
 }
 
-func (j *JWTManager) BlacklistTokens(ctx context.Context, accessTokenStr string, refreshTokenStr string) error {
-	// Blacklist Access Token in Redis
-	accessToken, err := jwt.ParseWithClaims(accessTokenStr, &JWTClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return j.accessSecret, nil
-	})
-	if err == nil && accessToken.Valid {
-		claims := accessToken.Claims.(*JWTClaims)
-		expiry := claims.ExpiresAt.Time.Sub(helpers.GetCurrentTimeStampUTC())
-		j.redis.Client.Set(ctx, accessTokenStr, "blacklisted", expiry)
+// RevokeRefreshToken revokes a single refresh token, e.g. after it has been
+// exchanged for a new pair.
+func (j *JWTManager) RevokeRefreshToken(ctx context.Context, refreshTokenStr string) error {
+	if err := j.repo.RevokeRefreshToken(ctx, j.hashToken(refreshTokenStr)); err != nil {
+		return err
+	}
+	metrics.Tokens.WithLabelValues("revoked", "jwt").Inc()
+	return nil
+}
+
+func (j *JWTManager) RevokeTokens(ctx context.Context, accessTokenStr string, refreshTokenStr string) error {
+	// Revoke Access Token until it expires
+	var revokeErr error
+	claims, err := j.Verify(accessTokenStr)
+	if err == nil {
+		revokeErr = j.revocation.Revoke(ctx, revocationID(claims, accessTokenStr), claims.ExpiresAt.Time)
+		if revokeErr != nil {
+			logger.Log.ErrorContext(ctx, "Failed to revoke access token", "error", revokeErr)
+		}
 	} else {
-		logger.Log.Printf("Failed to parse access token for blacklisting: %v", err)
+		logger.Log.WarnContext(ctx, "Failed to parse access token for revocation", "error", err)
 	}
 
 	// Revoke Refresh Token in DB
 	hash := j.hashToken(refreshTokenStr)
 	err = j.repo.RevokeRefreshToken(ctx, hash)
 	if err != nil {
-		logger.Log.Printf("Failed to revoke refresh token: %v", err)
+		logger.Log.ErrorContext(ctx, "Failed to revoke refresh token", "error", err)
 	}
-	return err
+	if revokeErr == nil && err == nil {
+		metrics.Tokens.WithLabelValues("revoked", "jwt").Inc()
+	}
+	return errors.Join(revokeErr, err)
+}
+
+// newTokenID returns a unique jti, which also keeps two tokens issued in the
+// same second from being identical.
+func newTokenID() (string, error) {
+	return RandomToken(16)
 }
@@ -0,0 +1,148 @@
+package security_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/cache"
+	"github.com/razedwell/go-hand/internal/repository/organization"
+	"github.com/razedwell/go-hand/internal/security"
+)
+
+// memberships is an organization.Repository holding only memberships.
+type memberships struct {
+	organization.Repository
+	roles map[int64]model.OrgRole // userID -> role in org 1
+}
+
+func (m *memberships) FindMembership(ctx context.Context, orgID int64, userID int64) (*model.Membership, error) {
+	role, ok := m.roles[userID]
+	if !ok || orgID != 1 {
+		return nil, organization.ErrMembershipNotFound
+	}
+	return &model.Membership{OrgID: orgID, UserID: userID, Role: role}, nil
+}
+
+func newJWTManager(t *testing.T) (*security.JWTManager, *memory.UserRepo, *memberships) {
+	t.Helper()
+	users := memory.NewUserRepo(nil)
+	orgs := &memberships{roles: map[int64]model.OrgRole{}}
+	store := cache.NewMemoryStore()
+	j := security.NewJWTManager("access-secret-for-tests-only-32b", "refresh-secret-for-tests-only-32", time.Minute, time.Hour, memory.NewTokenRepo(), store, security.FailClosed, users, orgs)
+	return j, users, orgs
+}
+
+func createUser(t *testing.T, users *memory.UserRepo, role model.Role) *model.User {
+	t.Helper()
+	u := &model.User{Email: "ada@example.com", Role: role, IsActive: true}
+	if err := users.CreateUser(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func refreshedClaims(t *testing.T, j *security.JWTManager, refreshToken string) *security.JWTClaims {
+	t.Helper()
+	ctx := context.Background()
+	accessToken, err := j.RefreshAccessToken(ctx, refreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	claims, err := j.Authenticate(ctx, accessToken)
+	if err != nil {
+		t.Fatalf("authenticate refreshed token: %v", err)
+	}
+	return claims
+}
+
+func TestRefreshUsesCurrentRole(t *testing.T) {
+	ctx := context.Background()
+	j, users, _ := newJWTManager(t)
+	u := createUser(t, users, model.RoleAdmin)
+
+	_, refreshToken, err := j.GenerateTokenPair(u.ID, string(u.Role))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u.Role = model.RoleUser
+	if err := users.UpdateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if claims := refreshedClaims(t, j, refreshToken); claims.Role != string(model.RoleUser) {
+		t.Fatalf("refresh kept the old role %q", claims.Role)
+	}
+}
+
+func TestRefreshRejectsDisabledAccounts(t *testing.T) {
+	ctx := context.Background()
+	for name, disable := range map[string]func(*model.User){
+		"banned":      func(u *model.User) { u.IsBanned = true },
+		"deactivated": func(u *model.User) { u.IsActive = false },
+	} {
+		t.Run(name, func(t *testing.T) {
+			j, users, _ := newJWTManager(t)
+			u := createUser(t, users, model.RoleUser)
+			_, refreshToken, err := j.GenerateTokenPair(u.ID, string(u.Role))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			disable(u)
+			if err := users.UpdateUser(ctx, u); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := j.RefreshAccessToken(ctx, refreshToken); err == nil {
+				t.Fatal("refresh succeeded for a disabled account")
+			}
+		})
+	}
+}
+
+func TestRefreshUsesCurrentMembership(t *testing.T) {
+	j, users, orgs := newJWTManager(t)
+	u := createUser(t, users, model.RoleUser)
+	orgs.roles[u.ID] = model.OrgRoleAdmin
+
+	_, refreshToken, err := j.GenerateOrgTokenPair(u.ID, string(u.Role), 1, string(model.OrgRoleAdmin))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orgs.roles[u.ID] = model.OrgRoleMember
+	claims := refreshedClaims(t, j, refreshToken)
+	if claims.OrgID != 1 || claims.OrgRole != string(model.OrgRoleMember) {
+		t.Fatalf("refresh kept the old org role: org %d, role %q", claims.OrgID, claims.OrgRole)
+	}
+
+	delete(orgs.roles, u.ID)
+	claims = refreshedClaims(t, j, refreshToken)
+	if claims.OrgID != 0 || claims.OrgRole != "" {
+		t.Fatalf("refresh kept a removed membership: org %d, role %q", claims.OrgID, claims.OrgRole)
+	}
+}
+
+func TestRefreshTokenOwner(t *testing.T) {
+	ctx := context.Background()
+	j, users, _ := newJWTManager(t)
+	u := createUser(t, users, model.RoleUser)
+
+	_, refreshToken, err := j.GenerateTokenPair(u.ID, string(u.Role))
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, err := j.RefreshTokenOwner(ctx, refreshToken)
+	if err != nil || owner != u.ID {
+		t.Fatalf("expected owner %d, got %d, %v", u.ID, owner, err)
+	}
+
+	if err := j.RevokeRefreshToken(ctx, refreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.RefreshTokenOwner(ctx, refreshToken); err == nil {
+		t.Fatal("revoked refresh token still has an owner")
+	}
+}
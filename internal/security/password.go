@@ -1,12 +1,35 @@
 package security
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/razedwell/go-hand/internal/platform/tracing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func HashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "bcrypt.Hash")
+	defer span.End()
 
-func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
 }
 
-func VerifyPassword(hash, password string) bool {
+func VerifyPassword(ctx context.Context, hash, password string) bool {
+	_, span := tracing.Start(ctx, "bcrypt.Compare")
+	defer span.End()
+
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
 }
+
+// UnusablePasswordHash hashes a random secret nobody knows. It is used for
+// accounts provisioned by an external identity provider.
+func UnusablePasswordHash(ctx context.Context) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return HashPassword(ctx, hex.EncodeToString(secret))
+}
@@ -0,0 +1,39 @@
+package security
+
+import (
+	"context"
+	"time"
+)
+
+// Revocation records revoked access tokens by ID (the jti claim) until they
+// would have expired anyway. cache.RedisClient, cache.MemoryStore and
+// postgres.RevocationRepo implement it.
+type Revocation interface {
+	Revoke(ctx context.Context, id string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// RevocationPolicy decides what happens to an access token when the
+// revocation store can't be reached.
+type RevocationPolicy string
+
+const (
+	// FailClosed rejects the token, trading availability for the guarantee
+	// that a logged-out token is never accepted.
+	FailClosed RevocationPolicy = "closed"
+	// FailOpen accepts the token as if it had not been revoked.
+	FailOpen RevocationPolicy = "open"
+)
+
+func (p RevocationPolicy) Valid() bool {
+	return p == FailClosed || p == FailOpen
+}
+
+// revocationID names an access token in the revocation store. Tokens
+// issued before the jti claim was added fall back to their hash.
+func revocationID(claims *JWTClaims, tokenStr string) string {
+	if claims.ID != "" {
+		return claims.ID
+	}
+	return HashToken(tokenStr)
+}
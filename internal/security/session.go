@@ -0,0 +1,168 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/platform/metrics"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrInvalidSession  = errors.New("invalid or expired session")
+)
+
+// SessionStore keeps sessions by the hash of their ID. Entries must expire
+// on their own after ttl. cache.RedisClient and cache.MemoryStore implement
+// it.
+type SessionStore interface {
+	SaveSession(ctx context.Context, id string, session *model.Session, ttl time.Duration) error
+	// TouchSession is SaveSession for a session that must already exist: it
+	// reports false and stores nothing if id is unknown or expired.
+	TouchSession(ctx context.Context, id string, session *model.Session, ttl time.Duration) (bool, error)
+	// GetSession returns ErrSessionNotFound for unknown or expired IDs.
+	GetSession(ctx context.Context, id string) (*model.Session, error)
+	DeleteSession(ctx context.Context, id string) error
+	DeleteUserSessions(ctx context.Context, userID int64) error
+}
+
+type SessionConfig struct {
+	IdleTimeout     time.Duration // since the session was last used
+	AbsoluteTimeout time.Duration // since login, however active the session is
+}
+
+// SessionManager issues opaque session IDs. Unlike access tokens they carry
+// nothing, so every request is resolved against the store and a deleted
+// session stops working immediately.
+type SessionManager struct {
+	store SessionStore
+	cfg   SessionConfig
+}
+
+func NewSessionManager(store SessionStore, cfg SessionConfig) *SessionManager {
+	return &SessionManager{store: store, cfg: cfg}
+}
+
+func (m *SessionManager) GenerateTokenPair(userID int64, role string) (string, string, error) {
+	return m.create(model.Session{UserID: userID, Role: role})
+}
+
+// GenerateOrgTokenPair starts a session with orgID as the active
+// organization. The caller must have checked the membership.
+func (m *SessionManager) GenerateOrgTokenPair(userID int64, role string, orgID int64, orgRole string) (string, string, error) {
+	return m.create(model.Session{UserID: userID, Role: role, OrgID: orgID, OrgRole: orgRole})
+}
+
+// create stores a new session and returns its ID as the access token. There
+// is no refresh token.
+func (m *SessionManager) create(session model.Session) (string, string, error) {
+	id, err := RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	now := helpers.GetCurrentTimeStampUTC()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+
+	if err := m.store.SaveSession(context.Background(), HashToken(id), &session, m.ttl(&session, now)); err != nil {
+		return "", "", err
+	}
+	metrics.Tokens.WithLabelValues("issued", "session").Inc()
+	return id, "", nil
+}
+
+// Authenticate resolves a session ID and records the activity, which
+// pushes back the idle timeout.
+func (m *SessionManager) Authenticate(ctx context.Context, sessionID string) (*JWTClaims, error) {
+	key := HashToken(sessionID)
+	session, err := m.store.GetSession(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+
+	now := helpers.GetCurrentTimeStampUTC()
+	ttl := m.ttl(session, now)
+	if ttl <= 0 {
+		if err := m.store.DeleteSession(ctx, key); err != nil {
+			logger.Log.ErrorContext(ctx, "Failed to delete expired session", "error", err)
+		}
+		return nil, ErrInvalidSession
+	}
+
+	// A plain save would bring back a session deleted since the lookup,
+	// e.g. by a logout or ban.
+	session.LastSeenAt = now
+	found, err := m.store.TouchSession(ctx, key, session, m.ttl(session, now))
+	if err != nil {
+		logger.Log.ErrorContext(ctx, "Failed to record session activity", "error", err)
+	} else if !found {
+		return nil, ErrInvalidSession
+	}
+
+	return &JWTClaims{
+		UserID:  session.UserID,
+		Role:    session.Role,
+		OrgID:   session.OrgID,
+		OrgRole: session.OrgRole,
+	}, nil
+}
+
+// ttl is how much longer the session may live: the idle timeout from its
+// last use, capped by the absolute timeout.
+func (m *SessionManager) ttl(session *model.Session, now time.Time) time.Duration {
+	idle := session.LastSeenAt.Add(m.cfg.IdleTimeout).Sub(now)
+	absolute := session.CreatedAt.Add(m.cfg.AbsoluteTimeout).Sub(now)
+	return min(idle, absolute)
+}
+
+func (m *SessionManager) RefreshAccessToken(ctx context.Context, refreshTokenStr string) (string, error) {
+	return "", ErrRefreshUnsupported
+}
+
+func (m *SessionManager) RefreshTokenOwner(ctx context.Context, refreshTokenStr string) (int64, error) {
+	return 0, ErrRefreshUnsupported
+}
+
+// RevokeRefreshToken is a no-op: sessions have no refresh token.
+func (m *SessionManager) RevokeRefreshToken(ctx context.Context, refreshTokenStr string) error {
+	return nil
+}
+
+func (m *SessionManager) RevokeTokens(ctx context.Context, sessionID string, refreshTokenStr string) error {
+	if sessionID == "" {
+		return nil
+	}
+	if err := m.store.DeleteSession(ctx, HashToken(sessionID)); err != nil {
+		return err
+	}
+	metrics.Tokens.WithLabelValues("revoked", "session").Inc()
+	return nil
+}
+
+// RevokeUserSessions ends every session of a user, e.g. after a ban.
+func (m *SessionManager) RevokeUserSessions(ctx context.Context, userID int64) error {
+	return m.store.DeleteUserSessions(ctx, userID)
+}
+
+// HandleEvent ends the sessions of users that were banned, deactivated or
+// deleted, or whose role or organization membership changed, since a
+// session keeps the roles it was created with. Subscribe it to those event
+// types.
+func (m *SessionManager) HandleEvent(ctx context.Context, e *model.Event) error {
+	if e.SubjectType != "user" {
+		return nil
+	}
+	userID, err := strconv.ParseInt(e.SubjectID, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return m.RevokeUserSessions(ctx, userID)
+}
@@ -0,0 +1,69 @@
+package security_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/cache"
+	"github.com/razedwell/go-hand/internal/security"
+)
+
+// racingStore deletes every session right after it is read, as a logout
+// running alongside the request would.
+type racingStore struct {
+	*cache.MemoryStore
+}
+
+func (s racingStore) GetSession(ctx context.Context, id string) (*model.Session, error) {
+	session, err := s.MemoryStore.GetSession(ctx, id)
+	if err == nil {
+		err = s.DeleteSession(ctx, id)
+	}
+	return session, err
+}
+
+func newSessionManager(store security.SessionStore) *security.SessionManager {
+	return security.NewSessionManager(store, security.SessionConfig{IdleTimeout: time.Minute, AbsoluteTimeout: time.Hour})
+}
+
+func TestSessionActivityDoesNotResurrectDeletedSession(t *testing.T) {
+	ctx := context.Background()
+	store := cache.NewMemoryStore()
+	m := newSessionManager(racingStore{store})
+
+	sessionID, _, err := m.GenerateTokenPair(1, string(model.RoleUser))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Authenticate(ctx, sessionID); !errors.Is(err, security.ErrInvalidSession) {
+		t.Fatalf("expected ErrInvalidSession, got %v", err)
+	}
+	if _, err := store.GetSession(ctx, security.HashToken(sessionID)); !errors.Is(err, security.ErrSessionNotFound) {
+		t.Fatalf("deleted session was stored again: %v", err)
+	}
+}
+
+func TestSessionEndsWhenRolesChange(t *testing.T) {
+	ctx := context.Background()
+	m := newSessionManager(cache.NewMemoryStore())
+
+	for _, e := range []*model.Event{
+		model.NewUserEvent(model.EventUserRoleChanged, 1, map[string]any{"from": model.RoleAdmin, "to": model.RoleUser}),
+		model.NewUserEvent(model.EventOrgMemberRoleChanged, 1, map[string]any{"org_id": 1, "role": model.OrgRoleMember}),
+		model.NewUserEvent(model.EventOrgMemberRemoved, 1, map[string]any{"org_id": 1}),
+	} {
+		sessionID, _, err := m.GenerateOrgTokenPair(1, string(model.RoleAdmin), 1, string(model.OrgRoleOwner))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.HandleEvent(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Authenticate(ctx, sessionID); !errors.Is(err, security.ErrInvalidSession) {
+			t.Fatalf("%s: session still valid, err %v", e.Type, err)
+		}
+	}
+}
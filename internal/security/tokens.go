@@ -0,0 +1,35 @@
+package security
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrTokenRevoked       = errors.New("token revoked")
+	ErrRefreshUnsupported = errors.New("sessions are extended on use and have no refresh token")
+)
+
+// TokenManager issues and checks the credentials handed to clients.
+// JWTManager issues signed access tokens with refresh tokens;
+// SessionManager issues opaque session IDs and no refresh token.
+type TokenManager interface {
+	GenerateTokenPair(userID int64, role string) (string, string, error)
+	GenerateOrgTokenPair(userID int64, role string, orgID int64, orgRole string) (string, string, error)
+	RefreshAccessToken(ctx context.Context, refreshTokenStr string) (string, error)
+	RevokeRefreshToken(ctx context.Context, refreshTokenStr string) error
+	RevokeTokens(ctx context.Context, accessTokenStr string, refreshTokenStr string) error
+
+	// RefreshTokenOwner returns the user a valid refresh token belongs to,
+	// or ErrRefreshUnsupported if there are no refresh tokens.
+	RefreshTokenOwner(ctx context.Context, refreshTokenStr string) (int64, error)
+
+	// Authenticate returns the claims of a valid access token, or
+	// ErrTokenRevoked if it has been logged out.
+	Authenticate(ctx context.Context, accessTokenStr string) (*JWTClaims, error)
+}
+
+var (
+	_ TokenManager = (*JWTManager)(nil)
+	_ TokenManager = (*SessionManager)(nil)
+)
@@ -0,0 +1,356 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+// appendAt links a new event into the chain the way postgres.AuditRepo
+// does, with at as the current time.
+func (s *eventStore) appendAt(at time.Time, action string) *model.AuditEvent {
+	e := &model.AuditEvent{Action: model.AuditAction(action), IP: "192.0.2.1"}
+	e.CreatedAt = at.UTC().Truncate(time.Microsecond)
+	e.Segment = e.CreatedAt.Format(time.DateOnly)
+	if head := s.head(e.Segment); head != nil {
+		e.Seq, e.PrevHash = head.Seq+1, head.Hash
+	} else {
+		e.Seq = 1
+	}
+	e.Hash = e.ChainHash()
+	s.add(e)
+	return e
+}
+
+func (s *eventStore) head(segment string) *model.AuditEvent {
+	var head *model.AuditEvent
+	for _, e := range s.events {
+		if e.Segment == segment && (head == nil || e.Seq > head.Seq) {
+			head = e
+		}
+	}
+	return head
+}
+
+func (s *eventStore) event(segment string, seq int64) *model.AuditEvent {
+	for _, e := range s.events {
+		if e.Segment == segment && e.Seq == seq {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *eventStore) remove(segment string, seq int64) {
+	s.events = slices.DeleteFunc(s.events, func(e *model.AuditEvent) bool { return e.Segment == segment && e.Seq == seq })
+}
+
+func (s *eventStore) ListSegments(ctx context.Context, from string, to string) ([]string, error) {
+	var segments []string
+	for _, e := range s.events {
+		if (from == "" || e.Segment >= from) && (to == "" || e.Segment <= to) && !slices.Contains(segments, e.Segment) {
+			segments = append(segments, e.Segment)
+		}
+	}
+	slices.Sort(segments)
+	return segments, nil
+}
+
+func (s *eventStore) ListSegmentEvents(ctx context.Context, segment string, afterSeq int64, limit int) ([]*model.AuditEvent, error) {
+	var events []*model.AuditEvent
+	for _, e := range s.events {
+		if e.Segment == segment && e.Seq > afterSeq {
+			events = append(events, e)
+		}
+	}
+	slices.SortFunc(events, func(a, b *model.AuditEvent) int { return int(a.Seq - b.Seq) })
+	return events[:min(limit, len(events))], nil
+}
+
+func (s *eventStore) SegmentHeads(ctx context.Context, since string) ([]*model.AuditEvent, error) {
+	segments, _ := s.ListSegments(ctx, since, "")
+	heads := make([]*model.AuditEvent, len(segments))
+	for i, segment := range segments {
+		heads[i] = s.head(segment)
+	}
+	return heads, nil
+}
+
+func (s *eventStore) CreateCheckpoint(ctx context.Context, cp *model.AuditCheckpoint) error {
+	cp.ID = int64(len(s.checkpoints) + 1)
+	s.checkpoints = append(s.checkpoints, cp)
+	return nil
+}
+
+func (s *eventStore) ListCheckpoints(ctx context.Context, segment string) ([]*model.AuditCheckpoint, error) {
+	var checkpoints []*model.AuditCheckpoint
+	for _, cp := range s.checkpoints {
+		if cp.Segment == segment {
+			checkpoints = append(checkpoints, cp)
+		}
+	}
+	return checkpoints, nil
+}
+
+func (s *eventStore) LatestCheckpoints(ctx context.Context, since string) ([]*model.AuditCheckpoint, error) {
+	latest := map[string]*model.AuditCheckpoint{}
+	for _, cp := range s.checkpoints {
+		if cp.Segment >= since && (latest[cp.Segment] == nil || cp.Seq > latest[cp.Segment].Seq) {
+			latest[cp.Segment] = cp
+		}
+	}
+	var checkpoints []*model.AuditCheckpoint
+	for _, cp := range latest {
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, nil
+}
+
+const (
+	day1 = "2026-03-14"
+	day2 = "2026-03-15"
+)
+
+// chainFixture has three events in each of two segments (IDs 1-3 and 4-6),
+// checkpoint 1 on day1 seq 2 and checkpoint 2 on day2 seq 3.
+func chainFixture(svc *Service) *eventStore {
+	store := &eventStore{}
+	for i := 0; i < 6; i++ {
+		store.appendAt(day.Add(time.Duration(i*8)*time.Hour), "user.login")
+	}
+	for _, at := range []struct {
+		segment string
+		seq     int64
+	}{{day1, 2}, {day2, 3}} {
+		head := store.event(at.segment, at.seq)
+		store.CreateCheckpoint(context.Background(), &model.AuditCheckpoint{
+			Segment: at.segment, Seq: at.seq, Hash: head.Hash,
+			KeyID: svc.keyID(), Signature: svc.sign(at.segment, at.seq, head.Hash),
+		})
+	}
+	return store
+}
+
+// rehash recomputes the chain of segment from seq onwards, as someone
+// covering up an edit would.
+func (s *eventStore) rehash(segment string, seq int64) {
+	for e := s.event(segment, seq); e != nil; e = s.event(segment, e.Seq+1) {
+		if prev := s.event(segment, e.Seq-1); prev != nil {
+			e.PrevHash = prev.Hash
+		}
+		e.Hash = e.ChainHash()
+	}
+}
+
+func TestVerifyIntactChain(t *testing.T) {
+	svc := NewService(nil, "audit-key")
+	svc.events = chainFixture(svc)
+
+	report, err := svc.Verify(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &VerifyReport{Segments: 2, Events: 6, Checkpoints: 2}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("got %+v, want %+v", report, want)
+	}
+
+	report, err = svc.Verify(context.Background(), day2, day2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&VerifyReport{Segments: 1, Events: 3, Checkpoints: 1}); !reflect.DeepEqual(report, want) {
+		t.Fatalf("day2 only: got %+v, want %+v", report, want)
+	}
+}
+
+func TestVerifyBreaks(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(s *eventStore)
+		want   Break
+	}{
+		{
+			"missing event",
+			func(s *eventStore) { s.remove(day2, 2) },
+			Break{Segment: day2, Seq: 2, Reason: "event is missing"},
+		},
+		{
+			"missing first event",
+			func(s *eventStore) { s.remove(day1, 1) },
+			Break{Segment: day1, Seq: 1, Reason: "event is missing"},
+		},
+		{
+			"wrong previous hash",
+			func(s *eventStore) {
+				e := s.event(day2, 3)
+				e.PrevHash = s.event(day2, 1).Hash
+				e.Hash = e.ChainHash()
+			},
+			Break{Segment: day2, Seq: 3, EventID: 6, Reason: "previous hash does not match the preceding event"},
+		},
+		{
+			"edited content",
+			func(s *eventStore) { s.event(day2, 2).IP = "198.51.100.7" },
+			Break{Segment: day2, Seq: 2, EventID: 5, Reason: "hash does not match the event content"},
+		},
+		{
+			"edited timestamp",
+			func(s *eventStore) { s.event(day1, 3).CreatedAt = s.event(day1, 3).CreatedAt.Add(time.Microsecond) },
+			Break{Segment: day1, Seq: 3, EventID: 3, Reason: "hash does not match the event content"},
+		},
+		{
+			"edit with the chain recomputed",
+			func(s *eventStore) {
+				s.event(day1, 1).IP = "198.51.100.7"
+				s.rehash(day1, 1)
+			},
+			Break{Segment: day1, Seq: 2, EventID: 2, Reason: "hash differs from signed checkpoint 1"},
+		},
+		{
+			"tail deleted after a checkpoint",
+			func(s *eventStore) { s.remove(day2, 3) },
+			Break{Segment: day2, Seq: 3, Reason: "event is missing; checkpoint 2 covers up to seq 3"},
+		},
+		{
+			"forged checkpoint digest",
+			func(s *eventStore) { s.checkpoints[1].Hash = s.event(day2, 2).Hash },
+			Break{Segment: day2, Seq: 3, Reason: "checkpoint 2 has an invalid signature"},
+		},
+		{
+			"forged checkpoint signature",
+			func(s *eventStore) { s.checkpoints[0].Signature = "00" + s.checkpoints[0].Signature[2:] },
+			Break{Segment: day1, Seq: 2, Reason: "checkpoint 1 has an invalid signature"},
+		},
+		{
+			"unknown key",
+			func(s *eventStore) { s.checkpoints[0].KeyID = "0123456789abcdef" },
+			Break{Segment: day1, Seq: 2, Reason: "checkpoint 1 was signed with an unknown key 0123456789abcdef"},
+		},
+		{
+			"first break wins",
+			func(s *eventStore) {
+				s.event(day2, 1).IP = "198.51.100.7"
+				s.event(day1, 3).IP = "198.51.100.7"
+				s.remove(day1, 2)
+			},
+			Break{Segment: day1, Seq: 2, Reason: "event is missing"},
+		},
+	}
+	for _, tt := range tests {
+		svc := NewService(nil, "audit-key")
+		store := chainFixture(svc)
+		svc.events = store
+		tt.tamper(store)
+
+		report, err := svc.Verify(context.Background(), "", "")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if report.Broken == nil {
+			t.Errorf("%s: chain verified", tt.name)
+			continue
+		}
+		if *report.Broken != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *report.Broken, tt.want)
+		}
+	}
+}
+
+func TestVerifyRotatedKey(t *testing.T) {
+	old := NewService(nil, "old-key")
+	store := chainFixture(old)
+	svc := NewService(store, "new-key")
+
+	report, err := svc.Verify(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Break{Segment: day1, Seq: 2, Reason: "checkpoint 1 was signed with an unknown key " + old.keyID()}
+	if report.Broken == nil || *report.Broken != want {
+		t.Fatalf("got %+v, want %+v", report.Broken, want)
+	}
+}
+
+func TestSegmentsSplitAtUTCMidnight(t *testing.T) {
+	store := &eventStore{}
+	svc := NewService(store, "audit-key")
+
+	// 01:30 in UTC+2 is still the previous day in UTC.
+	east := time.FixedZone("EET", 2*60*60)
+	last := store.appendAt(time.Date(2026, 3, 15, 1, 30, 0, 0, east), "user.login")
+	beforeMidnight := store.appendAt(time.Date(2026, 3, 14, 23, 59, 59, 999999999, time.UTC), "user.login")
+	afterMidnight := store.appendAt(time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), "user.login")
+
+	if last.Segment != day1 || beforeMidnight.Segment != day1 || beforeMidnight.Seq != 2 {
+		t.Fatalf("events before UTC midnight: %s/%d, %s/%d", last.Segment, last.Seq, beforeMidnight.Segment, beforeMidnight.Seq)
+	}
+	if afterMidnight.Segment != day2 || afterMidnight.Seq != 1 || afterMidnight.PrevHash != "" {
+		t.Fatalf("first event after midnight: %s/%d prev %q", afterMidnight.Segment, afterMidnight.Seq, afterMidnight.PrevHash)
+	}
+
+	// The driver may hand timestamps back in the local zone; the chain
+	// hashes the UTC instant, so that must not break it.
+	for _, e := range store.events {
+		e.CreatedAt = e.CreatedAt.In(east)
+	}
+	report, err := svc.Verify(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Broken != nil || report.Segments != 2 || report.Events != 3 {
+		t.Fatalf("got %+v", report)
+	}
+}
+
+func TestCheckpointCoversPreviousDay(t *testing.T) {
+	store := &eventStore{}
+	svc := NewService(store, "audit-key")
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	old := store.appendAt(now.AddDate(0, 0, -2), "user.login")
+	yesterday := store.appendAt(now.AddDate(0, 0, -1), "user.login")
+	today := store.appendAt(now, "user.login")
+
+	if err := svc.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var signed []string
+	for _, cp := range store.checkpoints {
+		signed = append(signed, cp.Segment)
+	}
+	// Yesterday's segment gets its final checkpoint after midnight; older
+	// segments are left alone.
+	if want := []string{yesterday.Segment, today.Segment}; !reflect.DeepEqual(signed, want) {
+		t.Fatalf("checkpointed %v, want %v (not %s)", signed, want, old.Segment)
+	}
+
+	// Nothing moved, so nothing is signed again.
+	if err := svc.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.checkpoints) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %d", len(store.checkpoints))
+	}
+
+	next := store.appendAt(now, "user.logout")
+	if err := svc.Checkpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if cp := store.checkpoints[len(store.checkpoints)-1]; len(store.checkpoints) != 3 || cp.Seq != next.Seq || cp.Hash != next.Hash {
+		t.Fatalf("checkpoints %+v", store.checkpoints)
+	}
+
+	report, err := svc.Verify(ctx, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Broken != nil || report.Checkpoints != 3 {
+		t.Fatalf("got %+v", report)
+	}
+}
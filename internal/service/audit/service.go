@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/repository/audit"
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+// Recorder writes audit events. Services depend on this rather than on
+// Service so they can be wired without the admin query side.
+type Recorder interface {
+	Record(ctx context.Context, event *model.AuditEvent)
+}
+
+// Discard is a Recorder that drops every event, for wiring without an
+// audit store.
+var Discard Recorder = discard{}
+
+type discard struct{}
+
+func (discard) Record(ctx context.Context, event *model.AuditEvent) {}
+
+// Request describes the client that triggered an event.
+type Request struct {
+	IP        string
+	UserAgent string
+}
+
+type ctxKey struct{}
+
+// WithRequest stores the client details that Record attaches to events.
+func WithRequest(ctx context.Context, req Request) context.Context {
+	return context.WithValue(ctx, ctxKey{}, req)
+}
+
+func RequestFromContext(ctx context.Context) Request {
+	req, _ := ctx.Value(ctxKey{}).(Request)
+	return req
+}
+
+// Query selects events for the admin API. Zero values don't filter.
+type Query struct {
+	ActorID    *int64
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time // inclusive
+	To         time.Time // exclusive
+	Offset     int
+	Limit      int
+}
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+
+	defaultLimit = 50
+	maxLimit     = 500
+	exportBatch  = 500
+)
+
+var ErrInvalidFormat = errors.New("unsupported export format")
+
+type Service struct {
+	events     audit.Repository
+	signingKey []byte // for checkpoints
+}
+
+var _ Recorder = (*Service)(nil)
+
+func NewService(events audit.Repository, signingKey string) *Service {
+	return &Service{events: events, signingKey: []byte(signingKey)}
+}
+
+// Record appends event, filling in the client details from ctx. A failure is
+// logged rather than returned so auditing never blocks the action itself.
+func (s *Service) Record(ctx context.Context, event *model.AuditEvent) {
+	req := RequestFromContext(ctx)
+	if event.IP == "" {
+		event.IP = req.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = req.UserAgent
+	}
+	// Detach from request cancellation so the record survives a client hang-up.
+	if err := s.events.AppendEvent(context.WithoutCancel(ctx), event); err != nil {
+		logger.Log.ErrorContext(ctx, "Failed to record audit event", "action", event.Action, "error", err)
+	}
+}
+
+// List returns a page of events matching q, newest first.
+func (s *Service) List(ctx context.Context, q Query) ([]*model.AuditEvent, int, error) {
+	filter := q.filter()
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+	if filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+	}
+	return s.events.ListEvents(ctx, filter)
+}
+
+// Export writes every event matching q to w as CSV or JSON lines, newest
+// first. Offset and Limit are ignored; events are read in batches keyed on
+// ID so rows appended during the export don't shift the pages.
+func (s *Service) Export(ctx context.Context, q Query, format string, w io.Writer) error {
+	var write func(*model.AuditEvent) error
+	var flush func() error
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"id", "created_at", "actor_id", "action", "target_type", "target_id", "ip", "user_agent", "metadata"}); err != nil {
+			return err
+		}
+		write = func(e *model.AuditEvent) error { return cw.Write(csvRecord(e)) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		write = func(e *model.AuditEvent) error { return enc.Encode(ToJSON(e)) }
+		flush = func() error { return nil }
+	default:
+		return ErrInvalidFormat
+	}
+
+	base := q.filter()
+	var lastID int64
+	for {
+		filter := query.Filter{Conditions: append([]query.Condition{}, base.Conditions...), Limit: exportBatch}
+		if lastID > 0 {
+			filter.Conditions = append(filter.Conditions, query.Condition{Field: "id", Op: query.OpLt, Value: strconv.FormatInt(lastID, 10)})
+		}
+		events, _, err := s.events.ListEvents(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := write(e); err != nil {
+				return err
+			}
+		}
+		if len(events) < exportBatch {
+			return flush()
+		}
+		lastID = events[len(events)-1].ID
+	}
+}
+
+func (q Query) filter() query.Filter {
+	var conditions []query.Condition
+	if q.ActorID != nil {
+		conditions = append(conditions, query.Condition{Field: "actor_id", Op: query.OpEq, Value: strconv.FormatInt(*q.ActorID, 10)})
+	}
+	if q.Action != "" {
+		conditions = append(conditions, query.Condition{Field: "action", Op: query.OpEq, Value: q.Action})
+	}
+	if q.TargetType != "" {
+		conditions = append(conditions, query.Condition{Field: "target_type", Op: query.OpEq, Value: q.TargetType})
+	}
+	if q.TargetID != "" {
+		conditions = append(conditions, query.Condition{Field: "target_id", Op: query.OpEq, Value: q.TargetID})
+	}
+	if !q.From.IsZero() {
+		conditions = append(conditions, query.Condition{Field: "created_at", Op: query.OpGe, Value: q.From.UTC().Format(time.RFC3339Nano)})
+	}
+	if !q.To.IsZero() {
+		conditions = append(conditions, query.Condition{Field: "created_at", Op: query.OpLt, Value: q.To.UTC().Format(time.RFC3339Nano)})
+	}
+	return query.Filter{Conditions: conditions, Offset: q.Offset, Limit: q.Limit}
+}
+
+// ToJSON is the API and JSONL export representation of an event.
+func ToJSON(e *model.AuditEvent) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          e.ID,
+		"created_at":  e.CreatedAt,
+		"actor_id":    e.ActorID,
+		"action":      e.Action,
+		"target_type": e.TargetType,
+		"target_id":   e.TargetID,
+		"ip":          e.IP,
+		"user_agent":  e.UserAgent,
+		"metadata":    e.Metadata,
+	}
+}
+
+func csvRecord(e *model.AuditEvent) []string {
+	actor := ""
+	if e.ActorID != nil {
+		actor = strconv.FormatInt(*e.ActorID, 10)
+	}
+	metadata, _ := json.Marshal(e.Metadata)
+	return []string{
+		strconv.FormatInt(e.ID, 10),
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		actor,
+		string(e.Action),
+		e.TargetType,
+		e.TargetID,
+		e.IP,
+		e.UserAgent,
+		string(metadata),
+	}
+}
@@ -0,0 +1,280 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/audit"
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+// eventStore is an in-memory audit.Repository. ListEvents follows the
+// filter semantics of postgres.AuditRepo for the fields the service uses.
+type eventStore struct {
+	audit.Repository
+	events      []*model.AuditEvent
+	filters     []query.Filter // every filter ListEvents was called with
+	checkpoints []*model.AuditCheckpoint
+}
+
+// add stores events as they would come back from the database.
+func (s *eventStore) add(events ...*model.AuditEvent) {
+	for _, e := range events {
+		e.ID = int64(len(s.events) + 1)
+		s.events = append(s.events, e)
+	}
+}
+
+func (s *eventStore) ListEvents(ctx context.Context, filter query.Filter) ([]*model.AuditEvent, int, error) {
+	s.filters = append(s.filters, filter)
+	var matched []*model.AuditEvent
+	for _, e := range s.events {
+		ok, err := matchEvent(e, filter.Conditions)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			matched = append(matched, e)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *model.AuditEvent) int { return int(b.ID - a.ID) })
+
+	total := len(matched)
+	matched = matched[min(filter.Offset, len(matched)):]
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+func matchEvent(e *model.AuditEvent, conditions []query.Condition) (bool, error) {
+	for _, c := range conditions {
+		var ok bool
+		switch c.Field {
+		case "id":
+			id, err := strconv.ParseInt(c.Value, 10, 64)
+			if err != nil || c.Op != query.OpLt {
+				return false, errors.New("unsupported id condition")
+			}
+			ok = e.ID < id
+		case "actor_id":
+			ok = e.ActorID != nil && strconv.FormatInt(*e.ActorID, 10) == c.Value
+		case "action":
+			ok = string(e.Action) == c.Value
+		case "target_type":
+			ok = e.TargetType == c.Value
+		case "target_id":
+			ok = e.TargetID == c.Value
+		case "created_at":
+			at, err := time.Parse(time.RFC3339Nano, c.Value)
+			if err != nil {
+				return false, err
+			}
+			switch c.Op {
+			case query.OpGe:
+				ok = !e.CreatedAt.Before(at)
+			case query.OpLt:
+				ok = e.CreatedAt.Before(at)
+			default:
+				return false, errors.New("unsupported created_at condition")
+			}
+		default:
+			return false, errors.New("unknown field " + c.Field)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func ptr[T any](v T) *T { return &v }
+
+var day = time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)
+
+// fixtureEvents are three events an hour apart, oldest first.
+func fixtureEvents() []*model.AuditEvent {
+	return []*model.AuditEvent{
+		{CreatedAt: day.Add(1 * time.Hour), ActorID: ptr(int64(1)), Action: model.AuditAction("user.login"), TargetType: "user", TargetID: "1"},
+		{CreatedAt: day.Add(2 * time.Hour), ActorID: ptr(int64(2)), Action: model.AuditAction("user.login"), TargetType: "user", TargetID: "2"},
+		{CreatedAt: day.Add(3 * time.Hour), ActorID: ptr(int64(1)), Action: model.AuditAction("user.banned"), TargetType: "user", TargetID: "2"},
+	}
+}
+
+func ids(events []*model.AuditEvent) []int64 {
+	out := make([]int64, len(events))
+	for i, e := range events {
+		out[i] = e.ID
+	}
+	return out
+}
+
+func TestListFilters(t *testing.T) {
+	store := &eventStore{}
+	store.add(fixtureEvents()...)
+	svc := NewService(store, "audit-key")
+
+	// A zone east of UTC, so a bound that isn't converted lands hours off.
+	tokyo := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		name string
+		q    Query
+		want []int64
+	}{
+		{"no filter, newest first", Query{}, []int64{3, 2, 1}},
+		{"actor", Query{ActorID: ptr(int64(1))}, []int64{3, 1}},
+		{"action", Query{Action: "user.login"}, []int64{2, 1}},
+		{"target", Query{TargetType: "user", TargetID: "2"}, []int64{3, 2}},
+		{"actor and action", Query{ActorID: ptr(int64(1)), Action: "user.login"}, []int64{1}},
+		{"from is inclusive", Query{From: day.Add(2 * time.Hour)}, []int64{3, 2}},
+		{"to is exclusive", Query{To: day.Add(2 * time.Hour)}, []int64{1}},
+		{"range", Query{From: day.Add(90 * time.Minute), To: day.Add(3 * time.Hour)}, []int64{2}},
+		{"bounds in another zone", Query{From: day.Add(2 * time.Hour).In(tokyo), To: day.Add(3 * time.Hour).In(tokyo)}, []int64{2}},
+		{"no match", Query{Action: "user.deleted"}, []int64{}},
+	}
+	for _, tt := range tests {
+		events, total, err := svc.List(context.Background(), tt.q)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := ids(events); !reflect.DeepEqual(got, tt.want) || total != len(tt.want) {
+			t.Errorf("%s: got %v (total %d), want %v", tt.name, got, total, tt.want)
+		}
+	}
+}
+
+func TestListClampsLimit(t *testing.T) {
+	store := &eventStore{}
+	svc := NewService(store, "audit-key")
+	for _, tt := range []struct{ limit, want int }{{0, defaultLimit}, {-1, defaultLimit}, {10, 10}, {maxLimit + 1, maxLimit}} {
+		if _, _, err := svc.List(context.Background(), Query{Limit: tt.limit}); err != nil {
+			t.Fatal(err)
+		}
+		if got := store.filters[len(store.filters)-1].Limit; got != tt.want {
+			t.Errorf("limit %d: got %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
+
+// awkward has every character CSV has to quote.
+func awkward() *model.AuditEvent {
+	return &model.AuditEvent{
+		CreatedAt: time.Date(2026, 3, 14, 9, 30, 0, 123456000, time.FixedZone("CET", 60*60)),
+		Action:    model.AuditAction("user.updated"),
+		TargetID:  "1",
+		IP:        "192.0.2.1",
+		UserAgent: "Mozilla/5.0 (X11, \"Linux\")\nsecond line",
+		Metadata:  map[string]any{"note": `comma, "quote"`},
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	store := &eventStore{}
+	store.add(awkward(), &model.AuditEvent{CreatedAt: day, ActorID: ptr(int64(7)), Action: model.AuditAction("user.login")})
+	svc := NewService(store, "audit-key")
+
+	var buf bytes.Buffer
+	if err := svc.Export(context.Background(), Query{}, FormatCSV, &buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"id", "created_at", "actor_id", "action", "target_type", "target_id", "ip", "user_agent", "metadata"},
+		{"2", "2026-03-14T00:00:00Z", "7", "user.login", "", "", "", "", "null"},
+		{"1", "2026-03-14T08:30:00.123456Z", "", "user.updated", "", "1", "192.0.2.1", "Mozilla/5.0 (X11, \"Linux\")\nsecond line", `{"note":"comma, \"quote\""}`},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Fatalf("got %q\nwant %q", records, want)
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	store := &eventStore{}
+	store.add(awkward(), &model.AuditEvent{CreatedAt: day, ActorID: ptr(int64(7)), Action: model.AuditAction("user.login")})
+	svc := NewService(store, "audit-key")
+
+	var buf bytes.Buffer
+	if err := svc.Export(context.Background(), Query{}, FormatJSONL, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var lines []map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %d is not one JSON object: %v", len(lines)+1, err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[0]["id"] != float64(2) || lines[0]["actor_id"] != float64(7) {
+		t.Errorf("first line = %v", lines[0])
+	}
+	if lines[1]["actor_id"] != nil || lines[1]["user_agent"] != awkward().UserAgent {
+		t.Errorf("second line = %v", lines[1])
+	}
+	if meta, _ := lines[1]["metadata"].(map[string]any); meta["note"] != `comma, "quote"` {
+		t.Errorf("metadata = %v", lines[1]["metadata"])
+	}
+}
+
+func TestExportFilteredInBatches(t *testing.T) {
+	store := &eventStore{}
+	for i := 0; i < 2*exportBatch+1; i++ {
+		action := "user.login"
+		if i%2 == 1 {
+			action = "user.logout"
+		}
+		store.add(&model.AuditEvent{CreatedAt: day.Add(time.Duration(i) * time.Second), Action: model.AuditAction(action)})
+	}
+	svc := NewService(store, "audit-key")
+
+	var buf bytes.Buffer
+	// Offset and Limit don't apply to exports.
+	if err := svc.Export(context.Background(), Query{Action: "user.login", Offset: 10, Limit: 1}, FormatJSONL, &buf); err != nil {
+		t.Fatal(err)
+	}
+	seen := map[float64]bool{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		if line["action"] != "user.login" || seen[line["id"].(float64)] {
+			t.Fatalf("unexpected line %v", line)
+		}
+		seen[line["id"].(float64)] = true
+	}
+	if len(seen) != exportBatch+1 {
+		t.Fatalf("exported %d events, want %d", len(seen), exportBatch+1)
+	}
+	if len(store.filters) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(store.filters))
+	}
+}
+
+func TestExportRejectsUnknownFormat(t *testing.T) {
+	store := &eventStore{}
+	if err := NewService(store, "audit-key").Export(context.Background(), Query{}, "xml", &bytes.Buffer{}); !errors.Is(err, ErrInvalidFormat) {
+		t.Fatalf("expected ErrInvalidFormat, got %v", err)
+	}
+	if len(store.filters) != 0 {
+		t.Fatal("queried events for an unsupported format")
+	}
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/security"
+)
+
+// Authenticator verifies credentials and returns the matching local user,
+// creating it first if the backend provisions users on demand.
+type Authenticator interface {
+	Authenticate(ctx context.Context, email string, password string) (*model.User, error)
+}
+
+// PasswordAuthenticator checks the bcrypt hash stored on the user.
+type PasswordAuthenticator struct {
+	users user.Repository
+}
+
+func NewPasswordAuthenticator(users user.Repository) *PasswordAuthenticator {
+	return &PasswordAuthenticator{users: users}
+}
+
+func (a *PasswordAuthenticator) Authenticate(ctx context.Context, email string, password string) (*model.User, error) {
+	user, err := a.users.FindUserByEmail(ctx, email)
+	if err != nil || security.VerifyPassword(ctx, user.PasswordHash, password) == false {
+		return nil, errUnauthorized
+	}
+	return user, nil
+}
+
+// DomainAuthenticator picks a backend by the domain part of the email,
+// falling back to a default for unlisted domains.
+type DomainAuthenticator struct {
+	fallback Authenticator
+	domains  map[string]Authenticator
+}
+
+func NewDomainAuthenticator(fallback Authenticator) *DomainAuthenticator {
+	return &DomainAuthenticator{fallback: fallback, domains: map[string]Authenticator{}}
+}
+
+// Route sends logins for the given domains to a.
+func (d *DomainAuthenticator) Route(a Authenticator, domains ...string) {
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			d.domains[domain] = a
+		}
+	}
+}
+
+func (d *DomainAuthenticator) Authenticate(ctx context.Context, email string, password string) (*model.User, error) {
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		if a, ok := d.domains[strings.ToLower(email[i+1:])]; ok {
+			return a.Authenticate(ctx, email, password)
+		}
+	}
+	return d.fallback.Authenticate(ctx, email, password)
+}
@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/repository/identity"
+	"github.com/razedwell/go-hand/internal/repository/transaction"
+	"github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type LDAPConfig struct {
+	URL                string // ldap://host:389 or ldaps://host:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string // service account used for the user search
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // %s is replaced with the escaped email, e.g. (mail=%s)
+	EmailAttr          string
+	FirstNameAttr      string
+	LastNameAttr       string
+	GroupAttr          string                // e.g. memberOf
+	GroupRoles         map[string]model.Role // group DN (case-insensitive) -> role
+	Timeout            time.Duration         // per dial and per request; defaults to 10s
+}
+
+const defaultLDAPTimeout = 10 * time.Second
+
+// LDAPAuthenticator authenticates against a directory using search-then-bind
+// and provisions a local user on first login. Roles are synced from groups
+// only for users it provisioned; local accounts that share an email with a
+// directory entry keep the role given to them here.
+type LDAPAuthenticator struct {
+	cfg        LDAPConfig
+	tx         transaction.Manager
+	users      user.Repository
+	identities identity.Repository
+}
+
+func NewLDAPAuthenticator(cfg LDAPConfig, tx transaction.Manager, users user.Repository, identities identity.Repository) *LDAPAuthenticator {
+	groupRoles := make(map[string]model.Role, len(cfg.GroupRoles))
+	for dn, role := range cfg.GroupRoles {
+		groupRoles[normalizeDN(dn)] = role
+	}
+	cfg.GroupRoles = groupRoles
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultLDAPTimeout
+	}
+
+	return &LDAPAuthenticator{cfg: cfg, tx: tx, users: users, identities: identities}
+}
+
+// connect dials the directory with the configured timeout, shortened to the
+// context deadline. The connection is closed if ctx ends while it is open,
+// which aborts any request in flight.
+func (a *LDAPAuthenticator) connect(ctx context.Context) (*ldap.Conn, func(), error) {
+	timeout := a.cfg.Timeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.cfg.InsecureSkipVerify}
+	conn, err := ldap.DialURL(a.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetTimeout(timeout)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	closeConn := func() {
+		stop()
+		conn.Close()
+	}
+
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			closeConn()
+			return nil, nil, err
+		}
+	}
+	return conn, closeConn, nil
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, email string, password string) (*model.User, error) {
+	// An empty password would be an unauthenticated bind, which most
+	// directories accept.
+	if password == "" {
+		return nil, errUnauthorized
+	}
+
+	entry, err := a.verify(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+	return a.provision(ctx, email, entry)
+}
+
+// verify finds the user's entry with the service account and then binds as
+// that entry with the supplied password.
+func (a *LDAPAuthenticator) verify(ctx context.Context, email string, password string) (*ldap.Entry, error) {
+	conn, closeConn, err := a.connect(ctx)
+	if err != nil {
+		logger.Log.ErrorContext(ctx, "Failed to connect to LDAP", "error", err)
+		return nil, errUnauthorized
+	}
+	defer closeConn()
+
+	if a.cfg.BindDN != "" {
+		err = conn.Bind(a.cfg.BindDN, a.cfg.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		logger.Log.ErrorContext(ctx, "LDAP service bind failed", "error", err)
+		return nil, errUnauthorized
+	}
+
+	attrs := []string{a.cfg.EmailAttr, a.cfg.FirstNameAttr, a.cfg.LastNameAttr, a.cfg.GroupAttr}
+	search := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(email)),
+		attrs,
+		nil,
+	)
+	res, err := conn.Search(search)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		logger.Log.ErrorContext(ctx, "LDAP search failed", "error", err)
+		return nil, errUnauthorized
+	}
+	if res == nil || len(res.Entries) != 1 {
+		return nil, errUnauthorized
+	}
+	entry := res.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		return nil, errUnauthorized
+	}
+	return entry, nil
+}
+
+func (a *LDAPAuthenticator) provision(ctx context.Context, email string, entry *ldap.Entry) (*model.User, error) {
+	role := a.roleFor(entry.GetAttributeValues(a.cfg.GroupAttr))
+	provider := "ldap:" + a.cfg.URL
+	subject := normalizeDN(entry.DN)
+
+	linked, err := a.identities.FindIdentity(ctx, provider, subject)
+	if err == nil {
+		existing, err := a.users.FindUserById(ctx, linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		// The directory owns group membership, so keep the role in sync.
+		if existing.Role != role {
+			existing.Role = role
+			if err := a.users.UpdateUser(ctx, existing); err != nil {
+				return nil, err
+			}
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, identity.ErrNotFound) {
+		return nil, err
+	}
+
+	if mail := entry.GetAttributeValue(a.cfg.EmailAttr); mail != "" {
+		email = mail
+	}
+	email = strings.ToLower(email)
+
+	existing, err := a.users.FindUserByEmail(ctx, email)
+	if err == nil {
+		// A local account the directory didn't create: the bind proved the
+		// password, but the role stays the one assigned here.
+		return existing, nil
+	}
+	if !errors.Is(err, user.ErrNotFound) {
+		return nil, err
+	}
+
+	hashedPassword, err := security.UnusablePasswordHash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := helpers.GetCurrentTimeStampUTC()
+	newUser := &model.User{
+		CreatedAt: now,
+		UpdatedAt: now,
+
+		// Identity fields
+		FirstName:    entry.GetAttributeValue(a.cfg.FirstNameAttr),
+		LastName:     entry.GetAttributeValue(a.cfg.LastNameAttr),
+		Email:        email,
+		PasswordHash: hashedPassword,
+
+		// Account state
+		IsActive:        true,
+		IsEmailVerified: true, // owned by the directory
+		IsPhoneVerified: false,
+		IsBanned:        false,
+
+		// Authorization
+		Role: role,
+	}
+	err = a.tx.WithinTx(ctx, func(ctx context.Context) error {
+		registered := model.NewUserEvent(model.EventUserRegistered, 0, map[string]any{"email": newUser.Email, "source": "ldap"})
+		if err := a.users.CreateUser(ctx, newUser, registered); err != nil {
+			return err
+		}
+		return a.identities.CreateIdentity(ctx, &model.Identity{UserID: newUser.ID, Provider: provider, Subject: subject})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newUser, nil
+}
+
+// roleFor maps group DNs onto a role. Admin wins over any other mapped
+// role; users in no mapped group get model.RoleUser.
+func (a *LDAPAuthenticator) roleFor(groups []string) model.Role {
+	role := model.RoleUser
+	for _, dn := range groups {
+		mapped, ok := a.cfg.GroupRoles[normalizeDN(dn)]
+		if !ok {
+			continue
+		}
+		if mapped == model.RoleAdmin {
+			return model.RoleAdmin
+		}
+		role = mapped
+	}
+	return role
+}
+
+func normalizeDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(dn))
+	}
+	parts := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		attrs := make([]string, 0, len(rdn.Attributes))
+		for _, attr := range rdn.Attributes {
+			attrs = append(attrs, strings.ToLower(attr.Type)+"="+strings.ToLower(attr.Value))
+		}
+		parts = append(parts, strings.Join(attrs, "+"))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+const (
+	testServiceDN       = "cn=svc,dc=example,dc=com"
+	testServicePassword = "svc-secret"
+	testAdminsGroup     = "cn=admins,ou=groups,dc=example,dc=com"
+)
+
+type directoryEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// directory is an in-process LDAP server that answers simple binds and
+// searches for the entry whose mail appears in the filter. It speaks just
+// enough of RFC 4511 for the go-ldap client.
+type directory struct {
+	mu      sync.Mutex
+	entries []directoryEntry
+	silent  bool // accept connections but never answer
+	ln      net.Listener
+}
+
+func newDirectory(t *testing.T, entries ...directoryEntry) *directory {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &directory{entries: entries, ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go d.serve()
+	return d
+}
+
+func (d *directory) url() string { return "ldap://" + d.ln.Addr().String() }
+
+func (d *directory) setGroups(dn string, groups ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.entries {
+		if d.entries[i].dn == dn {
+			d.entries[i].attrs["memberOf"] = groups
+		}
+	}
+}
+
+func (d *directory) serve() {
+	for {
+		conn, err := d.ln.Accept()
+		if err != nil {
+			return
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *directory) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		req, err := ber.ReadPacket(conn)
+		if err != nil || len(req.Children) < 2 {
+			return
+		}
+		if d.silent {
+			continue
+		}
+		id := req.Children[0].Value.(int64)
+		op := req.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			code := ldap.LDAPResultInvalidCredentials
+			if d.bind(op.Children[1].Value.(string), op.Children[2].Data.String()) {
+				code = ldap.LDAPResultSuccess
+			}
+			conn.Write(response(id, ldap.ApplicationBindResponse, code).Bytes())
+		case ldap.ApplicationSearchRequest:
+			for _, entry := range d.search(filterValues(op.Children[6])) {
+				conn.Write(searchEntry(id, entry).Bytes())
+			}
+			conn.Write(response(id, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess).Bytes())
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (d *directory) bind(dn, password string) bool {
+	if dn == testServiceDN {
+		return password == testServicePassword
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, e := range d.entries {
+		if e.dn == dn {
+			return password != "" && password == e.password
+		}
+	}
+	return false
+}
+
+func (d *directory) search(values []string) []directoryEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var found []directoryEntry
+	for _, e := range d.entries {
+		for _, v := range values {
+			if len(e.attrs["mail"]) > 0 && strings.EqualFold(e.attrs["mail"][0], v) {
+				found = append(found, e)
+				break
+			}
+		}
+	}
+	return found
+}
+
+func filterValues(p *ber.Packet) []string {
+	var values []string
+	if s, ok := p.Value.(string); ok {
+		values = append(values, s)
+	}
+	for _, child := range p.Children {
+		values = append(values, filterValues(child)...)
+	}
+	return values
+}
+
+func envelope(id int64) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	return p
+}
+
+func response(id int64, tag ber.Tag, code int) *ber.Packet {
+	p := envelope(id)
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	p.AppendChild(op)
+	return p
+}
+
+func searchEntry(id int64, entry directoryEntry) *ber.Packet {
+	p := envelope(id)
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "Object Name"))
+	attrs := ber.NewSequence("Attributes")
+	for name, values := range entry.attrs {
+		attr := ber.NewSequence("Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	p.AppendChild(op)
+	return p
+}
+
+const adaDN = "uid=ada,ou=people,dc=example,dc=com"
+
+func adaEntry(groups ...string) directoryEntry {
+	return directoryEntry{
+		dn:       adaDN,
+		password: "correct horse",
+		attrs: map[string][]string{
+			"mail":      {"ada@example.com"},
+			"givenName": {"Ada"},
+			"sn":        {"Lovelace"},
+			"memberOf":  groups,
+		},
+	}
+}
+
+func newTestLDAPAuthenticator(d *directory, users *memory.UserRepo, timeout time.Duration) *LDAPAuthenticator {
+	return NewLDAPAuthenticator(LDAPConfig{
+		URL:           d.url(),
+		BindDN:        testServiceDN,
+		BindPassword:  testServicePassword,
+		BaseDN:        "dc=example,dc=com",
+		UserFilter:    "(&(objectClass=person)(mail=%s))",
+		EmailAttr:     "mail",
+		FirstNameAttr: "givenName",
+		LastNameAttr:  "sn",
+		GroupAttr:     "memberOf",
+		GroupRoles:    map[string]model.Role{"CN=Admins,OU=Groups,DC=example,DC=com": model.RoleAdmin},
+		Timeout:       timeout,
+	}, memory.TxManager{}, users, memory.NewIdentityRepo())
+}
+
+func TestLDAPProvisionsAndSyncsRoles(t *testing.T) {
+	ctx := context.Background()
+	d := newDirectory(t, adaEntry(testAdminsGroup))
+	users := memory.NewUserRepo(nil)
+	a := newTestLDAPAuthenticator(d, users, time.Second)
+
+	u, err := a.Authenticate(ctx, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if u.Role != model.RoleAdmin || u.FirstName != "Ada" || !u.IsEmailVerified {
+		t.Fatalf("unexpected provisioned user: %+v", u)
+	}
+
+	// Leaving the group demotes the user on the next login.
+	d.setGroups(adaDN)
+	u, err = a.Authenticate(ctx, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	stored, err := users.FindUserById(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Role != model.RoleUser || stored.Role != model.RoleUser {
+		t.Fatalf("role not synced from directory: returned %q, stored %q", u.Role, stored.Role)
+	}
+}
+
+func TestLDAPKeepsRoleOfLocalAccount(t *testing.T) {
+	ctx := context.Background()
+	d := newDirectory(t, adaEntry(testAdminsGroup))
+	users := memory.NewUserRepo(nil)
+	local := &model.User{Email: "ada@example.com", Role: model.RoleUser, IsActive: true}
+	if err := users.CreateUser(ctx, local); err != nil {
+		t.Fatal(err)
+	}
+	a := newTestLDAPAuthenticator(d, users, time.Second)
+
+	u, err := a.Authenticate(ctx, "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	stored, err := users.FindUserById(ctx, local.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != local.ID || u.Role != model.RoleUser || stored.Role != model.RoleUser {
+		t.Fatalf("directory changed the role of a local account: returned %+v, stored %q", u, stored.Role)
+	}
+}
+
+func TestLDAPRejectsWrongPassword(t *testing.T) {
+	ctx := context.Background()
+	d := newDirectory(t, adaEntry())
+	users := memory.NewUserRepo(nil)
+	a := newTestLDAPAuthenticator(d, users, time.Second)
+
+	for _, password := range []string{"wrong", ""} {
+		if _, err := a.Authenticate(ctx, "ada@example.com", password); !errors.Is(err, errUnauthorized) {
+			t.Fatalf("password %q: expected errUnauthorized, got %v", password, err)
+		}
+	}
+	if _, err := a.Authenticate(ctx, "nobody@example.com", "correct horse"); !errors.Is(err, errUnauthorized) {
+		t.Fatalf("unknown user: expected errUnauthorized, got %v", err)
+	}
+	if _, err := users.FindUserByEmail(ctx, "ada@example.com"); err == nil {
+		t.Fatal("failed login provisioned a user")
+	}
+}
+
+func TestLDAPTimesOutOnUnresponsiveServer(t *testing.T) {
+	d := newDirectory(t, adaEntry())
+	d.silent = true
+	a := newTestLDAPAuthenticator(d, memory.NewUserRepo(nil), time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := a.Authenticate(ctx, "ada@example.com", "correct horse"); !errors.Is(err, errUnauthorized) {
+		t.Fatalf("expected errUnauthorized, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("authentication ignored the context deadline, took %s", elapsed)
+	}
+}
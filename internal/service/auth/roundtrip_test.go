@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/platform/cache"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/service/audit"
+	"github.com/razedwell/go-hand/internal/service/user"
+)
+
+// TestSessionRoundTrip runs register, login, refresh and logout against the
+// memory repositories, as in --storage=memory.
+func TestSessionRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	userRepo := memory.NewUserRepo(nil)
+	tokenRepo := memory.NewTokenRepo()
+	tokens := security.NewJWTManager("access-secret-for-tests-only-32b", "refresh-secret-for-tests-only-32", time.Minute, time.Hour, tokenRepo, cache.NewMemoryStore(), security.FailClosed, userRepo, nil)
+	accounts := user.NewService(memory.TxManager{}, userRepo, tokenRepo, nil, false, audit.Discard)
+	svc := NewService(userRepo, tokens, NewPasswordAuthenticator(userRepo), nil, audit.Discard, memory.NewOutbox())
+
+	err := accounts.RegisterUser(ctx, user.RegParams{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	if _, _, err := svc.Login(ctx, "ada@example.com", "wrong password"); err == nil {
+		t.Fatal("login with the wrong password succeeded")
+	}
+	accessToken, refreshToken, err := svc.Login(ctx, "ada@example.com", "correct horse battery")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	claims, err := tokens.Authenticate(ctx, accessToken)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	registered, err := userRepo.FindUserByEmail(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != registered.ID {
+		t.Fatalf("token is for user %d, want %d", claims.UserID, registered.ID)
+	}
+
+	refreshed, err := svc.RefreshAccessToken(ctx, refreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, err := tokens.Authenticate(ctx, refreshed); err != nil {
+		t.Fatalf("authenticate refreshed token: %v", err)
+	}
+
+	if err := svc.Logout(ctx, refreshed, refreshToken); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if _, err := svc.RefreshAccessToken(ctx, refreshToken); err == nil {
+		t.Fatal("refresh token still works after logout")
+	}
+	if _, err := tokens.Authenticate(ctx, refreshed); err == nil {
+		t.Fatal("access token still works after logout")
+	}
+}
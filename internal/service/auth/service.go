@@ -3,9 +3,15 @@ package auth
 import (
 	"context"
 	"errors"
+	"strconv"
 
+	"github.com/razedwell/go-hand/internal/event"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/platform/metrics"
 	"github.com/razedwell/go-hand/internal/repository/user"
 	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/service/audit"
 )
 
 type LoginParams struct {
@@ -13,29 +19,182 @@ type LoginParams struct {
 	Password string `json:"password"`
 }
 
+type SecondFactorParams struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
 var errUnauthorized = errors.New("unauthorized")
 
+// SecondFactor challenges a user after a successful password check.
+// phone.Service implements it with SMS codes.
+type SecondFactor interface {
+	// Challenge returns false if the user isn't enrolled.
+	Challenge(ctx context.Context, u *model.User) (string, bool, error)
+	VerifyChallenge(ctx context.Context, challenge string, code string) (int64, error)
+}
+
+// SecondFactorRequiredError is returned by Login and IssueTokens when the
+// user must complete a second factor before tokens are issued.
+type SecondFactorRequiredError struct {
+	Challenge string
+	Method    string
+}
+
+func (e *SecondFactorRequiredError) Error() string {
+	return "second factor required"
+}
+
 type Service struct {
-	users user.Repository
-	jwt   *security.JWTManager
+	users        user.Repository
+	tokens       security.TokenManager
+	authn        Authenticator
+	secondFactor SecondFactor // optional
+	audit        audit.Recorder
+	events       event.Publisher
 }
 
-func NewService(users user.Repository, jwt *security.JWTManager) *Service {
-	return &Service{users, jwt}
+func NewService(users user.Repository, tokens security.TokenManager, authn Authenticator, secondFactor SecondFactor, audit audit.Recorder, events event.Publisher) *Service {
+	return &Service{users, tokens, authn, secondFactor, audit, events}
 }
 
 func (s *Service) Login(ctx context.Context, email string, password string) (string, string, error) {
-	user, err := s.users.FindUserByEmail(ctx, email)
-	if err != nil || security.VerifyPassword(user.PasswordHash, password) == false {
+	user, err := s.authn.Authenticate(ctx, email, password)
+	if err != nil {
+		s.loginFailed(ctx, "password", email, nil, "invalid_credentials")
 		return "", "", errUnauthorized
 	}
-	return s.jwt.GenerateTokenPair(user.ID, string(user.Role))
+	return s.IssueTokens(ctx, user, "password")
+}
+
+// IssueTokens logs in a user who has proven who they are by method, e.g. a
+// password, magic link or SAML assertion. Every login goes through here, so
+// account state and the second factor are checked the same way for all of
+// them.
+func (s *Service) IssueTokens(ctx context.Context, user *model.User, method string) (string, string, error) {
+	if !s.allowed(ctx, user, method) {
+		return "", "", errUnauthorized
+	}
+
+	if s.secondFactor != nil {
+		challenge, required, err := s.secondFactor.Challenge(ctx, user)
+		if err != nil {
+			return "", "", err
+		}
+		if required {
+			return "", "", &SecondFactorRequiredError{Challenge: challenge, Method: "sms"}
+		}
+	}
+
+	return s.issueTokens(ctx, user, method)
+}
+
+// allowed rejects banned and deactivated accounts.
+func (s *Service) allowed(ctx context.Context, user *model.User, method string) bool {
+	if user.IsBanned {
+		s.loginFailed(ctx, method, user.Email, user, "banned")
+		return false
+	}
+	if !user.IsActive {
+		s.loginFailed(ctx, method, user.Email, user, "inactive")
+		return false
+	}
+	return true
+}
+
+func (s *Service) issueTokens(ctx context.Context, user *model.User, method string) (string, string, error) {
+	accessToken, refreshToken, err := s.tokens.GenerateTokenPair(user.ID, string(user.Role))
+	if err != nil {
+		return "", "", err
+	}
+	metrics.LoginSucceeded(method)
+	s.audit.Record(ctx, &model.AuditEvent{
+		ActorID:    &user.ID,
+		Action:     model.AuditLogin,
+		TargetType: model.AuditTargetUser,
+		TargetID:   strconv.FormatInt(user.ID, 10),
+		Metadata:   map[string]any{"method": method},
+	})
+	return accessToken, refreshToken, nil
+}
+
+// loginFailed records a rejected login. user is nil if the credentials
+// didn't match any account.
+func (s *Service) loginFailed(ctx context.Context, method string, email string, user *model.User, reason string) {
+	metrics.LoginFailed(method, reason)
+	entry := &model.AuditEvent{
+		Action:   model.AuditLoginFailed,
+		Metadata: map[string]any{"email": email, "method": method, "reason": reason},
+	}
+	if user != nil {
+		entry.TargetType = model.AuditTargetUser
+		entry.TargetID = strconv.FormatInt(user.ID, 10)
+	}
+	s.audit.Record(ctx, entry)
+}
+
+// CompleteSecondFactor finishes a login interrupted by SecondFactorRequiredError.
+func (s *Service) CompleteSecondFactor(ctx context.Context, params SecondFactorParams) (string, string, error) {
+	if s.secondFactor == nil {
+		return "", "", errUnauthorized
+	}
+	userID, err := s.secondFactor.VerifyChallenge(ctx, params.Challenge, params.Code)
+	if err != nil {
+		s.loginFailed(ctx, "sms", "", nil, "invalid_second_factor")
+		return "", "", errUnauthorized
+	}
+	user, err := s.users.FindUserById(ctx, userID)
+	if err != nil {
+		return "", "", errUnauthorized
+	}
+	// The account may have been banned or deactivated since the challenge.
+	if !s.allowed(ctx, user, "sms") {
+		return "", "", errUnauthorized
+	}
+	return s.issueTokens(ctx, user, "sms")
 }
 
 func (s *Service) Logout(ctx context.Context, accessToken string, refreshToken string) error {
-	return s.jwt.BlacklistTokens(ctx, accessToken, refreshToken)
+	// Resolve the user first; a session can't be resolved once revoked.
+	entry := &model.AuditEvent{Action: model.AuditLogout}
+	if claims, verr := s.tokens.Authenticate(ctx, accessToken); verr == nil {
+		entry.ActorID = &claims.UserID
+		entry.TargetType = model.AuditTargetUser
+		entry.TargetID = strconv.FormatInt(claims.UserID, 10)
+	}
+
+	err := s.tokens.RevokeTokens(ctx, accessToken, refreshToken)
+	if err != nil {
+		entry.Metadata = map[string]any{"error": err.Error()}
+	}
+	s.audit.Record(ctx, entry)
+	if err == nil && entry.ActorID != nil {
+		revoked := model.NewUserEvent(model.EventSessionRevoked, *entry.ActorID, map[string]any{"reason": "logout"})
+		if perr := s.events.Publish(ctx, revoked); perr != nil {
+			logger.Log.ErrorContext(ctx, "Failed to publish logout event", "error", perr)
+		}
+	}
+
+	return err
 }
 
 func (s *Service) RefreshAccessToken(ctx context.Context, refreshTokenStr string) (string, error) {
-	return s.jwt.RefreshAccessToken(ctx, refreshTokenStr)
+	accessToken, err := s.tokens.RefreshAccessToken(ctx, refreshTokenStr)
+	if err != nil {
+		s.audit.Record(ctx, &model.AuditEvent{
+			Action:   model.AuditRefreshFailed,
+			Metadata: map[string]any{"reason": err.Error()},
+		})
+		return "", err
+	}
+
+	entry := &model.AuditEvent{Action: model.AuditTokenRefreshed}
+	if claims, err := s.tokens.Authenticate(ctx, accessToken); err == nil {
+		entry.ActorID = &claims.UserID
+		entry.TargetType = model.AuditTargetUser
+		entry.TargetID = strconv.FormatInt(claims.UserID, 10)
+	}
+	s.audit.Record(ctx, entry)
+
+	return accessToken, nil
 }
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/service/audit"
+)
+
+// stubSecondFactor challenges every user and accepts the code "123456".
+type stubSecondFactor struct {
+	userID int64
+}
+
+func (f *stubSecondFactor) Challenge(ctx context.Context, u *model.User) (string, bool, error) {
+	f.userID = u.ID
+	return "challenge", true, nil
+}
+
+func (f *stubSecondFactor) VerifyChallenge(ctx context.Context, challenge string, code string) (int64, error) {
+	if challenge != "challenge" || code != "123456" {
+		return 0, errors.New("invalid code")
+	}
+	return f.userID, nil
+}
+
+// countingTokens counts issued token pairs.
+type countingTokens struct {
+	security.TokenManager
+	issued int
+}
+
+func (t *countingTokens) GenerateTokenPair(userID int64, role string) (string, string, error) {
+	t.issued++
+	return "access", "refresh", nil
+}
+
+func TestIssueTokensRequiresSecondFactor(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepo(nil)
+	u := &model.User{Email: "ada@example.com", Role: model.RoleUser, IsActive: true}
+	if err := users.CreateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	tokens := &countingTokens{}
+	svc := NewService(users, tokens, NewPasswordAuthenticator(users), &stubSecondFactor{}, audit.Discard, memory.NewOutbox())
+
+	// Magic link and SAML logins go through IssueTokens too.
+	for _, method := range []string{"magic_link", "saml"} {
+		_, _, err := svc.IssueTokens(ctx, u, method)
+		var secondFactorErr *SecondFactorRequiredError
+		if !errors.As(err, &secondFactorErr) {
+			t.Fatalf("%s: expected SecondFactorRequiredError, got %v", method, err)
+		}
+	}
+	if tokens.issued != 0 {
+		t.Fatalf("tokens issued before the second factor: %d", tokens.issued)
+	}
+
+	if _, _, err := svc.CompleteSecondFactor(ctx, SecondFactorParams{Challenge: "challenge", Code: "123456"}); err != nil {
+		t.Fatalf("complete second factor: %v", err)
+	}
+	if tokens.issued != 1 {
+		t.Fatalf("expected one token pair, got %d", tokens.issued)
+	}
+}
+
+func TestCompleteSecondFactorRejectsBannedUser(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepo(nil)
+	u := &model.User{Email: "ada@example.com", Role: model.RoleUser, IsActive: true}
+	if err := users.CreateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	tokens := &countingTokens{}
+	svc := NewService(users, tokens, NewPasswordAuthenticator(users), &stubSecondFactor{}, audit.Discard, memory.NewOutbox())
+
+	if _, _, err := svc.IssueTokens(ctx, u, "password"); err == nil {
+		t.Fatal("expected a second factor challenge")
+	}
+
+	// Banned between the challenge and the code.
+	u.IsBanned = true
+	if err := users.UpdateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.CompleteSecondFactor(ctx, SecondFactorParams{Challenge: "challenge", Code: "123456"}); !errors.Is(err, errUnauthorized) {
+		t.Fatalf("expected errUnauthorized, got %v", err)
+	}
+	if tokens.issued != 0 {
+		t.Fatalf("tokens issued to a banned user: %d", tokens.issued)
+	}
+}
@@ -0,0 +1,233 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/mail"
+	"github.com/razedwell/go-hand/internal/repository/invitation"
+	"github.com/razedwell/go-hand/internal/repository/organization"
+	"github.com/razedwell/go-hand/internal/repository/transaction"
+	"github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/tenant"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type CreateParams struct {
+	Email string        `json:"email"`
+	Role  model.OrgRole `json:"role"`
+}
+
+type AcceptParams struct {
+	Token string `json:"token"`
+}
+
+type Config struct {
+	AcceptURL string        // frontend page that receives ?token=
+	Expiry    time.Duration // lifetime of an invitation link
+}
+
+var (
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrForbidden         = errors.New("forbidden")
+	ErrNotFound          = errors.New("invitation not found")
+	ErrInvalidInvitation = errors.New("invalid or expired invitation")
+	ErrEmailMismatch     = errors.New("invitation was sent to a different email address")
+)
+
+type Service struct {
+	tx          transaction.Manager
+	invitations invitation.Repository
+	orgs        organization.Repository
+	users       user.Repository
+	mailer      mail.Sender
+	cfg         Config
+}
+
+func NewService(tx transaction.Manager, invitations invitation.Repository, orgs organization.Repository, users user.Repository, mailer mail.Sender, cfg Config) *Service {
+	return &Service{tx, invitations, orgs, users, mailer, cfg}
+}
+
+// Create invites email into the active organization and mails the link.
+// Any earlier pending invitation for the same address is revoked.
+func (s *Service) Create(ctx context.Context, params CreateParams) (*model.Invitation, error) {
+	t, err := s.requireManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(params.Email))
+	if params.Role == "" {
+		params.Role = model.OrgRoleMember
+	}
+	if !strings.Contains(email, "@") || !params.Role.Valid() {
+		return nil, ErrInvalidInput
+	}
+	if params.Role == model.OrgRoleOwner && t.Role != model.OrgRoleOwner {
+		return nil, ErrForbidden
+	}
+
+	if err := s.invitations.RevokePendingInvitations(ctx, email); err != nil {
+		return nil, err
+	}
+
+	token, err := security.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	inviter := t.UserID
+	inv := &model.Invitation{
+		Email:     email,
+		Role:      params.Role,
+		TokenHash: security.HashToken(token),
+		InvitedBy: &inviter,
+		ExpiresAt: helpers.GetCurrentTimeStampUTC().Add(s.cfg.Expiry),
+	}
+	if err := s.invitations.CreateInvitation(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	if err := s.send(ctx, inv, token); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// List returns the active organization's invitations, newest first.
+func (s *Service) List(ctx context.Context) ([]*model.Invitation, error) {
+	if _, err := s.requireManager(ctx); err != nil {
+		return nil, err
+	}
+	return s.invitations.ListInvitations(ctx)
+}
+
+// Resend issues a fresh link, invalidating the previous one, and extends
+// the expiry.
+func (s *Service) Resend(ctx context.Context, id int64) error {
+	if _, err := s.requireManager(ctx); err != nil {
+		return err
+	}
+
+	inv, err := s.invitations.FindInvitationById(ctx, id)
+	if err != nil {
+		return mapNotFound(err)
+	}
+
+	token, err := security.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	inv.TokenHash = security.HashToken(token)
+	inv.ExpiresAt = helpers.GetCurrentTimeStampUTC().Add(s.cfg.Expiry)
+	if err := s.invitations.RenewInvitation(ctx, inv.ID, inv.TokenHash, inv.ExpiresAt); err != nil {
+		return mapNotFound(err)
+	}
+	return s.send(ctx, inv, token)
+}
+
+func (s *Service) Revoke(ctx context.Context, id int64) error {
+	if _, err := s.requireManager(ctx); err != nil {
+		return err
+	}
+	return mapNotFound(s.invitations.RevokeInvitation(ctx, id))
+}
+
+// Accept attaches an existing, logged-in account to the inviting organization.
+func (s *Service) Accept(ctx context.Context, userID int64, token string) (*model.Invitation, error) {
+	inv, err := s.Validate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.users.FindUserById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(u.Email, inv.Email) {
+		return nil, ErrEmailMismatch
+	}
+	if err := s.Redeem(ctx, inv, userID); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Validate returns the pending invitation for token.
+func (s *Service) Validate(ctx context.Context, token string) (*model.Invitation, error) {
+	if token == "" {
+		return nil, ErrInvalidInvitation
+	}
+	inv, err := s.invitations.FindInvitationByTokenHash(ctx, security.HashToken(token))
+	if err != nil {
+		if errors.Is(err, invitation.ErrNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+	if !inv.Pending(helpers.GetCurrentTimeStampUTC()) {
+		return nil, ErrInvalidInvitation
+	}
+	return inv, nil
+}
+
+// Redeem marks the invitation accepted by userID and adds the membership.
+func (s *Service) Redeem(ctx context.Context, inv *model.Invitation, userID int64) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.invitations.MarkInvitationAccepted(ctx, inv.ID, userID); err != nil {
+			if errors.Is(err, invitation.ErrNotFound) {
+				return ErrInvalidInvitation
+			}
+			return err
+		}
+		// The invitation names the organization, so scope to it explicitly.
+		orgCtx := tenant.WithTenant(ctx, tenant.Tenant{OrgID: inv.OrgID, UserID: userID, Role: inv.Role})
+		return s.orgs.AddMember(orgCtx, userID, inv.Role)
+	})
+}
+
+func (s *Service) send(ctx context.Context, inv *model.Invitation, token string) error {
+	org, err := s.orgs.FindOrganizationById(ctx, inv.OrgID)
+	if err != nil {
+		return err
+	}
+
+	link, err := url.Parse(s.cfg.AcceptURL)
+	if err != nil {
+		return fmt.Errorf("invalid invitation url: %w", err)
+	}
+	q := link.Query()
+	q.Set("token", token)
+	link.RawQuery = q.Encode()
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("You have been invited to %s", org.Name),
+		Body: fmt.Sprintf(
+			"You have been invited to join %s as %s.\n\nAccept the invitation:\n\n%s\n\nThe link expires on %s.\n",
+			org.Name, inv.Role, link.String(), inv.ExpiresAt.Format(time.RFC1123),
+		),
+	})
+}
+
+func (s *Service) requireManager(ctx context.Context) (tenant.Tenant, error) {
+	t, err := tenant.FromContext(ctx)
+	if err != nil {
+		return tenant.Tenant{}, err
+	}
+	if !t.Role.CanManage() {
+		return tenant.Tenant{}, ErrForbidden
+	}
+	return t, nil
+}
+
+func mapNotFound(err error) error {
+	if errors.Is(err, invitation.ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,201 @@
+package organization
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/organization"
+	"github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/tenant"
+)
+
+type CreateParams struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type SwitchParams struct {
+	OrgID int64 `json:"org_id"`
+}
+
+type UpdateMemberParams struct {
+	Role model.OrgRole `json:"role"`
+}
+
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotMember    = errors.New("not a member of this organization")
+	ErrLastOwner    = errors.New("organization must keep at least one owner")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+type Service struct {
+	orgs   organization.Repository
+	users  user.Repository
+	tokens security.TokenManager
+}
+
+func NewService(orgs organization.Repository, users user.Repository, tokens security.TokenManager) *Service {
+	return &Service{orgs, users, tokens}
+}
+
+// Create makes a new organization owned by userID.
+func (s *Service) Create(ctx context.Context, userID int64, params CreateParams) (*model.Organization, error) {
+	name := strings.TrimSpace(params.Name)
+	slug := strings.ToLower(strings.TrimSpace(params.Slug))
+	if name == "" || !slugPattern.MatchString(slug) {
+		return nil, ErrInvalidInput
+	}
+
+	org := &model.Organization{Name: name, Slug: slug}
+	if err := s.orgs.CreateOrganization(ctx, org, userID); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *Service) ListForUser(ctx context.Context, userID int64) ([]*model.Membership, error) {
+	return s.orgs.ListUserMemberships(ctx, userID)
+}
+
+// Switch re-issues the token pair with orgID as the active organization.
+// The credentials used before the switch are revoked. A refresh token that
+// belongs to someone else is refused rather than revoked.
+func (s *Service) Switch(ctx context.Context, userID int64, orgID int64, oldAccessToken string, oldRefreshToken string) (string, string, error) {
+	if oldRefreshToken != "" {
+		owner, err := s.tokens.RefreshTokenOwner(ctx, oldRefreshToken)
+		switch {
+		case errors.Is(err, security.ErrRefreshUnsupported):
+		case err != nil:
+			// Already revoked, expired or invalid: nothing left to revoke.
+			oldRefreshToken = ""
+		case owner != userID:
+			return "", "", ErrForbidden
+		}
+	}
+
+	m, err := s.orgs.FindMembership(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, organization.ErrMembershipNotFound) {
+			return "", "", ErrNotMember
+		}
+		return "", "", err
+	}
+	u, err := s.users.FindUserById(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, refreshToken, err := s.tokens.GenerateOrgTokenPair(u.ID, string(u.Role), m.OrgID, string(m.Role))
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.tokens.RevokeTokens(ctx, oldAccessToken, oldRefreshToken); err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// ListMembers lists the members of the active organization.
+func (s *Service) ListMembers(ctx context.Context) ([]*model.Membership, error) {
+	return s.orgs.ListMembers(ctx)
+}
+
+func (s *Service) UpdateMemberRole(ctx context.Context, userID int64, role model.OrgRole) error {
+	t, err := s.requireManager(ctx)
+	if err != nil {
+		return err
+	}
+	if !role.Valid() {
+		return ErrInvalidInput
+	}
+	// Only owners can hand out or take away ownership.
+	if t.Role != model.OrgRoleOwner {
+		target, err := s.orgs.FindMembership(ctx, t.OrgID, userID)
+		if err != nil {
+			return mapMembershipErr(err)
+		}
+		if role == model.OrgRoleOwner || target.Role == model.OrgRoleOwner {
+			return ErrForbidden
+		}
+	}
+	if role != model.OrgRoleOwner {
+		if err := s.ensureOtherOwner(ctx, userID); err != nil {
+			return err
+		}
+	}
+	changed := model.NewUserEvent(model.EventOrgMemberRoleChanged, userID, map[string]any{"org_id": t.OrgID, "role": role})
+	return mapMembershipErr(s.orgs.UpdateMemberRole(ctx, userID, role, changed))
+}
+
+func (s *Service) RemoveMember(ctx context.Context, userID int64) error {
+	t, err := tenant.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	// Members may leave on their own; removing others needs a manager.
+	if userID != t.UserID {
+		if !t.Role.CanManage() {
+			return ErrForbidden
+		}
+		if t.Role != model.OrgRoleOwner {
+			target, err := s.orgs.FindMembership(ctx, t.OrgID, userID)
+			if err != nil {
+				return mapMembershipErr(err)
+			}
+			if target.Role == model.OrgRoleOwner {
+				return ErrForbidden
+			}
+		}
+	}
+	if err := s.ensureOtherOwner(ctx, userID); err != nil {
+		return err
+	}
+	removed := model.NewUserEvent(model.EventOrgMemberRemoved, userID, map[string]any{"org_id": t.OrgID})
+	return mapMembershipErr(s.orgs.RemoveMember(ctx, userID, removed))
+}
+
+// ensureOtherOwner fails if userID is the only owner of the active organization.
+func (s *Service) ensureOtherOwner(ctx context.Context, userID int64) error {
+	members, err := s.orgs.ListMembers(ctx)
+	if err != nil {
+		return err
+	}
+	isOwner, owners := false, 0
+	for _, m := range members {
+		if m.Role == model.OrgRoleOwner {
+			owners++
+			if m.UserID == userID {
+				isOwner = true
+			}
+		}
+	}
+	if isOwner && owners == 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func (s *Service) requireManager(ctx context.Context) (tenant.Tenant, error) {
+	t, err := tenant.FromContext(ctx)
+	if err != nil {
+		return tenant.Tenant{}, err
+	}
+	if !t.Role.CanManage() {
+		return tenant.Tenant{}, ErrForbidden
+	}
+	return t, nil
+}
+
+func mapMembershipErr(err error) error {
+	if errors.Is(err, organization.ErrMembershipNotFound) {
+		return ErrNotMember
+	}
+	return err
+}
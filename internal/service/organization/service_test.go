@@ -0,0 +1,113 @@
+package organization
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/organization"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/tenant"
+)
+
+// memberships is an organization.Repository where everyone is a member of
+// every organization.
+type memberships struct{ organization.Repository }
+
+func (memberships) FindMembership(ctx context.Context, orgID int64, userID int64) (*model.Membership, error) {
+	return &model.Membership{OrgID: orgID, UserID: userID, Role: model.OrgRoleMember}, nil
+}
+
+// ownedTokens maps refresh tokens to their owners and records revocations.
+type ownedTokens struct {
+	security.TokenManager
+	owners  map[string]int64
+	revoked []string
+}
+
+func (t *ownedTokens) RefreshTokenOwner(ctx context.Context, refreshTokenStr string) (int64, error) {
+	owner, ok := t.owners[refreshTokenStr]
+	if !ok {
+		return 0, errors.New("refresh token not found")
+	}
+	return owner, nil
+}
+
+func (t *ownedTokens) GenerateOrgTokenPair(userID int64, role string, orgID int64, orgRole string) (string, string, error) {
+	return "access", "refresh", nil
+}
+
+func (t *ownedTokens) RevokeTokens(ctx context.Context, accessTokenStr string, refreshTokenStr string) error {
+	t.revoked = append(t.revoked, refreshTokenStr)
+	return nil
+}
+
+func TestSwitchRefusesForeignRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepo(nil)
+	u := &model.User{Email: "ada@example.com", Role: model.RoleUser, IsActive: true}
+	if err := users.CreateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	tokens := &ownedTokens{owners: map[string]int64{"mine": u.ID, "theirs": u.ID + 1}}
+	svc := NewService(memberships{}, users, tokens)
+
+	if _, _, err := svc.Switch(ctx, u.ID, 1, "access", "theirs"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+	if len(tokens.revoked) != 0 {
+		t.Fatalf("revoked a refresh token of another user: %v", tokens.revoked)
+	}
+
+	if _, _, err := svc.Switch(ctx, u.ID, 1, "access", "mine"); err != nil {
+		t.Fatalf("switch: %v", err)
+	}
+	if len(tokens.revoked) != 1 || tokens.revoked[0] != "mine" {
+		t.Fatalf("expected the old refresh token to be revoked, got %v", tokens.revoked)
+	}
+}
+
+// recordedMembers records the events passed with membership changes.
+type recordedMembers struct {
+	memberships
+	events []*model.Event
+}
+
+func (m *recordedMembers) ListMembers(ctx context.Context) ([]*model.Membership, error) {
+	return []*model.Membership{{OrgID: 1, UserID: 1, Role: model.OrgRoleOwner}, {OrgID: 1, UserID: 2, Role: model.OrgRoleAdmin}}, nil
+}
+
+func (m *recordedMembers) UpdateMemberRole(ctx context.Context, userID int64, role model.OrgRole, events ...*model.Event) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func (m *recordedMembers) RemoveMember(ctx context.Context, userID int64, events ...*model.Event) error {
+	m.events = append(m.events, events...)
+	return nil
+}
+
+func TestMembershipChangesPublishEvents(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), tenant.Tenant{OrgID: 1, UserID: 1, Role: model.OrgRoleOwner})
+	orgs := &recordedMembers{}
+	svc := NewService(orgs, memory.NewUserRepo(nil), &ownedTokens{})
+
+	if err := svc.UpdateMemberRole(ctx, 2, model.OrgRoleMember); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.RemoveMember(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{model.EventOrgMemberRoleChanged, model.EventOrgMemberRemoved}
+	if len(orgs.events) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(orgs.events))
+	}
+	for i, e := range orgs.events {
+		if e.Type != want[i] || e.SubjectID != "2" || e.Data["org_id"] != int64(1) {
+			t.Errorf("event %d: got %+v", i, e)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package passwordless
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/platform/mail"
+	"github.com/razedwell/go-hand/internal/platform/metrics"
+	"github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/repository/verification"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type StartParams struct {
+	Email string `json:"email"`
+}
+
+// VerifyParams carries either the token from the link or the email and
+// the 6-digit code.
+type VerifyParams struct {
+	Token string `json:"token"`
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// RateLimiter is satisfied by cache.RedisClient.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+}
+
+type Config struct {
+	LinkURL     string        // frontend page that receives ?token=
+	Expiry      time.Duration // lifetime of links and codes
+	MaxAttempts int           // wrong codes before the code is burned
+	RateLimit   int           // links per email and per client per window
+	RateWindow  time.Duration
+}
+
+var (
+	ErrRateLimited = errors.New("too many login links requested, try again later")
+	errInvalidCode = errors.New("invalid or expired code")
+)
+
+// TokenIssuer logs in a user once the code has been redeemed, running any
+// second factor first. auth.Service implements it.
+type TokenIssuer interface {
+	IssueTokens(ctx context.Context, u *model.User, method string) (string, string, error)
+}
+
+type Service struct {
+	users   user.Repository
+	codes   verification.Repository
+	issuer  TokenIssuer
+	mailer  mail.Sender
+	limiter RateLimiter
+	cfg     Config
+}
+
+func NewService(users user.Repository, codes verification.Repository, issuer TokenIssuer, mailer mail.Sender, limiter RateLimiter, cfg Config) *Service {
+	return &Service{users, codes, issuer, mailer, limiter, cfg}
+}
+
+// Start emails a login link and code to the user. It returns a binding
+// secret the caller must keep in the requesting browser; verification only
+// succeeds when the same secret is presented, so forwarded links are useless.
+// Unknown addresses get a binding too, so responses don't reveal accounts.
+// Requests are limited per address and per client, whether or not the
+// account exists.
+func (s *Service) Start(ctx context.Context, email string, client string) (string, error) {
+	email = strings.TrimSpace(email)
+	if err := s.allow(ctx, "magic:rate:email:"+strings.ToLower(email)); err != nil {
+		return "", err
+	}
+	if err := s.allow(ctx, "magic:rate:client:"+client); err != nil {
+		return "", err
+	}
+
+	binding, err := security.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := s.users.FindUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return binding, nil
+		}
+		return "", err
+	}
+	if !u.IsActive || u.IsBanned {
+		return binding, nil
+	}
+
+	// Only the most recent link/code pair is valid.
+	if err := s.codes.InvalidateUserCodes(ctx, u.ID, model.MagicLink, model.LoginCode); err != nil {
+		return "", err
+	}
+
+	linkToken, err := security.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	code, err := security.RandomDigits(6)
+	if err != nil {
+		return "", err
+	}
+
+	bindingHash := security.HashToken(binding)
+	expiresAt := helpers.GetCurrentTimeStampUTC().Add(s.cfg.Expiry)
+	for _, vc := range []*model.VerificationCode{
+		{UserID: u.ID, CodeHash: security.HashToken(linkToken), Type: model.MagicLink, ExpiresAt: expiresAt, BindingHash: &bindingHash},
+		{UserID: u.ID, CodeHash: security.HashToken(code), Type: model.LoginCode, ExpiresAt: expiresAt, BindingHash: &bindingHash},
+	} {
+		if err := s.codes.CreateVerificationCode(ctx, vc); err != nil {
+			return "", err
+		}
+	}
+
+	link, err := url.Parse(s.cfg.LinkURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid magic link url: %w", err)
+	}
+	q := link.Query()
+	q.Set("token", linkToken)
+	link.RawQuery = q.Encode()
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Click to sign in:\n\n%s\n\nOr enter this code: %s\n\nThe link and code expire in %d minutes and only work in the browser where you requested them.\n",
+			link.String(), code, int(s.cfg.Expiry.Minutes()),
+		),
+	})
+	if err != nil {
+		return "", err
+	}
+	return binding, nil
+}
+
+func (s *Service) allow(ctx context.Context, key string) error {
+	allowed, err := s.limiter.Allow(ctx, key, s.cfg.RateLimit, s.cfg.RateWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check magic link rate limit: %w", err)
+	}
+	if !allowed {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// Verify redeems a link token or code and issues a token pair. It returns
+// an auth.SecondFactorRequiredError if the user is enrolled in one.
+func (s *Service) Verify(ctx context.Context, params VerifyParams, binding string) (string, string, error) {
+	u, err := s.verify(ctx, params, binding)
+	switch {
+	case err == nil:
+		return s.issuer.IssueTokens(ctx, u, "magic_link")
+	case errors.Is(err, errInvalidCode):
+		metrics.LoginFailed("magic_link", "invalid_code")
+	default:
+		metrics.LoginFailed("magic_link", "error")
+	}
+	return "", "", err
+}
+
+func (s *Service) verify(ctx context.Context, params VerifyParams, binding string) (*model.User, error) {
+	if binding == "" {
+		return nil, errInvalidCode
+	}
+
+	vc, err := s.findCode(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if helpers.GetCurrentTimeStampUTC().After(vc.ExpiresAt) {
+		return nil, errInvalidCode
+	}
+	if vc.BindingHash == nil || subtle.ConstantTimeCompare([]byte(*vc.BindingHash), []byte(security.HashToken(binding))) != 1 {
+		return nil, errInvalidCode
+	}
+
+	if err := s.codes.MarkVerificationCodeUsed(ctx, vc.ID); err != nil {
+		if errors.Is(err, verification.ErrNotFound) {
+			return nil, errInvalidCode
+		}
+		return nil, err
+	}
+	if err := s.codes.InvalidateUserCodes(ctx, vc.UserID, model.MagicLink, model.LoginCode); err != nil {
+		return nil, err
+	}
+
+	u, err := s.users.FindUserById(ctx, vc.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsActive || u.IsBanned {
+		return nil, errInvalidCode
+	}
+
+	// Redeeming a code sent to the mailbox proves ownership of the address.
+	if !u.IsEmailVerified {
+		u.IsEmailVerified = true
+		if err := s.users.UpdateUser(ctx, u); err != nil {
+			logger.Log.ErrorContext(ctx, "Failed to mark email verified", "user_id", u.ID, "error", err)
+		}
+	}
+
+	return u, nil
+}
+
+func (s *Service) findCode(ctx context.Context, params VerifyParams) (*model.VerificationCode, error) {
+	if params.Token != "" {
+		vc, err := s.codes.GetVerificationCode(ctx, security.HashToken(params.Token), model.MagicLink)
+		if err != nil {
+			if errors.Is(err, verification.ErrNotFound) {
+				return nil, errInvalidCode
+			}
+			return nil, err
+		}
+		return vc, nil
+	}
+
+	if params.Email == "" || params.Code == "" {
+		return nil, errInvalidCode
+	}
+	u, err := s.users.FindUserByEmail(ctx, strings.TrimSpace(params.Email))
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, errInvalidCode
+		}
+		return nil, err
+	}
+	vc, err := s.codes.GetLatestVerificationCode(ctx, u.ID, model.LoginCode)
+	if err != nil {
+		if errors.Is(err, verification.ErrNotFound) {
+			return nil, errInvalidCode
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(vc.CodeHash), []byte(security.HashToken(params.Code))) != 1 {
+		// Six digits are guessable, so each code only gets a few tries.
+		attempts, err := s.codes.IncrementAttempts(ctx, vc.ID)
+		if err != nil {
+			return nil, err
+		}
+		if attempts >= s.cfg.MaxAttempts {
+			if err := s.codes.MarkVerificationCodeUsed(ctx, vc.ID); err != nil && !errors.Is(err, verification.ErrNotFound) {
+				return nil, err
+			}
+		}
+		return nil, errInvalidCode
+	}
+	return vc, nil
+}
@@ -0,0 +1,258 @@
+package passwordless
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/mail"
+	"github.com/razedwell/go-hand/internal/repository/verification"
+)
+
+// codeStore is an in-memory verification.Repository.
+type codeStore struct {
+	verification.Repository
+	codes []*model.VerificationCode
+}
+
+func (s *codeStore) CreateVerificationCode(ctx context.Context, code *model.VerificationCode) error {
+	code.ID = int64(len(s.codes) + 1)
+	s.codes = append(s.codes, code)
+	return nil
+}
+
+func (s *codeStore) GetVerificationCode(ctx context.Context, codeHash string, codeType model.VerificationType) (*model.VerificationCode, error) {
+	for _, c := range s.codes {
+		if c.CodeHash == codeHash && c.Type == codeType && c.UsedAt == nil {
+			return c, nil
+		}
+	}
+	return nil, verification.ErrNotFound
+}
+
+func (s *codeStore) GetLatestVerificationCode(ctx context.Context, userID int64, codeType model.VerificationType) (*model.VerificationCode, error) {
+	for i := len(s.codes) - 1; i >= 0; i-- {
+		if c := s.codes[i]; c.UserID == userID && c.Type == codeType && c.UsedAt == nil {
+			return c, nil
+		}
+	}
+	return nil, verification.ErrNotFound
+}
+
+func (s *codeStore) IncrementAttempts(ctx context.Context, id int64) (int, error) {
+	s.codes[id-1].Attempts++
+	return s.codes[id-1].Attempts, nil
+}
+
+func (s *codeStore) MarkVerificationCodeUsed(ctx context.Context, id int64) error {
+	c := s.codes[id-1]
+	if c.UsedAt != nil {
+		return verification.ErrNotFound
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	return nil
+}
+
+func (s *codeStore) InvalidateUserCodes(ctx context.Context, userID int64, codeTypes ...model.VerificationType) error {
+	now := time.Now()
+	for _, c := range s.codes {
+		if c.UserID == userID && c.UsedAt == nil {
+			c.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// mailbox keeps the messages it is asked to send.
+type mailbox struct {
+	sent []mail.Message
+}
+
+func (m *mailbox) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+type issuer struct{}
+
+func (issuer) IssueTokens(ctx context.Context, u *model.User, method string) (string, string, error) {
+	return "access", "refresh", nil
+}
+
+// counter is a fixed-window RateLimiter that never expires.
+type counter struct {
+	hits map[string]int
+}
+
+func (c *counter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	c.hits[key]++
+	return c.hits[key] <= limit, nil
+}
+
+type fixture struct {
+	svc    *Service
+	codes  *codeStore
+	mailer *mailbox
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	users := memory.NewUserRepo(nil)
+	if err := users.CreateUser(context.Background(), &model.User{Email: "ada@example.com", Role: model.RoleUser, IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+	f := &fixture{codes: &codeStore{}, mailer: &mailbox{}}
+	f.svc = NewService(users, f.codes, issuer{}, f.mailer, &counter{hits: map[string]int{}}, Config{
+		LinkURL:     "https://app.example.com/magic",
+		Expiry:      15 * time.Minute,
+		MaxAttempts: 3,
+		RateLimit:   2,
+		RateWindow:  time.Hour,
+	})
+	return f
+}
+
+var (
+	mailToken = regexp.MustCompile(`token=(\S+)`)
+	mailCode  = regexp.MustCompile(`code: (\d{6})`)
+)
+
+// start requests a link and returns the binding, link token and code.
+func (f *fixture) start(t *testing.T) (string, string, string) {
+	t.Helper()
+	binding, err := f.svc.Start(context.Background(), "ada@example.com", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := f.mailer.sent[len(f.mailer.sent)-1].Body
+	token, err := url.QueryUnescape(mailToken.FindStringSubmatch(body)[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return binding, token, mailCode.FindStringSubmatch(body)[1]
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("link", func(t *testing.T) {
+		f := newFixture(t)
+		binding, token, _ := f.start(t)
+		if _, _, err := f.svc.Verify(ctx, VerifyParams{Token: token}, binding); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("code", func(t *testing.T) {
+		f := newFixture(t)
+		binding, _, code := f.start(t)
+		if _, _, err := f.svc.Verify(ctx, VerifyParams{Email: "ada@example.com", Code: code}, binding); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("binding mismatch", func(t *testing.T) {
+		f := newFixture(t)
+		_, token, _ := f.start(t)
+		for _, binding := range []string{"", "someone-elses-binding"} {
+			if _, _, err := f.svc.Verify(ctx, VerifyParams{Token: token}, binding); !errors.Is(err, errInvalidCode) {
+				t.Fatalf("binding %q: expected errInvalidCode, got %v", binding, err)
+			}
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		f := newFixture(t)
+		binding, token, _ := f.start(t)
+		for _, c := range f.codes.codes {
+			c.ExpiresAt = time.Now().Add(-time.Second)
+		}
+		if _, _, err := f.svc.Verify(ctx, VerifyParams{Token: token}, binding); !errors.Is(err, errInvalidCode) {
+			t.Fatalf("expected errInvalidCode, got %v", err)
+		}
+	})
+
+	t.Run("single use", func(t *testing.T) {
+		f := newFixture(t)
+		binding, token, code := f.start(t)
+		if _, _, err := f.svc.Verify(ctx, VerifyParams{Token: token}, binding); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := f.svc.Verify(ctx, VerifyParams{Token: token}, binding); !errors.Is(err, errInvalidCode) {
+			t.Fatalf("link reused: %v", err)
+		}
+		// Redeeming the link burns the code sent with it.
+		if _, _, err := f.svc.Verify(ctx, VerifyParams{Email: "ada@example.com", Code: code}, binding); !errors.Is(err, errInvalidCode) {
+			t.Fatalf("code reused: %v", err)
+		}
+	})
+
+	t.Run("newer link replaces older", func(t *testing.T) {
+		f := newFixture(t)
+		_, token, _ := f.start(t)
+		binding, _, _ := f.start(t)
+		if _, _, err := f.svc.Verify(ctx, VerifyParams{Token: token}, binding); !errors.Is(err, errInvalidCode) {
+			t.Fatalf("expected errInvalidCode, got %v", err)
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		f := newFixture(t)
+		binding, _, code := f.start(t)
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+		for i := 0; i < 3; i++ {
+			if _, _, err := f.svc.Verify(ctx, VerifyParams{Email: "ada@example.com", Code: wrong}, binding); !errors.Is(err, errInvalidCode) {
+				t.Fatalf("attempt %d: expected errInvalidCode, got %v", i+1, err)
+			}
+		}
+		if _, _, err := f.svc.Verify(ctx, VerifyParams{Email: "ada@example.com", Code: code}, binding); !errors.Is(err, errInvalidCode) {
+			t.Fatalf("burned code accepted: %v", err)
+		}
+	})
+}
+
+func TestStartRateLimited(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("per email", func(t *testing.T) {
+		f := newFixture(t)
+		for i, client := range []string{"192.0.2.1", "192.0.2.2"} {
+			if _, err := f.svc.Start(ctx, "ada@example.com", client); err != nil {
+				t.Fatalf("request %d: %v", i+1, err)
+			}
+		}
+		// Case and whitespace don't make a new address.
+		if _, err := f.svc.Start(ctx, " ADA@example.com", "192.0.2.3"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected ErrRateLimited, got %v", err)
+		}
+		if len(f.mailer.sent) != 2 {
+			t.Fatalf("expected 2 emails, got %d", len(f.mailer.sent))
+		}
+	})
+
+	t.Run("per client", func(t *testing.T) {
+		f := newFixture(t)
+		// Unknown addresses count too, so the limit can't be used to probe
+		// for accounts.
+		for i, email := range []string{"ada@example.com", "nobody@example.com"} {
+			if _, err := f.svc.Start(ctx, email, "192.0.2.1"); err != nil {
+				t.Fatalf("request %d: %v", i+1, err)
+			}
+		}
+		if _, err := f.svc.Start(ctx, "grace@example.com", "192.0.2.1"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("expected ErrRateLimited, got %v", err)
+		}
+		if _, err := f.svc.Start(ctx, "grace@example.com", "192.0.2.2"); err != nil {
+			t.Fatalf("other client: %v", err)
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package phone
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/sms"
+	"github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/repository/verification"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type StartParams struct {
+	Phone string `json:"phone"`
+}
+
+type ConfirmParams struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+// RateLimiter is satisfied by cache.RedisClient.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+}
+
+type Config struct {
+	DefaultCountryCode string // used for numbers entered without +<country>
+	CodeExpiry         time.Duration
+	MaxAttempts        int // wrong codes before the code is burned
+	RateLimit          int // messages per number per window
+	RateWindow         time.Duration
+}
+
+var (
+	ErrRateLimited = errors.New("too many messages sent to this number, try again later")
+	ErrInvalidCode = errors.New("invalid or expired code")
+)
+
+type Service struct {
+	users   user.Repository
+	codes   verification.Repository
+	sender  sms.Sender
+	limiter RateLimiter
+	cfg     Config
+}
+
+func NewService(users user.Repository, codes verification.Repository, sender sms.Sender, limiter RateLimiter, cfg Config) *Service {
+	return &Service{users, codes, sender, limiter, cfg}
+}
+
+// StartVerification texts a code to the number. The number is only stored
+// on the account once ConfirmVerification succeeds.
+func (s *Service) StartVerification(ctx context.Context, userID int64, rawPhone string) error {
+	phone, err := sms.NormalizeE164(rawPhone, s.cfg.DefaultCountryCode)
+	if err != nil {
+		return err
+	}
+
+	if err := s.codes.InvalidateUserCodes(ctx, userID, model.VerifyPhone); err != nil {
+		return err
+	}
+
+	code, err := security.RandomDigits(6)
+	if err != nil {
+		return err
+	}
+	phoneHash := security.HashToken(phone)
+	err = s.codes.CreateVerificationCode(ctx, &model.VerificationCode{
+		UserID:      userID,
+		CodeHash:    security.HashToken(code),
+		Type:        model.VerifyPhone,
+		ExpiresAt:   helpers.GetCurrentTimeStampUTC().Add(s.cfg.CodeExpiry),
+		BindingHash: &phoneHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, phone, fmt.Sprintf("Your verification code is %s", code))
+}
+
+// ConfirmVerification checks the code and stores the number as verified.
+func (s *Service) ConfirmVerification(ctx context.Context, userID int64, params ConfirmParams) error {
+	phone, err := sms.NormalizeE164(params.Phone, s.cfg.DefaultCountryCode)
+	if err != nil {
+		return err
+	}
+
+	vc, err := s.codes.GetLatestVerificationCode(ctx, userID, model.VerifyPhone)
+	if err != nil {
+		if errors.Is(err, verification.ErrNotFound) {
+			return ErrInvalidCode
+		}
+		return err
+	}
+	if vc.BindingHash == nil || *vc.BindingHash != security.HashToken(phone) {
+		return ErrInvalidCode
+	}
+	if err := s.redeem(ctx, vc, params.Code); err != nil {
+		return err
+	}
+
+	u, err := s.users.FindUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	u.Phone = &phone
+	u.IsPhoneVerified = true
+	return s.users.UpdateUser(ctx, u)
+}
+
+// Challenge texts a login code to the user's verified number and returns
+// an opaque challenge ID for the second step. It reports false when the
+// user has no verified number and so can't be challenged.
+func (s *Service) Challenge(ctx context.Context, u *model.User) (string, bool, error) {
+	if !u.IsPhoneVerified || u.Phone == nil || *u.Phone == "" {
+		return "", false, nil
+	}
+
+	if err := s.codes.InvalidateUserCodes(ctx, u.ID, model.LoginSMS); err != nil {
+		return "", false, err
+	}
+
+	challenge, err := security.RandomToken(32)
+	if err != nil {
+		return "", false, err
+	}
+	code, err := security.RandomDigits(6)
+	if err != nil {
+		return "", false, err
+	}
+	challengeHash := security.HashToken(challenge)
+	err = s.codes.CreateVerificationCode(ctx, &model.VerificationCode{
+		UserID:      u.ID,
+		CodeHash:    security.HashToken(code),
+		Type:        model.LoginSMS,
+		ExpiresAt:   helpers.GetCurrentTimeStampUTC().Add(s.cfg.CodeExpiry),
+		BindingHash: &challengeHash,
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	if err := s.send(ctx, *u.Phone, fmt.Sprintf("Your login code is %s", code)); err != nil {
+		return "", false, err
+	}
+	return challenge, true, nil
+}
+
+// VerifyChallenge redeems a login code and returns the user it belongs to.
+func (s *Service) VerifyChallenge(ctx context.Context, challenge string, code string) (int64, error) {
+	vc, err := s.codes.GetVerificationCodeByBinding(ctx, security.HashToken(challenge), model.LoginSMS)
+	if err != nil {
+		if errors.Is(err, verification.ErrNotFound) {
+			return 0, ErrInvalidCode
+		}
+		return 0, err
+	}
+	if err := s.redeem(ctx, vc, code); err != nil {
+		return 0, err
+	}
+	return vc.UserID, nil
+}
+
+// redeem checks expiry and the code, burning the code after too many
+// wrong guesses, and marks it used on success.
+func (s *Service) redeem(ctx context.Context, vc *model.VerificationCode, code string) error {
+	if helpers.GetCurrentTimeStampUTC().After(vc.ExpiresAt) {
+		return ErrInvalidCode
+	}
+
+	if subtle.ConstantTimeCompare([]byte(vc.CodeHash), []byte(security.HashToken(code))) != 1 {
+		attempts, err := s.codes.IncrementAttempts(ctx, vc.ID)
+		if err != nil {
+			return err
+		}
+		if attempts >= s.cfg.MaxAttempts {
+			if err := s.codes.MarkVerificationCodeUsed(ctx, vc.ID); err != nil && !errors.Is(err, verification.ErrNotFound) {
+				return err
+			}
+		}
+		return ErrInvalidCode
+	}
+
+	if err := s.codes.MarkVerificationCodeUsed(ctx, vc.ID); err != nil {
+		if errors.Is(err, verification.ErrNotFound) {
+			return ErrInvalidCode
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) send(ctx context.Context, phone string, body string) error {
+	allowed, err := s.limiter.Allow(ctx, "sms:rate:"+phone, s.cfg.RateLimit, s.cfg.RateWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check sms rate limit: %w", err)
+	}
+	if !allowed {
+		return ErrRateLimited
+	}
+	return s.sender.Send(ctx, phone, body)
+}
@@ -0,0 +1,159 @@
+package scim
+
+// Discovery documents (RFC 7644 section 4). They describe exactly what this
+// server supports, so IdPs don't attempt bulk, sort or etag requests.
+
+type attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []attribute `json:"subAttributes,omitempty"`
+}
+
+func attr(name, typ string, required bool, mutability string) attribute {
+	return attribute{
+		Name:       name,
+		Type:       typ,
+		Required:   required,
+		Mutability: mutability,
+		Returned:   "default",
+		Uniqueness: "none",
+	}
+}
+
+func multi(name string, subs ...attribute) attribute {
+	a := attr(name, "complex", false, "readWrite")
+	a.MultiValued = true
+	a.SubAttributes = subs
+	return a
+}
+
+func (s *Service) ServiceProviderConfig() map[string]any {
+	return map[string]any{
+		"schemas":          []string{schemaServiceProviderConfig},
+		"documentationUri": "https://datatracker.ietf.org/doc/html/rfc7644",
+		"patch":            map[string]bool{"supported": true},
+		"bulk":             map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           map[string]any{"supported": true, "maxResults": maxPageSize},
+		"changePassword":   map[string]bool{"supported": true},
+		"sort":             map[string]bool{"supported": false},
+		"etag":             map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with a dedicated SCIM bearer token",
+			"primary":     true,
+		}},
+		"meta": map[string]string{
+			"resourceType": "ServiceProviderConfig",
+			"location":     s.baseURL + "/ServiceProviderConfig",
+		},
+	}
+}
+
+func (s *Service) ResourceTypes() []map[string]any {
+	return []map[string]any{s.resourceType("User"), s.resourceType("Group")}
+}
+
+// ResourceType returns the resource type with the given name, or nil.
+func (s *Service) ResourceType(name string) map[string]any {
+	if name != "User" && name != "Group" {
+		return nil
+	}
+	return s.resourceType(name)
+}
+
+func (s *Service) resourceType(name string) map[string]any {
+	schema := SchemaUser
+	if name == "Group" {
+		schema = SchemaGroup
+	}
+	return map[string]any{
+		"schemas":     []string{schemaResourceType},
+		"id":          name,
+		"name":        name,
+		"endpoint":    "/" + name + "s",
+		"description": name,
+		"schema":      schema,
+		"meta": map[string]string{
+			"resourceType": "ResourceType",
+			"location":     s.baseURL + "/ResourceTypes/" + name,
+		},
+	}
+}
+
+func (s *Service) Schemas() []map[string]any {
+	return []map[string]any{s.userSchema(), s.groupSchema()}
+}
+
+// Schema returns the schema with the given URN, or nil.
+func (s *Service) Schema(id string) map[string]any {
+	switch id {
+	case SchemaUser:
+		return s.userSchema()
+	case SchemaGroup:
+		return s.groupSchema()
+	}
+	return nil
+}
+
+func (s *Service) userSchema() map[string]any {
+	userName := attr("userName", "string", true, "readWrite")
+	userName.Uniqueness = "server"
+	password := attr("password", "string", false, "writeOnly")
+	password.Returned = "never"
+
+	name := attr("name", "complex", false, "readWrite")
+	name.SubAttributes = []attribute{
+		attr("formatted", "string", false, "readOnly"),
+		attr("givenName", "string", false, "readWrite"),
+		attr("familyName", "string", false, "readWrite"),
+	}
+
+	value := attr("value", "string", false, "readWrite")
+	typ := attr("type", "string", false, "readWrite")
+	primary := attr("primary", "boolean", false, "readWrite")
+
+	return s.schema(SchemaUser, "User", []attribute{
+		userName,
+		name,
+		attr("displayName", "string", false, "readOnly"),
+		multi("emails", value, typ, primary),
+		multi("phoneNumbers", value, typ, primary),
+		attr("active", "boolean", false, "readWrite"),
+		password,
+	})
+}
+
+func (s *Service) groupSchema() map[string]any {
+	displayName := attr("displayName", "string", true, "readWrite")
+	displayName.Uniqueness = "server"
+
+	return s.schema(SchemaGroup, "Group", []attribute{
+		displayName,
+		attr("externalId", "string", false, "readWrite"),
+		multi("members",
+			attr("value", "string", false, "immutable"),
+			attr("display", "string", false, "readOnly"),
+			attr("$ref", "reference", false, "immutable"),
+		),
+	})
+}
+
+func (s *Service) schema(id, name string, attributes []attribute) map[string]any {
+	return map[string]any{
+		"schemas":    []string{schemaSchema},
+		"id":         id,
+		"name":       name,
+		"attributes": attributes,
+		"meta": map[string]string{
+			"resourceType": "Schema",
+			"location":     s.baseURL + "/Schemas/" + id,
+		},
+	}
+}
@@ -0,0 +1,161 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+// Attribute paths accepted in filters, lower-cased, mapped onto repository fields.
+var (
+	userFilterAttrs = map[string]string{
+		"id":                 "id",
+		"username":           "email",
+		"emails":             "email",
+		"emails.value":       "email",
+		"name.givenname":     "first_name",
+		"name.familyname":    "last_name",
+		"phonenumbers":       "phone",
+		"phonenumbers.value": "phone",
+		"active":             "is_active",
+		"meta.created":       "created_at",
+		"meta.lastmodified":  "updated_at",
+	}
+	groupFilterAttrs = map[string]string{
+		"id":                "id",
+		"displayname":       "display_name",
+		"externalid":        "external_id",
+		"members":           "member_id",
+		"members.value":     "member_id",
+		"meta.created":      "created_at",
+		"meta.lastmodified": "updated_at",
+	}
+	// numericFields only accept integer comparison values.
+	numericFields = map[string]bool{"id": true, "member_id": true}
+)
+
+var filterOps = map[string]query.Op{
+	"eq": query.OpEq,
+	"ne": query.OpNe,
+	"co": query.OpContains,
+	"sw": query.OpStartsWith,
+	"ew": query.OpEndsWith,
+	"pr": query.OpPresent,
+	"gt": query.OpGt,
+	"ge": query.OpGe,
+	"lt": query.OpLt,
+	"le": query.OpLe,
+}
+
+// parseFilter parses the subset of RFC 7644 filters we support: attribute
+// expressions joined by "and". "or", "not" and grouping are rejected.
+func parseFilter(filter string, attrs map[string]string) ([]query.Condition, error) {
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []query.Condition
+	for i := 0; i < len(tokens); {
+		if len(conditions) > 0 {
+			if !strings.EqualFold(tokens[i], "and") {
+				return nil, fmt.Errorf("%w: unsupported operator %q", ErrInvalidFilter, tokens[i])
+			}
+			i++
+		}
+		if i+1 >= len(tokens) {
+			return nil, fmt.Errorf("%w: incomplete expression", ErrInvalidFilter)
+		}
+
+		field, ok := attrs[strings.ToLower(tokens[i])]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported attribute %q", ErrInvalidFilter, tokens[i])
+		}
+		op, ok := filterOps[strings.ToLower(tokens[i+1])]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported operator %q", ErrInvalidFilter, tokens[i+1])
+		}
+
+		cond := query.Condition{Field: field, Op: op}
+		i += 2
+		if op != query.OpPresent {
+			if i >= len(tokens) {
+				return nil, fmt.Errorf("%w: missing value", ErrInvalidFilter)
+			}
+			cond.Value, err = filterValue(tokens[i])
+			if err != nil {
+				return nil, err
+			}
+			if numericFields[field] {
+				if _, err := strconv.ParseInt(cond.Value, 10, 64); err != nil {
+					return nil, fmt.Errorf("%w: %s requires a numeric value", ErrInvalidFilter, tokens[i-2])
+				}
+			}
+			i++
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions, nil
+}
+
+func filterValue(token string) (string, error) {
+	if strings.HasPrefix(token, `"`) {
+		v, err := strconv.Unquote(token)
+		if err != nil {
+			return "", fmt.Errorf("%w: bad string %s", ErrInvalidFilter, token)
+		}
+		return v, nil
+	}
+	switch strings.ToLower(token) {
+	case "true", "false":
+		return strings.ToLower(token), nil
+	case "null":
+		return "", fmt.Errorf("%w: null comparisons are not supported", ErrInvalidFilter)
+	}
+	if _, err := strconv.ParseFloat(token, 64); err == nil {
+		return token, nil
+	}
+	return "", fmt.Errorf("%w: bad value %s", ErrInvalidFilter, token)
+}
+
+// tokenizeFilter splits on whitespace, keeping quoted strings intact.
+func tokenizeFilter(filter string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inQuotes, escaped := false, false
+
+	for _, r := range filter {
+		switch {
+		case inQuotes:
+			cur.WriteRune(r)
+			if escaped {
+				escaped = false
+			} else if r == '\\' {
+				escaped = true
+			} else if r == '"' {
+				inQuotes = false
+			}
+		case r == '"':
+			inQuotes = true
+			cur.WriteRune(r)
+		case r == '(' || r == ')' || r == '[' || r == ']':
+			return nil, fmt.Errorf("%w: grouping is not supported", ErrInvalidFilter)
+		case r == ' ' || r == '\t':
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("%w: unterminated string", ErrInvalidFilter)
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
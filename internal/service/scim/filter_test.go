@@ -0,0 +1,103 @@
+package scim
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []query.Condition
+	}{
+		{``, nil},
+		{`userName eq "ada@example.com"`, []query.Condition{{Field: "email", Op: query.OpEq, Value: "ada@example.com"}}},
+		{`USERNAME EQ "ada@example.com"`, []query.Condition{{Field: "email", Op: query.OpEq, Value: "ada@example.com"}}},
+		{`emails.value co "example"`, []query.Condition{{Field: "email", Op: query.OpContains, Value: "example"}}},
+		{`phoneNumbers pr`, []query.Condition{{Field: "phone", Op: query.OpPresent}}},
+		{`active eq true`, []query.Condition{{Field: "is_active", Op: query.OpEq, Value: "true"}}},
+		{`id gt 10`, []query.Condition{{Field: "id", Op: query.OpGt, Value: "10"}}},
+		// "and" chains; pr takes no value, so the next token starts a new term.
+		{
+			`name.givenName sw "A" and phoneNumbers pr and active eq False`,
+			[]query.Condition{
+				{Field: "first_name", Op: query.OpStartsWith, Value: "A"},
+				{Field: "phone", Op: query.OpPresent},
+				{Field: "is_active", Op: query.OpEq, Value: "false"},
+			},
+		},
+		// Quoted values keep spaces, escaped quotes and the word "and".
+		{`name.familyName eq "van der Berg and co"`, []query.Condition{{Field: "last_name", Op: query.OpEq, Value: "van der Berg and co"}}},
+		{`name.familyName eq "O\"Brien"`, []query.Condition{{Field: "last_name", Op: query.OpEq, Value: `O"Brien`}}},
+		{`name.familyName eq "back\\slash"`, []query.Condition{{Field: "last_name", Op: query.OpEq, Value: `back\slash`}}},
+	}
+	for _, tt := range tests {
+		got, err := parseFilter(tt.filter, userFilterAttrs)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterRejects(t *testing.T) {
+	for _, filter := range []string{
+		`userName eq "a" or userName eq "b"`, // only "and" is supported
+		`not userName eq "a"`,
+		`(userName eq "a")`,
+		`emails[type eq "work"]`,
+		`password eq "secret"`, // not filterable
+		`meta.version eq "1"`,
+		`userName xx "a"`,
+		`userName eq`,
+		`userName`,
+		`userName eq "a" and`,
+		`userName eq "unterminated`,
+		`userName eq null`,
+		`userName eq bare`,
+		`id eq "abc"`,
+		`userName eq "a" userName eq "b"`,
+	} {
+		if _, err := parseFilter(filter, userFilterAttrs); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%s: expected ErrInvalidFilter, got %v", filter, err)
+		}
+	}
+
+	// Attributes are per resource type.
+	if _, err := parseFilter(`displayName eq "x"`, userFilterAttrs); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("group attribute accepted for users: %v", err)
+	}
+	if _, err := parseFilter(`members eq "x"`, groupFilterAttrs); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("non-numeric member id accepted: %v", err)
+	}
+}
+
+func TestPage(t *testing.T) {
+	tests := []struct {
+		startIndex, count int
+		wantOffset        int
+		wantLimit         int
+		wantStart         int
+	}{
+		{1, -1, 0, defaultPageSize, 1},
+		{0, 10, 0, 10, 1},
+		{-5, 10, 0, 10, 1},
+		{11, 10, 10, 10, 11},
+		{1, 0, 0, 0, 1},
+		{1, maxPageSize + 1, 0, maxPageSize, 1},
+		{1, 1 << 30, 0, maxPageSize, 1},
+	}
+	for _, tt := range tests {
+		q, start := page(tt.startIndex, tt.count)
+		if q.Offset != tt.wantOffset || q.Limit != tt.wantLimit || start != tt.wantStart {
+			t.Errorf("page(%d, %d) = offset %d, limit %d, start %d; want %d, %d, %d",
+				tt.startIndex, tt.count, q.Offset, q.Limit, start, tt.wantOffset, tt.wantLimit, tt.wantStart)
+		}
+	}
+}
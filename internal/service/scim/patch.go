@@ -0,0 +1,253 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// valueFilterPath matches paths like `members[value eq "42"]` or
+// `emails[type eq "work"].value`.
+var valueFilterPath = regexp.MustCompile(`^(\w+)\[(\w+) eq "([^"]*)"\](?:\.(\w+))?$`)
+
+// applyUserOp applies one PATCH operation to a user resource.
+func applyUserOp(u *User, op PatchOp) error {
+	kind := strings.ToLower(op.Op)
+	if kind != "add" && kind != "replace" && kind != "remove" {
+		return fmt.Errorf("%w: unsupported op %q", ErrInvalidValue, op.Op)
+	}
+
+	if op.Path == "" {
+		if kind == "remove" {
+			return fmt.Errorf("%w: remove requires a path", ErrInvalidPath)
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return fmt.Errorf("%w: value must be an object", ErrInvalidValue)
+		}
+		for path, value := range attrs {
+			if err := setUserAttr(u, kind, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setUserAttr(u, kind, op.Path, op.Value)
+}
+
+func setUserAttr(u *User, kind, path string, value json.RawMessage) error {
+	attr := strings.ToLower(path)
+	if m := valueFilterPath.FindStringSubmatch(path); m != nil {
+		// emails[type eq "work"].value: we only keep one address per attribute.
+		attr = strings.ToLower(m[1])
+	}
+	attr = strings.TrimSuffix(attr, ".value")
+
+	if strings.HasPrefix(attr, "urn:") {
+		if !strings.HasPrefix(attr, strings.ToLower(SchemaUser)+":") {
+			return nil // extension attributes are not stored
+		}
+		attr = strings.TrimPrefix(attr, strings.ToLower(SchemaUser)+":")
+	}
+
+	if u.Name == nil {
+		u.Name = &Name{}
+	}
+
+	switch attr {
+	case "active":
+		if kind == "remove" {
+			return fmt.Errorf("%w: active cannot be removed", ErrInvalidValue)
+		}
+		active, err := boolValue(value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+	case "username":
+		if kind == "remove" {
+			return fmt.Errorf("%w: userName is required", ErrInvalidValue)
+		}
+		return json.Unmarshal(value, &u.UserName)
+	case "displayname", "externalid":
+		// Derived from name / not stored.
+	case "name":
+		if kind == "remove" {
+			u.Name = &Name{}
+			return nil
+		}
+		var name Name
+		if err := json.Unmarshal(value, &name); err != nil {
+			return fmt.Errorf("%w: name must be an object", ErrInvalidValue)
+		}
+		if name.GivenName != "" {
+			u.Name.GivenName = name.GivenName
+		}
+		if name.FamilyName != "" {
+			u.Name.FamilyName = name.FamilyName
+		}
+	case "name.givenname":
+		return stringAttr(kind, value, &u.Name.GivenName)
+	case "name.familyname":
+		return stringAttr(kind, value, &u.Name.FamilyName)
+	case "name.formatted":
+		// Derived from givenName and familyName.
+	case "emails":
+		values, err := multiValues(kind, value)
+		if err != nil {
+			return err
+		}
+		u.Emails = values
+	case "phonenumbers":
+		values, err := multiValues(kind, value)
+		if err != nil {
+			return err
+		}
+		u.PhoneNumbers = values
+	case "password":
+		return stringAttr(kind, value, &u.Password)
+	default:
+		return fmt.Errorf("%w: unsupported attribute %q", ErrInvalidPath, path)
+	}
+	return nil
+}
+
+func stringAttr(kind string, value json.RawMessage, dst *string) error {
+	if kind == "remove" {
+		*dst = ""
+		return nil
+	}
+	if err := json.Unmarshal(value, dst); err != nil {
+		return fmt.Errorf("%w: expected a string", ErrInvalidValue)
+	}
+	return nil
+}
+
+// multiValues accepts an array of {value,...} objects or a bare string, which
+// is what IdPs send for paths ending in ".value".
+func multiValues(kind string, value json.RawMessage) ([]MultiValue, error) {
+	if kind == "remove" {
+		return nil, nil
+	}
+	var values []MultiValue
+	if err := json.Unmarshal(value, &values); err == nil {
+		return values, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return []MultiValue{{Value: s, Primary: true}}, nil
+	}
+	return nil, fmt.Errorf("%w: expected a multi-valued attribute", ErrInvalidValue)
+}
+
+// boolValue accepts JSON booleans and the "True"/"False" strings some IdPs send.
+func boolValue(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("%w: expected a boolean", ErrInvalidValue)
+}
+
+// groupPatch is the effect of one PATCH operation on a group.
+type groupPatch struct {
+	displayName   *string
+	externalID    *string
+	addMembers    []string
+	removeMembers []string
+	replace       bool // members are replaced by addMembers
+	removeAll     bool
+}
+
+func parseGroupOp(op PatchOp) (*groupPatch, error) {
+	kind := strings.ToLower(op.Op)
+	if kind != "add" && kind != "replace" && kind != "remove" {
+		return nil, fmt.Errorf("%w: unsupported op %q", ErrInvalidValue, op.Op)
+	}
+	p := &groupPatch{}
+
+	if op.Path == "" {
+		if kind == "remove" {
+			return nil, fmt.Errorf("%w: remove requires a path", ErrInvalidPath)
+		}
+		var attrs struct {
+			DisplayName *string  `json:"displayName"`
+			ExternalID  *string  `json:"externalId"`
+			Members     []Member `json:"members"`
+		}
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return nil, fmt.Errorf("%w: value must be an object", ErrInvalidValue)
+		}
+		p.displayName, p.externalID = attrs.DisplayName, attrs.ExternalID
+		if attrs.Members != nil {
+			p.addMembers = memberValues(attrs.Members)
+			p.replace = kind == "replace"
+		}
+		return p, nil
+	}
+
+	if m := valueFilterPath.FindStringSubmatch(op.Path); m != nil {
+		if !strings.EqualFold(m[1], "members") || !strings.EqualFold(m[2], "value") || kind != "remove" {
+			return nil, fmt.Errorf("%w: unsupported path %q", ErrInvalidPath, op.Path)
+		}
+		p.removeMembers = []string{m[3]}
+		return p, nil
+	}
+
+	switch strings.ToLower(op.Path) {
+	case "displayname":
+		if kind == "remove" {
+			return nil, fmt.Errorf("%w: displayName is required", ErrInvalidValue)
+		}
+		var name string
+		if err := json.Unmarshal(op.Value, &name); err != nil {
+			return nil, fmt.Errorf("%w: expected a string", ErrInvalidValue)
+		}
+		p.displayName = &name
+	case "externalid":
+		var id string
+		if kind != "remove" {
+			if err := json.Unmarshal(op.Value, &id); err != nil {
+				return nil, fmt.Errorf("%w: expected a string", ErrInvalidValue)
+			}
+		}
+		p.externalID = &id
+	case "members":
+		var members []Member
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &members); err != nil {
+				return nil, fmt.Errorf("%w: members must be an array", ErrInvalidValue)
+			}
+		}
+		switch kind {
+		case "add":
+			p.addMembers = memberValues(members)
+		case "replace":
+			p.addMembers, p.replace = memberValues(members), true
+		case "remove":
+			if len(members) == 0 {
+				p.removeAll = true
+			}
+			p.removeMembers = memberValues(members)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported attribute %q", ErrInvalidPath, op.Path)
+	}
+	return p, nil
+}
+
+func memberValues(members []Member) []string {
+	values := make([]string, len(members))
+	for i, m := range members {
+		values[i] = m.Value
+	}
+	return values
+}
@@ -0,0 +1,99 @@
+package scim
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseGroupOp(t *testing.T) {
+	name := "platform"
+	empty := ""
+	tests := []struct {
+		name string
+		op   PatchOp
+		want groupPatch
+	}{
+		{"value filter removal", patchOp("remove", `members[value eq "42"]`, nil), groupPatch{removeMembers: []string{"42"}}},
+		{"value filter is case-insensitive", patchOp("Remove", `Members[Value eq "42"]`, nil), groupPatch{removeMembers: []string{"42"}}},
+		{"remove all members", PatchOp{Op: "remove", Path: "members"}, groupPatch{removeAll: true, removeMembers: []string{}}},
+		{"remove listed members", patchOp("remove", "members", []Member{{Value: "1"}, {Value: "2"}}), groupPatch{removeMembers: []string{"1", "2"}}},
+		{"add members", patchOp("add", "members", []Member{{Value: "1"}}), groupPatch{addMembers: []string{"1"}}},
+		{"replace members", patchOp("replace", "members", []Member{{Value: "1"}}), groupPatch{addMembers: []string{"1"}, replace: true}},
+		{"rename", patchOp("replace", "displayName", name), groupPatch{displayName: &name}},
+		{"clear external id", PatchOp{Op: "remove", Path: "externalId"}, groupPatch{externalID: &empty}},
+		{
+			"no path",
+			patchOp("replace", "", map[string]any{"displayName": name, "members": []Member{{Value: "3"}}}),
+			groupPatch{displayName: &name, addMembers: []string{"3"}, replace: true},
+		},
+	}
+	for _, tt := range tests {
+		got, err := parseGroupOp(tt.op)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestParseGroupOpRejects(t *testing.T) {
+	tests := []struct {
+		op   PatchOp
+		want error
+	}{
+		{patchOp("move", "members", nil), ErrInvalidValue},
+		{PatchOp{Op: "remove"}, ErrInvalidPath},
+		{patchOp("add", `members[value eq "42"]`, nil), ErrInvalidPath},
+		{patchOp("remove", `members[display eq "Ada"]`, nil), ErrInvalidPath},
+		{patchOp("remove", `emails[value eq "42"]`, nil), ErrInvalidPath},
+		{PatchOp{Op: "remove", Path: "displayName"}, ErrInvalidValue},
+		{patchOp("replace", "displayName", 42), ErrInvalidValue},
+		{patchOp("add", "members", "42"), ErrInvalidValue},
+		{patchOp("add", "owner", "42"), ErrInvalidPath},
+	}
+	for _, tt := range tests {
+		if _, err := parseGroupOp(tt.op); !errors.Is(err, tt.want) {
+			t.Errorf("%s %s: expected %v, got %v", tt.op.Op, tt.op.Path, tt.want, err)
+		}
+	}
+}
+
+func TestApplyUserOp(t *testing.T) {
+	u := &User{UserName: "ada@example.com", Name: &Name{GivenName: "Ada", FamilyName: "Lovelace"}}
+	for _, op := range []PatchOp{
+		patchOp("replace", "active", "False"),
+		patchOp("replace", "name.familyName", "Byron"),
+		patchOp("replace", `emails[type eq "work"].value`, "ada@byron.example.com"),
+		patchOp("add", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "Maths"),
+		patchOp("replace", "", map[string]any{"userName": "ada@byron.example.com"}),
+	} {
+		if err := applyUserOp(u, op); err != nil {
+			t.Fatalf("%s %s: %v", op.Op, op.Path, err)
+		}
+	}
+	if u.Active == nil || *u.Active {
+		t.Error("active not cleared")
+	}
+	if u.Name.GivenName != "Ada" || u.Name.FamilyName != "Byron" {
+		t.Errorf("name = %+v", u.Name)
+	}
+	if len(u.Emails) != 1 || u.Emails[0].Value != "ada@byron.example.com" || u.UserName != "ada@byron.example.com" {
+		t.Errorf("emails = %+v, userName = %q", u.Emails, u.UserName)
+	}
+
+	for _, op := range []PatchOp{
+		{Op: "remove", Path: "active"},
+		{Op: "remove", Path: "userName"},
+		patchOp("replace", "active", "maybe"),
+		patchOp("replace", "nickName", "Ada"),
+		{Op: "remove"},
+	} {
+		if err := applyUserOp(u, op); !errors.Is(err, ErrInvalidValue) && !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%s %s: expected a rejection, got %v", op.Op, op.Path, err)
+		}
+	}
+}
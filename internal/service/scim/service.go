@@ -0,0 +1,551 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/group"
+	"github.com/razedwell/go-hand/internal/repository/query"
+	"github.com/razedwell/go-hand/internal/repository/token"
+	"github.com/razedwell/go-hand/internal/repository/transaction"
+	"github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 200
+)
+
+var (
+	ErrNotFound      = errors.New("resource not found")
+	ErrConflict      = errors.New("resource already exists")
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidValue  = errors.New("invalid value")
+	ErrInvalidPath   = errors.New("invalid path")
+)
+
+type Service struct {
+	tx      transaction.Manager
+	users   user.Repository
+	groups  group.Repository
+	tokens  token.Repository
+	baseURL string
+}
+
+// NewService creates the SCIM provisioning service. baseURL is the public
+// URL of the SCIM root (e.g. https://api.example.com/scim/v2) used in
+// resource locations.
+func NewService(tx transaction.Manager, users user.Repository, groups group.Repository, tokens token.Repository, baseURL string) *Service {
+	return &Service{
+		tx:      tx,
+		users:   users,
+		groups:  groups,
+		tokens:  tokens,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// page converts SCIM's 1-based startIndex/count into a repository filter.
+// A negative count means the client did not ask for a page size.
+func page(startIndex, count int) (query.Filter, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = defaultPageSize
+	}
+	if count > maxPageSize {
+		count = maxPageSize
+	}
+	return query.Filter{Offset: startIndex - 1, Limit: count}, startIndex
+}
+
+func (s *Service) ListUsers(ctx context.Context, filter string, startIndex, count int) (*ListResponse, error) {
+	q, startIndex := page(startIndex, count)
+	conditions, err := parseFilter(filter, userFilterAttrs)
+	if err != nil {
+		return nil, err
+	}
+	q.Conditions = conditions
+
+	// count=0 asks for totalResults only.
+	totalOnly := q.Limit == 0
+	if totalOnly {
+		q.Limit = 1
+	}
+	users, total, err := s.users.ListUsers(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if totalOnly {
+		users = nil
+	}
+
+	resources := make([]any, 0, len(users))
+	for _, u := range users {
+		resources = append(resources, s.toUser(u))
+	}
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, id string) (*User, error) {
+	u, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toUser(u), nil
+}
+
+func (s *Service) CreateUser(ctx context.Context, in *User) (*User, error) {
+	now := helpers.GetCurrentTimeStampUTC()
+	u := &model.User{
+		CreatedAt: now,
+		UpdatedAt: now,
+
+		// Account state
+		IsActive:        true,
+		IsEmailVerified: true, // asserted by the IdP
+		IsPhoneVerified: false,
+		IsBanned:        false,
+
+		// Authorization
+		Role: model.RoleUser,
+	}
+	if err := s.applyUser(ctx, u, in); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if in.Password != "" {
+		u.PasswordHash, err = security.HashPassword(ctx, in.Password)
+	} else {
+		u.PasswordHash, err = security.UnusablePasswordHash(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	registered := model.NewUserEvent(model.EventUserRegistered, 0, map[string]any{"email": u.Email, "source": "scim"})
+	if err := s.users.CreateUser(ctx, u, registered); err != nil {
+		return nil, err
+	}
+	return s.toUser(u), nil
+}
+
+func (s *Service) ReplaceUser(ctx context.Context, id string, in *User) (*User, error) {
+	u, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.Active == nil {
+		active := true
+		in.Active = &active
+	}
+	return s.saveUser(ctx, u, in)
+}
+
+func (s *Service) PatchUser(ctx context.Context, id string, req *PatchRequest) (*User, error) {
+	u, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := s.toUser(u)
+	for _, op := range req.Operations {
+		if err := applyUserOp(res, op); err != nil {
+			return nil, err
+		}
+	}
+	return s.saveUser(ctx, u, res)
+}
+
+func (s *Service) DeleteUser(ctx context.Context, id string) error {
+	userID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	deleted := model.NewUserEvent(model.EventUserDeleted, userID, map[string]any{"source": "scim"})
+	if err := s.users.DeleteUser(ctx, userID, deleted); err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// saveUser applies a resource onto an existing user. Deactivation revokes
+// every refresh token so the user cannot mint new access tokens.
+func (s *Service) saveUser(ctx context.Context, u *model.User, in *User) (*User, error) {
+	wasActive := u.IsActive
+	if err := s.applyUser(ctx, u, in); err != nil {
+		return nil, err
+	}
+	if in.Password != "" {
+		hashedPassword, err := security.HashPassword(ctx, in.Password)
+		if err != nil {
+			return nil, err
+		}
+		u.PasswordHash = hashedPassword
+	}
+
+	deactivated := wasActive && !u.IsActive
+	var events []*model.Event
+	if in.Password != "" {
+		events = append(events, model.NewUserEvent(model.EventPasswordChanged, u.ID, map[string]any{"source": "scim"}))
+	}
+	if deactivated {
+		events = append(events,
+			model.NewUserEvent(model.EventUserDeactivated, u.ID, map[string]any{"source": "scim"}),
+			model.NewUserEvent(model.EventSessionRevoked, u.ID, map[string]any{"reason": "deactivated"}),
+		)
+	}
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.users.UpdateUser(ctx, u, events...); err != nil {
+			if errors.Is(err, user.ErrNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if deactivated {
+			if err := s.tokens.RevokeAllUserTokens(ctx, u.ID); err != nil {
+				return fmt.Errorf("failed to revoke tokens: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.toUser(u), nil
+}
+
+// applyUser copies the stored attributes of a SCIM user onto u.
+func (s *Service) applyUser(ctx context.Context, u *model.User, in *User) error {
+	email := strings.ToLower(in.email())
+	if email == "" {
+		return fmt.Errorf("%w: userName must be an email address", ErrInvalidValue)
+	}
+	if !strings.EqualFold(email, u.Email) {
+		existing, err := s.users.FindUserByEmail(ctx, email)
+		if err == nil && existing.ID != u.ID {
+			return fmt.Errorf("%w: userName %q is taken", ErrConflict, email)
+		}
+		if err != nil && !errors.Is(err, user.ErrNotFound) {
+			return err
+		}
+	}
+	u.Email = email
+
+	if in.Name != nil {
+		u.FirstName = in.Name.GivenName
+		u.LastName = in.Name.FamilyName
+	}
+
+	if phone := strings.TrimSpace(primary(in.PhoneNumbers)); phone != "" {
+		u.Phone = &phone
+	} else {
+		u.Phone = nil
+	}
+
+	if in.Active != nil {
+		u.IsActive = *in.Active
+	}
+	return nil
+}
+
+func (s *Service) findUser(ctx context.Context, id string) (*model.User, error) {
+	userID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.users.FindUserById(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *Service) ListGroups(ctx context.Context, filter string, startIndex, count int) (*ListResponse, error) {
+	q, startIndex := page(startIndex, count)
+	conditions, err := parseFilter(filter, groupFilterAttrs)
+	if err != nil {
+		return nil, err
+	}
+	q.Conditions = conditions
+
+	// count=0 asks for totalResults only.
+	totalOnly := q.Limit == 0
+	if totalOnly {
+		q.Limit = 1
+	}
+	groups, total, err := s.groups.ListGroups(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if totalOnly {
+		groups = nil
+	}
+
+	resources := make([]any, 0, len(groups))
+	for _, g := range groups {
+		resources = append(resources, s.toGroup(g))
+	}
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, id string) (*Group, error) {
+	g, err := s.findGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toGroup(g), nil
+}
+
+func (s *Service) CreateGroup(ctx context.Context, in *Group) (*Group, error) {
+	if strings.TrimSpace(in.DisplayName) == "" {
+		return nil, fmt.Errorf("%w: displayName is required", ErrInvalidValue)
+	}
+	memberIDs, err := s.memberIDs(ctx, memberValues(in.Members))
+	if err != nil {
+		return nil, err
+	}
+
+	g := &model.Group{DisplayName: in.DisplayName}
+	if in.ExternalID != "" {
+		g.ExternalID = &in.ExternalID
+	}
+	for _, id := range memberIDs {
+		g.Members = append(g.Members, model.GroupMember{UserID: id})
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.ensureGroupNameFree(ctx, in.DisplayName, 0); err != nil {
+			return err
+		}
+		return mapGroupErr(s.groups.CreateGroup(ctx, g), g.DisplayName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGroup(ctx, strconv.FormatInt(g.ID, 10))
+}
+
+func (s *Service) ReplaceGroup(ctx context.Context, id string, in *Group) (*Group, error) {
+	g, err := s.findGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(in.DisplayName) == "" {
+		return nil, fmt.Errorf("%w: displayName is required", ErrInvalidValue)
+	}
+	memberIDs, err := s.memberIDs(ctx, memberValues(in.Members))
+	if err != nil {
+		return nil, err
+	}
+
+	g.DisplayName = in.DisplayName
+	g.ExternalID = nil
+	if in.ExternalID != "" {
+		g.ExternalID = &in.ExternalID
+	}
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.ensureGroupNameFree(ctx, g.DisplayName, g.ID); err != nil {
+			return err
+		}
+		if err := s.groups.UpdateGroup(ctx, g); err != nil {
+			return mapGroupErr(err, g.DisplayName)
+		}
+		return s.groups.ReplaceMembers(ctx, g.ID, memberIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGroup(ctx, id)
+}
+
+func (s *Service) PatchGroup(ctx context.Context, id string, req *PatchRequest) (*Group, error) {
+	g, err := s.findGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse every operation and resolve every member before touching
+	// storage, and apply them in one transaction, so an invalid request
+	// leaves the group unchanged.
+	patches := make([]*groupPatch, 0, len(req.Operations))
+	added := make([][]int64, 0, len(req.Operations))
+	for _, op := range req.Operations {
+		p, err := parseGroupOp(op)
+		if err != nil {
+			return nil, err
+		}
+		ids, err := s.memberIDs(ctx, p.addMembers)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, p)
+		added = append(added, ids)
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		for i, p := range patches {
+			if p.displayName != nil || p.externalID != nil {
+				if p.displayName != nil {
+					if err := s.ensureGroupNameFree(ctx, *p.displayName, g.ID); err != nil {
+						return err
+					}
+					g.DisplayName = *p.displayName
+				}
+				if p.externalID != nil {
+					g.ExternalID = p.externalID
+					if *p.externalID == "" {
+						g.ExternalID = nil
+					}
+				}
+				if err := s.groups.UpdateGroup(ctx, g); err != nil {
+					return mapGroupErr(err, g.DisplayName)
+				}
+			}
+
+			if err := s.applyMemberPatch(ctx, g.ID, p, added[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGroup(ctx, id)
+}
+
+// applyMemberPatch applies the membership part of p. addIDs are the
+// resolved p.addMembers.
+func (s *Service) applyMemberPatch(ctx context.Context, groupID int64, p *groupPatch, addIDs []int64) error {
+	if p.removeAll {
+		return s.groups.ReplaceMembers(ctx, groupID, nil)
+	}
+	if len(p.removeMembers) > 0 {
+		ids := make([]int64, 0, len(p.removeMembers))
+		for _, v := range p.removeMembers {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				continue // cannot be a member
+			}
+			ids = append(ids, id)
+		}
+		if err := s.groups.RemoveMembers(ctx, groupID, ids); err != nil {
+			return err
+		}
+	}
+	if p.replace {
+		return s.groups.ReplaceMembers(ctx, groupID, addIDs)
+	}
+	if len(addIDs) > 0 {
+		return s.groups.AddMembers(ctx, groupID, addIDs)
+	}
+	return nil
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, id string) error {
+	groupID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.groups.DeleteGroup(ctx, groupID); err != nil {
+		if errors.Is(err, group.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) findGroup(ctx context.Context, id string) (*model.Group, error) {
+	groupID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	g, err := s.groups.FindGroupById(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, group.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+func (s *Service) ensureGroupNameFree(ctx context.Context, name string, selfID int64) error {
+	groups, _, err := s.groups.ListGroups(ctx, query.Filter{
+		Conditions: []query.Condition{{Field: "display_name", Op: query.OpEq, Value: name}},
+		Limit:      1,
+	})
+	if err != nil {
+		return err
+	}
+	if len(groups) > 0 && groups[0].ID != selfID {
+		return fmt.Errorf("%w: displayName %q is taken", ErrConflict, name)
+	}
+	return nil
+}
+
+// mapGroupErr reports a display name taken by a concurrent write, which
+// ensureGroupNameFree can't see, as a conflict.
+func mapGroupErr(err error, name string) error {
+	if errors.Is(err, group.ErrNameTaken) {
+		return fmt.Errorf("%w: displayName %q is taken", ErrConflict, name)
+	}
+	return err
+}
+
+// memberIDs resolves member values to existing user IDs.
+func (s *Service) memberIDs(ctx context.Context, values []string) ([]int64, error) {
+	ids := make([]int64, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown member %q", ErrInvalidValue, v)
+		}
+		if _, err := s.users.FindUserById(ctx, id); err != nil {
+			if errors.Is(err, user.ErrNotFound) {
+				return nil, fmt.Errorf("%w: unknown member %q", ErrInvalidValue, v)
+			}
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseID maps malformed IDs onto not-found, as no such resource can exist.
+func parseID(id string) (int64, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, ErrNotFound
+	}
+	return n, nil
+}
@@ -0,0 +1,316 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/group"
+	"github.com/razedwell/go-hand/internal/repository/query"
+)
+
+type txKey struct{}
+
+// markingTx tags the context of each unit of work with a sequence number.
+type markingTx struct {
+	units int
+}
+
+func (m *markingTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.units++
+	return fn(context.WithValue(ctx, txKey{}, m.units))
+}
+
+// groupStore is a group.Repository that records the transaction of every
+// write.
+type groupStore struct {
+	groups map[int64]*model.Group
+	nextID int64
+	writes []int // transaction of each write, 0 outside one
+}
+
+func newGroupStore() *groupStore {
+	return &groupStore{groups: map[int64]*model.Group{}}
+}
+
+func (s *groupStore) write(ctx context.Context) {
+	n, _ := ctx.Value(txKey{}).(int)
+	s.writes = append(s.writes, n)
+}
+
+func (s *groupStore) CreateGroup(ctx context.Context, g *model.Group) error {
+	s.write(ctx)
+	s.nextID++
+	g.ID = s.nextID
+	c := *g
+	s.groups[g.ID] = &c
+	return nil
+}
+
+func (s *groupStore) FindGroupById(ctx context.Context, id int64) (*model.Group, error) {
+	g, ok := s.groups[id]
+	if !ok {
+		return nil, group.ErrNotFound
+	}
+	c := *g
+	c.Members = append([]model.GroupMember(nil), g.Members...)
+	return &c, nil
+}
+
+func (s *groupStore) ListGroups(ctx context.Context, filter query.Filter) ([]*model.Group, int, error) {
+	var matched []*model.Group
+	for _, g := range s.groups {
+		ok := true
+		for _, c := range filter.Conditions {
+			if c.Field == "display_name" && c.Op == query.OpEq && g.DisplayName != c.Value {
+				ok = false
+			}
+		}
+		if ok {
+			matched = append(matched, g)
+		}
+	}
+	return matched, len(matched), nil
+}
+
+func (s *groupStore) UpdateGroup(ctx context.Context, g *model.Group) error {
+	s.write(ctx)
+	stored, ok := s.groups[g.ID]
+	if !ok {
+		return group.ErrNotFound
+	}
+	stored.DisplayName, stored.ExternalID = g.DisplayName, g.ExternalID
+	return nil
+}
+
+func (s *groupStore) DeleteGroup(ctx context.Context, id int64) error {
+	s.write(ctx)
+	delete(s.groups, id)
+	return nil
+}
+
+func (s *groupStore) AddMembers(ctx context.Context, groupID int64, userIDs []int64) error {
+	s.write(ctx)
+	g := s.groups[groupID]
+	for _, id := range userIDs {
+		g.Members = append(g.Members, model.GroupMember{UserID: id})
+	}
+	return nil
+}
+
+func (s *groupStore) RemoveMembers(ctx context.Context, groupID int64, userIDs []int64) error {
+	s.write(ctx)
+	g := s.groups[groupID]
+	kept := g.Members[:0]
+	for _, m := range g.Members {
+		remove := false
+		for _, id := range userIDs {
+			remove = remove || m.UserID == id
+		}
+		if !remove {
+			kept = append(kept, m)
+		}
+	}
+	g.Members = kept
+	return nil
+}
+
+func (s *groupStore) ReplaceMembers(ctx context.Context, groupID int64, userIDs []int64) error {
+	s.write(ctx)
+	s.groups[groupID].Members = nil
+	for _, id := range userIDs {
+		s.groups[groupID].Members = append(s.groups[groupID].Members, model.GroupMember{UserID: id})
+	}
+	return nil
+}
+
+type fixture struct {
+	svc    *Service
+	tx     *markingTx
+	users  *memory.UserRepo
+	tokens *memory.TokenRepo
+	groups *groupStore
+}
+
+func newFixture(t *testing.T, emails ...string) *fixture {
+	t.Helper()
+	f := &fixture{tx: &markingTx{}, users: memory.NewUserRepo(nil), tokens: memory.NewTokenRepo(), groups: newGroupStore()}
+	for _, email := range emails {
+		if err := f.users.CreateUser(context.Background(), &model.User{Email: email, IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.svc = NewService(f.tx, f.users, f.groups, f.tokens, "https://api.example.com/scim/v2")
+	return f
+}
+
+func patchOp(op, path string, value any) PatchOp {
+	raw, _ := json.Marshal(value)
+	return PatchOp{Op: op, Path: path, Value: raw}
+}
+
+func TestPatchGroupUnknownMemberLeavesGroupUnchanged(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "ada@example.com")
+	created, err := f.svc.CreateGroup(ctx, &Group{DisplayName: "engineering", Members: []Member{{Value: "1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.groups.writes = nil
+
+	_, err = f.svc.PatchGroup(ctx, created.ID, &PatchRequest{Operations: []PatchOp{
+		patchOp("replace", "displayName", "platform"),
+		patchOp("remove", `members[value eq "1"]`, nil),
+		patchOp("add", "members", []Member{{Value: "99"}}),
+	}})
+	if !errors.Is(err, ErrInvalidValue) || !strings.Contains(err.Error(), `"99"`) {
+		t.Fatalf("expected an unknown member error, got %v", err)
+	}
+	if len(f.groups.writes) != 0 {
+		t.Fatalf("expected no writes, got %d", len(f.groups.writes))
+	}
+	g, _ := f.svc.GetGroup(ctx, created.ID)
+	if g.DisplayName != "engineering" || len(g.Members) != 1 {
+		t.Fatalf("group changed: %+v", g)
+	}
+}
+
+func TestGroupWritesShareOneTransaction(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "ada@example.com", "grace@example.com")
+	created, err := f.svc.CreateGroup(ctx, &Group{DisplayName: "engineering"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, write := range map[string]func() error{
+		"replace": func() error {
+			_, err := f.svc.ReplaceGroup(ctx, created.ID, &Group{DisplayName: "platform", Members: []Member{{Value: "1"}}})
+			return err
+		},
+		"patch": func() error {
+			_, err := f.svc.PatchGroup(ctx, created.ID, &PatchRequest{Operations: []PatchOp{
+				patchOp("replace", "displayName", "infra"),
+				patchOp("add", "members", []Member{{Value: "2"}}),
+			}})
+			return err
+		},
+	} {
+		f.groups.writes = nil
+		if err := write(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(f.groups.writes) < 2 {
+			t.Fatalf("%s: expected several writes, got %v", name, f.groups.writes)
+		}
+		for _, tx := range f.groups.writes {
+			if tx == 0 || tx != f.groups.writes[0] {
+				t.Fatalf("%s: writes ran in transactions %v", name, f.groups.writes)
+			}
+		}
+	}
+}
+
+// takenStore reports a concurrent insert of the same display name, which
+// the pre-check can't see.
+type takenStore struct {
+	*groupStore
+}
+
+func (s takenStore) CreateGroup(ctx context.Context, g *model.Group) error {
+	return group.ErrNameTaken
+}
+
+func TestCreateGroupNameRaceIsConflict(t *testing.T) {
+	svc := NewService(&markingTx{}, memory.NewUserRepo(nil), takenStore{newGroupStore()}, memory.NewTokenRepo(), "")
+	if _, err := svc.CreateGroup(context.Background(), &Group{DisplayName: "engineering"}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+}
+
+func TestPatchGroupRemovesFilteredMember(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "ada@example.com", "grace@example.com")
+	created, err := f.svc.CreateGroup(ctx, &Group{DisplayName: "engineering", Members: []Member{{Value: "1"}, {Value: "2"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := f.svc.PatchGroup(ctx, created.ID, &PatchRequest{Operations: []PatchOp{patchOp("remove", `members[value eq "1"]`, nil)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Members) != 1 || g.Members[0].Value != "2" {
+		t.Fatalf("members = %+v", g.Members)
+	}
+}
+
+func TestListUsersPaging(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "a@example.com", "b@example.com", "c@example.com")
+
+	tests := []struct {
+		startIndex, count int
+		wantStart         int
+		wantItems         int
+	}{
+		{1, -1, 1, 3},
+		{0, 2, 1, 2},
+		{3, 10, 3, 1},
+		{4, 10, 4, 0},
+		{1, 0, 1, 0}, // totalResults only
+	}
+	for _, tt := range tests {
+		res, err := f.svc.ListUsers(ctx, "", tt.startIndex, tt.count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.TotalResults != 3 || res.StartIndex != tt.wantStart || res.ItemsPerPage != tt.wantItems || len(res.Resources) != tt.wantItems {
+			t.Errorf("startIndex %d, count %d: total %d, start %d, items %d",
+				tt.startIndex, tt.count, res.TotalResults, res.StartIndex, res.ItemsPerPage)
+		}
+	}
+
+	res, err := f.svc.ListUsers(ctx, `userName eq "b@example.com"`, 1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TotalResults != 1 || res.Resources[0].(*User).UserName != "b@example.com" {
+		t.Fatalf("filtered list: %+v", res)
+	}
+	if _, err := f.svc.ListUsers(ctx, `password eq "x"`, 1, -1); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("expected ErrInvalidFilter, got %v", err)
+	}
+}
+
+func TestDeactivationRevokesRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, "ada@example.com")
+	if err := f.tokens.CreateRefreshToken(ctx, &model.RefreshToken{UserID: 1, TokenHash: "h", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := f.svc.PatchUser(ctx, "1", &PatchRequest{Operations: []PatchOp{patchOp("replace", "active", false)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Active == nil || *res.Active {
+		t.Fatal("user still active")
+	}
+	u, _ := f.users.FindUserById(ctx, 1)
+	if u.IsActive {
+		t.Fatal("stored user still active")
+	}
+	rt, err := f.tokens.GetRefreshToken(ctx, "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt.RevokedAt == nil {
+		t.Fatal("refresh token not revoked")
+	}
+}
@@ -0,0 +1,284 @@
+package sso
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/metrics"
+	"github.com/razedwell/go-hand/internal/repository/identity"
+	"github.com/razedwell/go-hand/internal/repository/transaction"
+	"github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+// SPConfig describes how the SAML service provider identifies itself and
+// where it finds the identity provider's metadata.
+type SPConfig struct {
+	RootURL           string // public base URL of this service, e.g. https://api.example.com
+	EntityID          string // defaults to the metadata URL when empty
+	CertFile          string
+	KeyFile           string
+	IDPMetadataURL    string
+	IDPMetadataFile   string
+	AllowIDPInitiated bool
+	MetadataTimeout   time.Duration // for fetching IDPMetadataURL; defaults to 10s
+}
+
+const defaultMetadataTimeout = 10 * time.Second
+
+// AttributeMap names the assertion attributes mapped onto model.User.
+// Each attribute is matched by Name or FriendlyName.
+type AttributeMap struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Phone     string
+}
+
+var (
+	errInvalidAssertion = errors.New("invalid saml assertion")
+	errMissingSubject   = errors.New("saml assertion has no persistent name id")
+	errMissingEmail     = errors.New("saml assertion has no email")
+	// errNotLinked rejects an assertion for the email of a local account
+	// that was never linked to this IdP, so an IdP user can't take over an
+	// existing account by using its address.
+	errNotLinked = errors.New("account exists but is not linked to this identity provider")
+)
+
+// TokenIssuer logs in a user once the assertion has been accepted, checking
+// account state and running any second factor first. auth.Service
+// implements it.
+type TokenIssuer interface {
+	IssueTokens(ctx context.Context, u *model.User, method string) (string, string, error)
+}
+
+type Service struct {
+	sp         *saml.ServiceProvider
+	tx         transaction.Manager
+	users      user.Repository
+	identities identity.Repository
+	issuer     TokenIssuer
+	attrs      AttributeMap
+}
+
+func NewService(sp *saml.ServiceProvider, tx transaction.Manager, users user.Repository, identities identity.Repository, issuer TokenIssuer, attrs AttributeMap) *Service {
+	return &Service{sp: sp, tx: tx, users: users, identities: identities, issuer: issuer, attrs: attrs}
+}
+
+// NewServiceProvider loads the SP key pair and the IdP metadata and builds
+// a service provider whose metadata and ACS endpoints live under /saml.
+func NewServiceProvider(ctx context.Context, cfg SPConfig) (*saml.ServiceProvider, error) {
+	keyPair, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saml key pair: %w", err)
+	}
+	if len(keyPair.Certificate) == 0 {
+		return nil, errors.New("saml certificate file is empty")
+	}
+	key, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("saml key must be an RSA private key")
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse saml certificate: %w", err)
+	}
+
+	idpMetadata, err := loadIDPMetadata(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	rootURL, err := url.Parse(strings.TrimSuffix(cfg.RootURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid saml root url: %w", err)
+	}
+
+	sp := &saml.ServiceProvider{
+		EntityID:          cfg.EntityID,
+		Key:               key,
+		Certificate:       cert,
+		MetadataURL:       *rootURL.JoinPath("/saml/metadata"),
+		AcsURL:            *rootURL.JoinPath("/saml/acs"),
+		IDPMetadata:       idpMetadata,
+		AllowIDPInitiated: cfg.AllowIDPInitiated,
+	}
+	return sp, nil
+}
+
+func loadIDPMetadata(ctx context.Context, cfg SPConfig) (*saml.EntityDescriptor, error) {
+	if cfg.IDPMetadataFile != "" {
+		data, err := os.ReadFile(cfg.IDPMetadataFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read idp metadata: %w", err)
+		}
+		return samlsp.ParseMetadata(data)
+	}
+	if cfg.IDPMetadataURL != "" {
+		u, err := url.Parse(cfg.IDPMetadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid idp metadata url: %w", err)
+		}
+		timeout := cfg.MetadataTimeout
+		if timeout <= 0 {
+			timeout = defaultMetadataTimeout
+		}
+		// A hanging IdP would otherwise block startup indefinitely.
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return samlsp.FetchMetadata(ctx, &http.Client{Timeout: timeout}, *u)
+	}
+	return nil, errors.New("no idp metadata configured")
+}
+
+// Metadata returns the SP metadata document to hand to the IdP.
+func (s *Service) Metadata() ([]byte, error) {
+	return xml.MarshalIndent(s.sp.Metadata(), "", "  ")
+}
+
+// LoginURL starts an SP-initiated login. The returned request ID must be
+// presented again when the response arrives at the ACS endpoint.
+func (s *Service) LoginURL(relayState string) (*url.URL, string, error) {
+	req, err := s.sp.MakeAuthenticationRequest(s.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return nil, "", err
+	}
+	redirectURL, err := req.Redirect(relayState, s.sp)
+	if err != nil {
+		return nil, "", err
+	}
+	return redirectURL, req.ID, nil
+}
+
+// ConsumeAssertion validates a SAML response, provisions the user on first
+// login and issues the normal access/refresh token pair. It returns an
+// auth.SecondFactorRequiredError if the user is enrolled in one.
+func (s *Service) ConsumeAssertion(ctx context.Context, samlResponse []byte, requestIDs []string) (string, string, error) {
+	assertion, err := s.sp.ParseXMLResponse(samlResponse, requestIDs)
+	if err != nil {
+		metrics.LoginFailed("saml", "invalid_assertion")
+		return "", "", errInvalidAssertion
+	}
+
+	u, err := s.userFromAssertion(ctx, assertion)
+	if err != nil {
+		reason := "provisioning"
+		if errors.Is(err, errNotLinked) {
+			reason = "not_linked"
+		}
+		metrics.LoginFailed("saml", reason)
+		return "", "", err
+	}
+	return s.issuer.IssueTokens(ctx, u, "saml")
+}
+
+// userFromAssertion resolves the user by the NameID, scoped to the IdP.
+// Unknown NameIDs get a new account, unless a local account already uses
+// the email address: linking that one takes an explicit user_identities
+// row, since the IdP's word on an email address proves nothing here.
+func (s *Service) userFromAssertion(ctx context.Context, assertion *saml.Assertion) (*model.User, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" ||
+		assertion.Subject.NameID.Format == string(saml.TransientNameIDFormat) {
+		return nil, errMissingSubject
+	}
+	nameID := assertion.Subject.NameID
+	provider := "saml:" + s.sp.IDPMetadata.EntityID
+
+	linked, err := s.identities.FindIdentity(ctx, provider, nameID.Value)
+	if err == nil {
+		return s.users.FindUserById(ctx, linked.UserID)
+	}
+	if !errors.Is(err, identity.ErrNotFound) {
+		return nil, err
+	}
+
+	email := s.attribute(assertion, s.attrs.Email)
+	if email == "" && nameID.Format == string(saml.EmailAddressNameIDFormat) {
+		email = nameID.Value
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, errMissingEmail
+	}
+
+	if _, err := s.users.FindUserByEmail(ctx, email); err == nil {
+		return nil, errNotLinked
+	} else if !errors.Is(err, user.ErrNotFound) {
+		return nil, err
+	}
+
+	// Just-in-time provisioning. SSO users never log in with a password.
+	hashedPassword, err := security.UnusablePasswordHash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := helpers.GetCurrentTimeStampUTC()
+	newUser := &model.User{
+		CreatedAt: now,
+		UpdatedAt: now,
+
+		// Identity fields
+		FirstName:    s.attribute(assertion, s.attrs.FirstName),
+		LastName:     s.attribute(assertion, s.attrs.LastName),
+		Email:        email,
+		PasswordHash: hashedPassword,
+
+		// Account state
+		IsActive:        true,
+		IsEmailVerified: true, // asserted by the IdP
+		IsPhoneVerified: false,
+		IsBanned:        false,
+
+		// Authorization
+		Role: model.RoleUser,
+	}
+	if phone := s.attribute(assertion, s.attrs.Phone); phone != "" {
+		newUser.Phone = &phone
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		registered := model.NewUserEvent(model.EventUserRegistered, 0, map[string]any{"email": newUser.Email, "source": "saml"})
+		if err := s.users.CreateUser(ctx, newUser, registered); err != nil {
+			return err
+		}
+		return s.identities.CreateIdentity(ctx, &model.Identity{UserID: newUser.ID, Provider: provider, Subject: nameID.Value})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newUser, nil
+}
+
+func (s *Service) attribute(assertion *saml.Assertion, name string) string {
+	if name == "" {
+		return ""
+	}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			if attr.Name != name && attr.FriendlyName != name {
+				continue
+			}
+			for _, v := range attr.Values {
+				if v.Value != "" {
+					return v.Value
+				}
+			}
+		}
+	}
+	return ""
+}
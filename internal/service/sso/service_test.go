@@ -0,0 +1,232 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+func newKeyPair(t *testing.T, commonName string) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+func mustParseURL(t *testing.T, raw string) url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *u
+}
+
+// stubIssuer hands out the user ID as the access token, which is all the
+// tests need to tell who was logged in.
+type stubIssuer struct{}
+
+func (stubIssuer) IssueTokens(ctx context.Context, u *model.User, method string) (string, string, error) {
+	return "access-" + strconv.FormatInt(u.ID, 10), "refresh", nil
+}
+
+type fixture struct {
+	svc        *Service
+	idp        *saml.IdentityProvider
+	sp         *saml.ServiceProvider
+	users      *memory.UserRepo
+	identities *memory.IdentityRepo
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	idpKey, idpCert := newKeyPair(t, "idp.test")
+	spKey, spCert := newKeyPair(t, "sp.test")
+
+	idp := &saml.IdentityProvider{
+		Key:         idpKey,
+		Certificate: idpCert,
+		MetadataURL: mustParseURL(t, "https://idp.test/metadata"),
+		SSOURL:      mustParseURL(t, "https://idp.test/sso"),
+	}
+	sp := &saml.ServiceProvider{
+		Key:               spKey,
+		Certificate:       spCert,
+		MetadataURL:       mustParseURL(t, "https://sp.test/saml/metadata"),
+		AcsURL:            mustParseURL(t, "https://sp.test/saml/acs"),
+		IDPMetadata:       idp.Metadata(),
+		AllowIDPInitiated: true,
+	}
+
+	users := memory.NewUserRepo(nil)
+	identities := memory.NewIdentityRepo()
+	svc := NewService(sp, memory.TxManager{}, users, identities, stubIssuer{}, AttributeMap{
+		Email:     "email",
+		FirstName: "givenName",
+		LastName:  "sn",
+	})
+	return &fixture{svc: svc, idp: idp, sp: sp, users: users, identities: identities}
+}
+
+// response returns an IdP-initiated, signed SAML response for nameID.
+func (f *fixture) response(t *testing.T, nameID, email string) []byte {
+	t.Helper()
+	spMetadata := f.sp.Metadata()
+	spDescriptor := &spMetadata.SPSSODescriptors[0]
+	req := &saml.IdpAuthnRequest{
+		IDP:                     f.idp,
+		HTTPRequest:             httptest.NewRequest(http.MethodPost, "https://idp.test/sso", nil),
+		Now:                     saml.TimeNow(),
+		ServiceProviderMetadata: spMetadata,
+		SPSSODescriptor:         spDescriptor,
+		ACSEndpoint:             &spDescriptor.AssertionConsumerServices[0],
+	}
+	session := &saml.Session{
+		ID:            "session-" + nameID,
+		CreateTime:    saml.TimeNow(),
+		ExpireTime:    saml.TimeNow().Add(time.Hour),
+		Index:         "1",
+		NameID:        nameID,
+		NameIDFormat:  string(saml.PersistentNameIDFormat),
+		UserGivenName: "Ada",
+		UserSurname:   "Lovelace",
+		CustomAttributes: []saml.Attribute{{
+			Name:   "email",
+			Values: []saml.AttributeValue{{Type: "xs:string", Value: email}},
+		}},
+	}
+	if err := (saml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		t.Fatal(err)
+	}
+	form, err := req.PostBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(form.SAMLResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestConsumeAssertionProvisionsAndLinks(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	access, _, err := f.svc.ConsumeAssertion(ctx, f.response(t, "ada-1", "Ada@Example.com"), nil)
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	u, err := f.users.FindUserByEmail(ctx, "ada@example.com")
+	if err != nil {
+		t.Fatalf("user was not provisioned: %v", err)
+	}
+	if u.FirstName != "Ada" || u.LastName != "Lovelace" || u.Role != model.RoleUser {
+		t.Fatalf("unexpected provisioned user: %+v", u)
+	}
+	linked, err := f.identities.FindIdentity(ctx, "saml:"+f.idp.Metadata().EntityID, "ada-1")
+	if err != nil || linked.UserID != u.ID {
+		t.Fatalf("identity not linked to user %d: %+v, %v", u.ID, linked, err)
+	}
+
+	// The email attribute no longer matters once the NameID is linked.
+	again, _, err := f.svc.ConsumeAssertion(ctx, f.response(t, "ada-1", "renamed@example.com"), nil)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if again != access {
+		t.Fatalf("second login issued tokens for another user: %q != %q", again, access)
+	}
+}
+
+func TestConsumeAssertionRefusesUnlinkedLocalAccount(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	admin := &model.User{Email: "admin@example.com", Role: model.RoleAdmin, IsActive: true}
+	if err := f.users.CreateUser(ctx, admin); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := f.svc.ConsumeAssertion(ctx, f.response(t, "attacker", "admin@example.com"), nil)
+	if !errors.Is(err, errNotLinked) {
+		t.Fatalf("expected errNotLinked, got %v", err)
+	}
+}
+
+func TestConsumeAssertionRejectsTamperedResponse(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	raw := f.response(t, "ada-1", "ada@example.com")
+	tampered := strings.Replace(string(raw), "https://sp.test/saml/acs", "https://sp.test/saml/acz", 1)
+	if tampered == string(raw) {
+		t.Fatal("response does not contain the ACS URL")
+	}
+	if _, _, err := f.svc.ConsumeAssertion(ctx, []byte(tampered), nil); !errors.Is(err, errInvalidAssertion) {
+		t.Fatalf("expected errInvalidAssertion, got %v", err)
+	}
+
+	// A response signed by a different IdP key must not verify either.
+	other := newFixture(t)
+	other.sp = f.sp
+	if _, _, err := f.svc.ConsumeAssertion(ctx, other.response(t, "ada-1", "ada@example.com"), nil); !errors.Is(err, errInvalidAssertion) {
+		t.Fatalf("expected errInvalidAssertion for a foreign IdP, got %v", err)
+	}
+	if _, err := f.users.FindUserByEmail(ctx, "ada@example.com"); err == nil {
+		t.Fatal("rejected response provisioned a user")
+	}
+}
+
+func TestMetadataFetchTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	start := time.Now()
+	_, err := loadIDPMetadata(context.Background(), SPConfig{IDPMetadataURL: srv.URL, MetadataTimeout: 50 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected the fetch to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("fetch took %s", elapsed)
+	}
+}
@@ -2,10 +2,16 @@ package user
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"strings"
 
 	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/token"
+	"github.com/razedwell/go-hand/internal/repository/transaction"
 	"github.com/razedwell/go-hand/internal/repository/user"
 	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/service/audit"
 	"github.com/razedwell/go-hand/internal/transport/http/helpers"
 )
 
@@ -15,23 +21,66 @@ type RegParams struct {
 	Email     string `json:"email" validate:"required,email"`
 	Phone     string `json:"phone" validate:"omitempty,e164"`
 	Password  string `json:"password" validate:"required,min=8"`
+
+	InvitationToken string `json:"invitation_token" validate:"omitempty"`
+}
+
+// Invitations redeems organization invitations during registration.
+// invitation.Service implements it.
+type Invitations interface {
+	Validate(ctx context.Context, token string) (*model.Invitation, error)
+	Redeem(ctx context.Context, inv *model.Invitation, userID int64) error
 }
 
+var (
+	ErrRegistrationClosed = errors.New("registration is by invitation only")
+	ErrInvitationMismatch = errors.New("invitation was sent to a different email address")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrSelfAction         = errors.New("admins cannot ban or demote themselves")
+)
+
 type Service struct {
-	users user.Repository
-	// Add fields if necessary
+	tx          transaction.Manager
+	users       user.Repository
+	tokens      token.Repository
+	invitations Invitations
+	inviteOnly  bool
+	audit       audit.Recorder
 }
 
-func NewService(users user.Repository) *Service {
+func NewService(tx transaction.Manager, users user.Repository, tokens token.Repository, invitations Invitations, inviteOnly bool, audit audit.Recorder) *Service {
 	return &Service{
-		users: users,
+		tx:          tx,
+		users:       users,
+		tokens:      tokens,
+		invitations: invitations,
+		inviteOnly:  inviteOnly,
+		audit:       audit,
 	}
 }
 
 func (s *Service) RegisterUser(ctx context.Context, user RegParams) error {
+	// Resolve the invitation first so a bad token doesn't leave an account behind.
+	var invitation *model.Invitation
+	if user.InvitationToken != "" {
+		inv, err := s.invitations.Validate(ctx, user.InvitationToken)
+		if err != nil {
+			return err
+		}
+		if user.Email == "" {
+			user.Email = inv.Email
+		}
+		if !strings.EqualFold(strings.TrimSpace(user.Email), inv.Email) {
+			return ErrInvitationMismatch
+		}
+		invitation = inv
+	} else if s.inviteOnly {
+		return ErrRegistrationClosed
+	}
+
 	now := helpers.GetCurrentTimeStampUTC()
 	// Hash the password
-	hashedPassword, err := security.HashPassword(user.Password)
+	hashedPassword, err := security.HashPassword(ctx, user.Password)
 	if err != nil {
 		return err
 	}
@@ -49,8 +98,9 @@ func (s *Service) RegisterUser(ctx context.Context, user RegParams) error {
 		PasswordHash: hashedPassword,
 
 		// Account state
-		IsActive:        true,
-		IsEmailVerified: false,
+		IsActive: true,
+		// The invitation link was delivered to this address, which proves it.
+		IsEmailVerified: invitation != nil,
 		IsPhoneVerified: false,
 		IsBanned:        false,
 
@@ -58,5 +108,123 @@ func (s *Service) RegisterUser(ctx context.Context, user RegParams) error {
 		Role: model.RoleUser,
 	}
 
-	return s.users.CreateUser(ctx, newUser)
+	// The account and the invitation redemption commit together, so a
+	// failed redemption doesn't leave a member-less account behind.
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		registered := model.NewUserEvent(model.EventUserRegistered, 0, map[string]any{"email": newUser.Email})
+		if err := s.users.CreateUser(ctx, newUser, registered); err != nil {
+			return err
+		}
+		if invitation != nil {
+			return s.invitations.Redeem(ctx, invitation, newUser.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]any{}
+	if invitation != nil {
+		metadata["invitation_id"] = invitation.ID
+		metadata["org_id"] = invitation.OrgID
+	}
+	s.audit.Record(ctx, &model.AuditEvent{
+		ActorID:    &newUser.ID,
+		Action:     model.AuditUserRegistered,
+		TargetType: model.AuditTargetUser,
+		TargetID:   strconv.FormatInt(newUser.ID, 10),
+		Metadata:   metadata,
+	})
+	return nil
+}
+
+// BanUser bans userID on behalf of the admin actorID and revokes their
+// refresh tokens in the same transaction. Access tokens already issued
+// stay valid until they expire.
+func (s *Service) BanUser(ctx context.Context, actorID int64, userID int64, reason string) error {
+	if actorID == userID {
+		return ErrSelfAction
+	}
+	u, err := s.users.FindUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now := helpers.GetCurrentTimeStampUTC()
+	u.IsBanned = true
+	u.BannedAt = &now
+	u.BanReason = &reason
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		err := s.users.UpdateUser(ctx, u,
+			model.NewUserEvent(model.EventUserBanned, userID, map[string]any{"reason": reason}),
+			model.NewUserEvent(model.EventSessionRevoked, userID, map[string]any{"reason": "banned"}),
+		)
+		if err != nil {
+			return err
+		}
+		return s.tokens.RevokeAllUserTokens(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordAdminAction(ctx, model.AuditUserBanned, actorID, userID, map[string]any{"reason": reason})
+	return nil
+}
+
+func (s *Service) UnbanUser(ctx context.Context, actorID int64, userID int64) error {
+	u, err := s.users.FindUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	u.IsBanned = false
+	u.BannedAt = nil
+	u.BanReason = nil
+	if err := s.users.UpdateUser(ctx, u, model.NewUserEvent(model.EventUserUnbanned, userID, nil)); err != nil {
+		return err
+	}
+
+	s.recordAdminAction(ctx, model.AuditUserUnbanned, actorID, userID, nil)
+	return nil
+}
+
+// ChangeRole sets the global role of userID. Refreshes reload the role, so
+// it applies from the next login or refresh; access tokens already issued
+// keep the old role until they expire.
+func (s *Service) ChangeRole(ctx context.Context, actorID int64, userID int64, role model.Role) error {
+	if role != model.RoleUser && role != model.RoleAdmin {
+		return ErrInvalidRole
+	}
+	if actorID == userID && role != model.RoleAdmin {
+		return ErrSelfAction
+	}
+	u, err := s.users.FindUserById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.Role == role {
+		return nil
+	}
+
+	from := u.Role
+	u.Role = role
+	changed := model.NewUserEvent(model.EventUserRoleChanged, userID, map[string]any{"from": from, "to": role})
+	if err := s.users.UpdateUser(ctx, u, changed); err != nil {
+		return err
+	}
+
+	s.recordAdminAction(ctx, model.AuditUserRoleChanged, actorID, userID, map[string]any{"from": from, "to": role})
+	return nil
+}
+
+func (s *Service) recordAdminAction(ctx context.Context, action model.AuditAction, actorID int64, userID int64, metadata map[string]any) {
+	s.audit.Record(ctx, &model.AuditEvent{
+		ActorID:    &actorID,
+		Action:     action,
+		TargetType: model.AuditTargetUser,
+		TargetID:   strconv.FormatInt(userID, 10),
+		Metadata:   metadata,
+	})
 }
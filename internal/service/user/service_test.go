@@ -0,0 +1,109 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/cache"
+	userrepo "github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/service/audit"
+)
+
+type txKey struct{}
+
+// markingTx tags the context of each unit of work with a sequence number.
+type markingTx struct {
+	units int
+}
+
+func (m *markingTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.units++
+	return fn(context.WithValue(ctx, txKey{}, m.units))
+}
+
+func txOf(ctx context.Context) int {
+	n, _ := ctx.Value(txKey{}).(int)
+	return n
+}
+
+// txUsers records the transaction CreateUser ran in.
+type txUsers struct {
+	userrepo.Repository
+	tx int
+}
+
+func (u *txUsers) CreateUser(ctx context.Context, user *model.User, events ...*model.Event) error {
+	u.tx = txOf(ctx)
+	return u.Repository.CreateUser(ctx, user, events...)
+}
+
+// failingInvitations accepts any token and fails the redemption.
+type failingInvitations struct {
+	tx int
+}
+
+func (i *failingInvitations) Validate(ctx context.Context, token string) (*model.Invitation, error) {
+	return &model.Invitation{ID: 1, OrgID: 1, Email: "ada@example.com"}, nil
+}
+
+func (i *failingInvitations) Redeem(ctx context.Context, inv *model.Invitation, userID int64) error {
+	i.tx = txOf(ctx)
+	return errors.New("invitation already redeemed")
+}
+
+func TestRegisterUserRedeemsInSameTransaction(t *testing.T) {
+	ctx := context.Background()
+	tx := &markingTx{}
+	users := &txUsers{Repository: memory.NewUserRepo(nil)}
+	invitations := &failingInvitations{}
+	svc := NewService(tx, users, memory.NewTokenRepo(), invitations, true, audit.Discard)
+
+	err := svc.RegisterUser(ctx, RegParams{Email: "ada@example.com", Password: "correct horse battery", InvitationToken: "token"})
+	if err == nil {
+		t.Fatal("expected the failed redemption to fail the registration")
+	}
+	if users.tx == 0 || users.tx != invitations.tx {
+		t.Fatalf("CreateUser ran in transaction %d and Redeem in %d", users.tx, invitations.tx)
+	}
+}
+
+func TestChangeRoleAppliesOnRefresh(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepo(nil)
+	tokenRepo := memory.NewTokenRepo()
+	svc := NewService(memory.TxManager{}, users, tokenRepo, nil, false, audit.Discard)
+
+	admin := &model.User{Email: "admin@example.com", Role: model.RoleAdmin, IsActive: true}
+	ada := &model.User{Email: "ada@example.com", Role: model.RoleUser, IsActive: true}
+	for _, u := range []*model.User{admin, ada} {
+		if err := users.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tokens := security.NewJWTManager("access-secret-for-tests-only-32b", "refresh-secret-for-tests-only-32", time.Minute, time.Hour, tokenRepo, cache.NewMemoryStore(), security.FailClosed, users, nil)
+	_, refreshToken, err := tokens.GenerateTokenPair(ada.ID, string(ada.Role))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.ChangeRole(ctx, admin.ID, ada.ID, model.RoleAdmin); err != nil {
+		t.Fatalf("change role: %v", err)
+	}
+	accessToken, err := tokens.RefreshAccessToken(ctx, refreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	claims, err := tokens.Authenticate(ctx, accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != string(model.RoleAdmin) {
+		t.Fatalf("refreshed token has role %q, want %q", claims.Role, model.RoleAdmin)
+	}
+}
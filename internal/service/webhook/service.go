@@ -0,0 +1,392 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/repository/webhook"
+	"github.com/razedwell/go-hand/internal/security"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+// Headers sent with every delivery. The signature is
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">" keyed with the
+// subscription secret; receivers should reject stale timestamps.
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderEvent     = "Webhook-Event"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+)
+
+type CreateParams struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type UpdateParams struct {
+	URL      *string  `json:"url"`
+	Events   []string `json:"events"`
+	IsActive *bool    `json:"is_active"`
+}
+
+type Config struct {
+	Timeout      time.Duration // per delivery request
+	MaxAttempts  int           // attempts before a delivery is dead
+	BaseBackoff  time.Duration // delay after the first failure, doubled each retry
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+
+	// AllowPrivateNetworks permits loopback, private and link-local
+	// destinations, e.g. a receiver on localhost during development.
+	AllowPrivateNetworks bool
+}
+
+var ErrInvalidInput = errors.New("invalid input")
+
+type Service struct {
+	repo   webhook.Repository
+	client *http.Client
+	cfg    Config
+}
+
+func NewService(repo webhook.Repository, cfg Config) *Service {
+	return &Service{
+		repo:   repo,
+		client: newClient(cfg.Timeout, cfg.AllowPrivateNetworks),
+		cfg:    cfg,
+	}
+}
+
+// HandleEvent queues e for every active subscription that wants it. It is
+// subscribed to the event bus; the event ID doubles as the delivery ID, so
+// a redelivered event doesn't queue a second delivery.
+func (s *Service) HandleEvent(ctx context.Context, e *model.Event) error {
+	subs, err := s.repo.ListActiveSubscriptions(ctx, e.Type)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"id":         strconv.FormatInt(e.ID, 10),
+		"type":       e.Type,
+		"created_at": e.OccurredAt,
+		"subject": map[string]any{
+			"type": e.SubjectType,
+			"id":   e.SubjectID,
+		},
+		"data": e.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]*model.WebhookDelivery, 0, len(subs))
+	for _, sub := range subs {
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        strconv.FormatInt(e.ID, 10),
+			EventType:      e.Type,
+			Payload:        payload,
+		})
+	}
+	return s.repo.CreateDeliveries(ctx, deliveries)
+}
+
+// Create registers a subscription. The generated secret is only returned
+// here and by RotateSecret.
+func (s *Service) Create(ctx context.Context, params CreateParams) (*model.WebhookSubscription, error) {
+	if err := s.validateURL(params.URL); err != nil {
+		return nil, err
+	}
+	if err := validateEvents(params.Events); err != nil {
+		return nil, err
+	}
+	secret, err := security.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &model.WebhookSubscription{
+		URL:      params.URL,
+		Secret:   secret,
+		Events:   params.Events,
+		IsActive: true,
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Service) Get(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	return s.repo.FindSubscriptionById(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	return s.repo.ListSubscriptions(ctx)
+}
+
+func (s *Service) Update(ctx context.Context, id int64, params UpdateParams) (*model.WebhookSubscription, error) {
+	sub, err := s.repo.FindSubscriptionById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if params.URL != nil {
+		if err := s.validateURL(*params.URL); err != nil {
+			return nil, err
+		}
+		sub.URL = *params.URL
+	}
+	if params.Events != nil {
+		if err := validateEvents(params.Events); err != nil {
+			return nil, err
+		}
+		sub.Events = params.Events
+	}
+	if params.IsActive != nil {
+		sub.IsActive = *params.IsActive
+	}
+	if err := s.repo.UpdateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Service) RotateSecret(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	sub, err := s.repo.FindSubscriptionById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := security.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = secret
+	if err := s.repo.UpdateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+// ListDeliveries pages through deliveries, optionally by status. Listing
+// model.DeliveryDead is the dead-letter view.
+func (s *Service) ListDeliveries(ctx context.Context, status model.DeliveryStatus, offset int, limit int) ([]*model.WebhookDelivery, int, error) {
+	switch status {
+	case "", model.DeliveryPending, model.DeliverySucceeded, model.DeliveryDead:
+	default:
+		return nil, 0, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, status)
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListDeliveries(ctx, status, offset, limit)
+}
+
+// Redeliver requeues a delivery, typically a dead one, for immediate sending.
+func (s *Service) Redeliver(ctx context.Context, id int64) error {
+	return s.repo.Redeliver(ctx, id)
+}
+
+// Run sends due deliveries every PollInterval until ctx is done.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.dispatch(ctx); err != nil {
+			logger.Log.ErrorContext(ctx, "Webhook dispatch failed", "error", err)
+		}
+	}
+}
+
+// leaseMargin covers the database work around the sends of a batch.
+const leaseMargin = 30 * time.Second
+
+// lease is how long a claimed batch is kept from other replicas. The batch
+// is sent one delivery at a time, so it has to cover every send timing out.
+func (s *Service) lease() time.Duration {
+	return time.Duration(max(s.cfg.BatchSize, 1))*s.cfg.Timeout + leaseMargin
+}
+
+func (s *Service) dispatch(ctx context.Context) error {
+	lease := s.lease()
+	deadline := time.Now().Add(lease)
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, s.cfg.BatchSize, lease)
+	if err != nil {
+		return err
+	}
+	// A failure is recorded on its own delivery; the rest of the batch is
+	// still sent. Unrecorded attempts are retried once the lease expires.
+	var errs []error
+	subs := map[int64]*model.WebhookSubscription{}
+	for _, d := range deliveries {
+		// Never start a send that could outlive the lease, or another
+		// replica may claim and send the same delivery. What's left is
+		// picked up once the lease expires.
+		if time.Until(deadline) < s.cfg.Timeout+leaseMargin/2 {
+			break
+		}
+		sub, ok := subs[d.SubscriptionID]
+		var lookupErr error
+		if !ok {
+			sub, lookupErr = s.repo.FindSubscriptionById(ctx, d.SubscriptionID)
+			if lookupErr == nil {
+				subs[d.SubscriptionID] = sub
+			}
+		}
+		if lookupErr != nil {
+			d.Attempts++
+			s.fail(d, helpers.GetCurrentTimeStampUTC(), fmt.Errorf("failed to load subscription: %w", lookupErr))
+		} else {
+			s.attempt(ctx, sub, d)
+		}
+		if err := s.repo.RecordAttempt(ctx, d); err != nil {
+			errs = append(errs, fmt.Errorf("delivery %d: %w", d.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// attempt sends d once and updates its status, attempt count and next
+// attempt time from the result.
+func (s *Service) attempt(ctx context.Context, sub *model.WebhookSubscription, d *model.WebhookDelivery) {
+	d.Attempts++
+	now := helpers.GetCurrentTimeStampUTC()
+
+	var statusCode int
+	var err error
+	if sub.IsActive {
+		statusCode, err = s.send(ctx, sub, d, now)
+	} else {
+		err = errors.New("subscription is disabled")
+	}
+
+	if statusCode != 0 {
+		d.LastStatusCode = &statusCode
+	}
+	if err == nil {
+		d.Status = model.DeliverySucceeded
+		d.DeliveredAt = &now
+		d.LastError = nil
+		return
+	}
+	s.fail(d, now, err)
+}
+
+// fail records a failed attempt and schedules the next one, or marks the
+// delivery dead once it is out of attempts.
+func (s *Service) fail(d *model.WebhookDelivery, now time.Time, err error) {
+	msg := err.Error()
+	d.LastError = &msg
+	if d.Attempts >= s.cfg.MaxAttempts {
+		d.Status = model.DeliveryDead
+		return
+	}
+	d.NextAttemptAt = now.Add(s.backoff(d.Attempts))
+}
+
+func (s *Service) send(ctx context.Context, sub *model.WebhookSubscription, d *model.WebhookDelivery, now time.Time) (int, error) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-hand-webhooks/1")
+	req.Header.Set(HeaderID, d.EventID)
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "t="+timestamp+",v1="+Sign(sub.Secret, timestamp, d.Payload))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver responded %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff returns the delay after the given failed attempt.
+func (s *Service) backoff(attempts int) time.Duration {
+	delay := s.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < s.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.cfg.MaxBackoff)
+}
+
+// Sign computes the v1 signature of a delivery body.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateURL rejects URLs that obviously point inside the network. Hosts
+// are only resolved when sending, where the dialer checks every address.
+func (s *Service) validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidInput)
+	}
+	if s.cfg.AllowPrivateNetworks {
+		return nil
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: url must not point to a private or loopback address", ErrInvalidInput)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && blockedAddr(addr) {
+		return fmt.Errorf("%w: url must not point to a private or loopback address", ErrInvalidInput)
+	}
+	return nil
+}
+
+func validateEvents(events []string) error {
+	if len(events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrInvalidInput)
+	}
+	for _, e := range events {
+		if e != model.EventAll && !slices.Contains(model.WebhookEventTypes, e) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidInput, e)
+		}
+	}
+	return nil
+}
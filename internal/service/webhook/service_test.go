@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/repository/webhook"
+)
+
+// batchRepo hands out one batch of deliveries and records the attempts.
+type batchRepo struct {
+	webhook.Repository
+	subs       map[int64]*model.WebhookSubscription
+	deliveries []*model.WebhookDelivery
+	recorded   map[int64]model.WebhookDelivery
+	lease      time.Duration // of the last claim
+}
+
+func (r *batchRepo) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*model.WebhookDelivery, error) {
+	r.lease = lease
+	return r.deliveries, nil
+}
+
+func (r *batchRepo) FindSubscriptionById(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	sub, ok := r.subs[id]
+	if !ok {
+		return nil, errors.New("connection reset")
+	}
+	return sub, nil
+}
+
+func (r *batchRepo) RecordAttempt(ctx context.Context, d *model.WebhookDelivery) error {
+	r.recorded[d.ID] = *d
+	return nil
+}
+
+func testConfig(allowPrivate bool) Config {
+	return Config{
+		Timeout:              time.Second,
+		MaxAttempts:          3,
+		BaseBackoff:          time.Second,
+		MaxBackoff:           time.Minute,
+		BatchSize:            10,
+		AllowPrivateNetworks: allowPrivate,
+	}
+}
+
+func newReceiver(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestValidateURLRejectsInternalHosts(t *testing.T) {
+	s := NewService(nil, testConfig(false))
+	for _, raw := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://172.16.0.1/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/hook",
+		"http://[fd00::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+		"ftp://example.com/hook",
+	} {
+		if err := s.validateURL(raw); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("%s: expected ErrInvalidInput, got %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"https://hooks.example.com/in", "http://93.184.216.34/hook"} {
+		if err := s.validateURL(raw); err != nil {
+			t.Errorf("%s: %v", raw, err)
+		}
+	}
+
+	dev := NewService(nil, testConfig(true))
+	if err := dev.validateURL("http://localhost:8080/hook"); err != nil {
+		t.Errorf("private networks allowed: %v", err)
+	}
+}
+
+func TestDispatchRefusesLoopbackAtSendTime(t *testing.T) {
+	srv, hits := newReceiver(t)
+	repo := &batchRepo{
+		// Stored before validation existed, or behind a name that
+		// resolves to loopback.
+		subs:       map[int64]*model.WebhookSubscription{1: {ID: 1, URL: srv.URL, Secret: "s", IsActive: true}},
+		deliveries: []*model.WebhookDelivery{{ID: 1, SubscriptionID: 1, EventID: "1", Payload: []byte(`{}`)}},
+		recorded:   map[int64]model.WebhookDelivery{},
+	}
+	s := NewService(repo, testConfig(false))
+
+	if err := s.dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if hits.Load() != 0 {
+		t.Fatal("delivery reached a loopback receiver")
+	}
+	d := repo.recorded[1]
+	if d.Status == model.DeliverySucceeded || d.LastError == nil || !strings.Contains(*d.LastError, errBlockedAddress.Error()) {
+		t.Fatalf("expected a blocked-address failure, got status %q, error %v", d.Status, d.LastError)
+	}
+}
+
+func TestDispatchContinuesAfterFailedLookup(t *testing.T) {
+	srv, hits := newReceiver(t)
+	repo := &batchRepo{
+		subs: map[int64]*model.WebhookSubscription{2: {ID: 2, URL: srv.URL, Secret: "s", IsActive: true}},
+		deliveries: []*model.WebhookDelivery{
+			{ID: 1, SubscriptionID: 1, EventID: "1", Payload: []byte(`{}`)},
+			{ID: 2, SubscriptionID: 2, EventID: "1", Payload: []byte(`{}`)},
+		},
+		recorded: map[int64]model.WebhookDelivery{},
+	}
+	s := NewService(repo, testConfig(true))
+
+	if err := s.dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	failed, sent := repo.recorded[1], repo.recorded[2]
+	if failed.Attempts != 1 || failed.LastError == nil || failed.Status == model.DeliverySucceeded {
+		t.Fatalf("failed lookup not recorded as an attempt: %+v", failed)
+	}
+	if sent.Status != model.DeliverySucceeded || hits.Load() != 1 {
+		t.Fatalf("rest of the batch not sent: status %q, hits %d", sent.Status, hits.Load())
+	}
+}
+
+func TestDispatchLeaseCoversBatch(t *testing.T) {
+	// Every receiver hangs until the client gives up.
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	cfg := testConfig(true)
+	cfg.Timeout = 50 * time.Millisecond
+	cfg.BatchSize = 5
+	repo := &batchRepo{
+		subs:     map[int64]*model.WebhookSubscription{1: {ID: 1, URL: srv.URL, Secret: "s", IsActive: true}},
+		recorded: map[int64]model.WebhookDelivery{},
+	}
+	for i := int64(1); i <= int64(cfg.BatchSize); i++ {
+		repo.deliveries = append(repo.deliveries, &model.WebhookDelivery{ID: i, SubscriptionID: 1, EventID: strconv.FormatInt(i, 10), Payload: []byte(`{}`)})
+	}
+	s := NewService(repo, cfg)
+
+	start := time.Now()
+	if err := s.dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+
+	if min := time.Duration(cfg.BatchSize) * cfg.Timeout; repo.lease < min {
+		t.Fatalf("lease %v is shorter than a batch of timeouts (%v)", repo.lease, min)
+	}
+	if repo.lease < elapsed {
+		t.Fatalf("lease %v expired before the batch finished (%v)", repo.lease, elapsed)
+	}
+	if len(repo.recorded) != cfg.BatchSize {
+		t.Fatalf("recorded %d of %d deliveries", len(repo.recorded), cfg.BatchSize)
+	}
+}
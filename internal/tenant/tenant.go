@@ -0,0 +1,35 @@
+// Package tenant carries the active organization through a request context.
+// Org-scoped repositories read it from the context instead of taking an org
+// ID argument, so a caller can't query another organization's rows by
+// passing the wrong ID.
+package tenant
+
+import (
+	"context"
+	"errors"
+
+	"github.com/razedwell/go-hand/internal/model"
+)
+
+var ErrNoTenant = errors.New("no active organization")
+
+type Tenant struct {
+	OrgID  int64
+	UserID int64
+	Role   model.OrgRole
+}
+
+type ctxKey struct{}
+
+func WithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromContext returns the active tenant, or ErrNoTenant.
+func FromContext(ctx context.Context) (Tenant, error) {
+	t, ok := ctx.Value(ctxKey{}).(Tenant)
+	if !ok || t.OrgID == 0 {
+		return Tenant{}, ErrNoTenant
+	}
+	return t, nil
+}
@@ -0,0 +1,227 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	userrepo "github.com/razedwell/go-hand/internal/repository/user"
+	"github.com/razedwell/go-hand/internal/service/audit"
+	"github.com/razedwell/go-hand/internal/service/user"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+	"github.com/razedwell/go-hand/internal/transport/http/middleware"
+)
+
+type Handler struct {
+	userService  *user.Service
+	auditService *audit.Service
+}
+
+func NewHandler(userService *user.Service, auditService *audit.Service) *Handler {
+	return &Handler{userService, auditService}
+}
+
+type banRequest struct {
+	Reason string `json:"reason"`
+}
+
+type roleRequest struct {
+	Role model.Role `json:"role"`
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	r.HandleFunc("POST /users/{id}/ban", h.Ban)
+	r.HandleFunc("POST /users/{id}/unban", h.Unban)
+	r.HandleFunc("PUT /users/{id}/role", h.ChangeRole)
+
+	r.HandleFunc("GET /audit", h.ListAudit)
+	r.HandleFunc("GET /audit/export", h.ExportAudit)
+}
+
+func (h *Handler) Ban(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := h.target(w, r)
+	if !ok {
+		return
+	}
+
+	var req banRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.BanUser(r.Context(), actorID, userID, req.Reason); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "User banned",
+	})
+}
+
+func (h *Handler) Unban(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := h.target(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.UnbanUser(r.Context(), actorID, userID); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "User unbanned",
+	})
+}
+
+func (h *Handler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := h.target(w, r)
+	if !ok {
+		return
+	}
+
+	var req roleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.ChangeRole(r.Context(), actorID, userID, req.Role); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Role updated",
+	})
+}
+
+// ListAudit accepts actor_id, action, target_type, target_id, from, to
+// (RFC 3339), offset and limit query parameters.
+func (h *Handler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAuditQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, total, err := h.auditService.List(r.Context(), q)
+	if err != nil {
+		logger.Log.ErrorContext(r.Context(), "Failed to list audit events", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	res := make([]map[string]interface{}, 0, len(events))
+	for _, e := range events {
+		res = append(res, audit.ToJSON(e))
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"events": res,
+		"total":  total,
+	})
+}
+
+// ExportAudit streams all matching events as CSV or JSON lines, selected
+// by the format query parameter (default csv).
+func (h *Handler) ExportAudit(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAuditQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	contentType := "text/csv"
+	switch format {
+	case "", audit.FormatCSV:
+		format = audit.FormatCSV
+	case audit.FormatJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		http.Error(w, audit.ErrInvalidFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filename := "audit-" + helpers.GetCurrentTimeStampUTC().Format("20060102T150405Z") + "." + format
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// Headers are already sent once rows start streaming, so a failure can
+	// only be logged.
+	if err := h.auditService.Export(r.Context(), q, format, w); err != nil {
+		logger.Log.ErrorContext(r.Context(), "Audit export failed", "error", err)
+	}
+}
+
+// target returns the acting admin and the user ID from the path.
+func (h *Handler) target(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	claims, ok := middleware.Claims(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return claims.UserID, userID, true
+}
+
+func parseAuditQuery(r *http.Request) (audit.Query, error) {
+	values := r.URL.Query()
+	q := audit.Query{
+		Action:     values.Get("action"),
+		TargetType: values.Get("target_type"),
+		TargetID:   values.Get("target_id"),
+	}
+
+	if v := values.Get("actor_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return q, errors.New("invalid actor_id")
+		}
+		q.ActorID = &id
+	}
+	for name, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := values.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return q, errors.New("invalid " + name + ": expected RFC 3339")
+			}
+			*dst = t
+		}
+	}
+	for name, dst := range map[string]*int{"offset": &q.Offset, "limit": &q.Limit} {
+		if v := values.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return q, errors.New("invalid " + name)
+			}
+			*dst = n
+		}
+	}
+	return q, nil
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, userrepo.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, user.ErrInvalidRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, user.ErrSelfAction):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		logger.Log.ErrorContext(r.Context(), "Admin request failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
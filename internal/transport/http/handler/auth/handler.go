@@ -2,12 +2,16 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/razedwell/go-hand/internal/platform/logger"
+	userrepo "github.com/razedwell/go-hand/internal/repository/user"
 	"github.com/razedwell/go-hand/internal/service/auth"
+	"github.com/razedwell/go-hand/internal/service/invitation"
 	"github.com/razedwell/go-hand/internal/service/user"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
 	"github.com/razedwell/go-hand/internal/transport/http/helpers"
 	"github.com/razedwell/go-hand/internal/transport/http/middleware"
 )
@@ -16,28 +20,24 @@ type Handler struct {
 	userService *user.Service
 	authService *auth.Service
 	authMW      func(http.Handler) http.Handler
+	cookies     *helpers.Cookies
 }
 
-func NewHandler(userService *user.Service, authService *auth.Service, authMW func(http.Handler) http.Handler) *Handler {
-	return &Handler{userService, authService, authMW}
+func NewHandler(userService *user.Service, authService *auth.Service, authMW func(http.Handler) http.Handler, cookies *helpers.Cookies) *Handler {
+	return &Handler{userService, authService, authMW, cookies}
 }
 
-func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /login", h.Login)
-	mux.HandleFunc("POST /register", h.Register)
-	mux.HandleFunc("POST /refresh", h.Refresh)
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	r.HandleFunc("POST /login", h.Login).Named("auth.login")
+	r.HandleFunc("POST /login/sms", h.LoginSecondFactor).Named("auth.login_sms")
+	r.HandleFunc("POST /register", h.Register).Named("auth.register")
 
-	// --- ADD THIS SECTION ---
-	// Serves the index.html file at the route /test
-	mux.HandleFunc("GET /test", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "internal/transport/http/index.html")
-	})
-	// ------------------------
-
-	protected := h.authMW
+	csrf := middleware.CSRF(h.cookies)
+	r.With(csrf).HandleFunc("POST /refresh", h.Refresh).Named("auth.refresh")
 
-	mux.Handle("GET /logout", protected(http.HandlerFunc(h.Logout)))
-	mux.Handle("GET /", protected(http.HandlerFunc(h.Home)))
+	protected := r.With(h.authMW)
+	protected.With(csrf).HandleFunc("GET /logout", h.Logout).Named("auth.logout")
+	protected.HandleFunc("GET /{$}", h.Home).Named("home")
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -49,56 +49,56 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	accessToken, refreshToken, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	var secondFactorErr *auth.SecondFactorRequiredError
+	if errors.As(err, &secondFactorErr) {
+		helpers.RespondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+			"message":   "Second factor required",
+			"challenge": secondFactorErr.Challenge,
+			"method":    secondFactorErr.Method,
+		})
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    refreshToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false, // Set to true in production with HTTPS
-		SameSite: http.SameSiteStrictMode,
-		MaxAge:   24 * 60 * 60,
-	})
-
-	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Login successful",
-		"token":   accessToken,
-	})
+	h.cookies.RespondWithTokens(w, "Login successful", accessToken, refreshToken)
 }
 
-func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
-	accessTokenVal := r.Context().Value(middleware.AcsKey)
-	accessToken, ok := accessTokenVal.(string)
-	if !ok {
-		http.Error(w, "Invalid access token", http.StatusUnauthorized)
-		logger.Log.Printf("Invalid access token in context: %v", accessTokenVal)
+func (h *Handler) LoginSecondFactor(w http.ResponseWriter, r *http.Request) {
+	var req auth.SecondFactorParams
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
-	cookie, err := r.Cookie("refresh_token")
 
+	accessToken, refreshToken, err := h.authService.CompleteSecondFactor(r.Context(), req)
 	if err != nil {
-		cookie = &http.Cookie{}
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
 	}
 
-	refreshToken := cookie.Value
+	h.cookies.RespondWithTokens(w, "Login successful", accessToken, refreshToken)
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := r.Context().Value(middleware.AcsKey).(string)
+	if !ok {
+		http.Error(w, "Invalid access token", http.StatusUnauthorized)
+		// Only the type: the value may be a credential.
+		logger.Log.ErrorContext(r.Context(), "Access token missing from context", "type", fmt.Sprintf("%T", r.Context().Value(middleware.AcsKey)))
+		return
+	}
+	refreshToken, _ := h.cookies.RefreshToken(r)
 
 	if err := h.authService.Logout(r.Context(), accessToken, refreshToken); err != nil {
 		http.Error(w, "Failed to logout", http.StatusInternalServerError)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    "",
-		Path:     "/auth/refresh", // Must match the path used in Login
-		HttpOnly: true,
-		MaxAge:   -1, // Tells browser to delete immediately
-		Expires:  time.Unix(0, 0),
-	})
+	h.cookies.ClearRefreshToken(w)
 
 	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "Logout successful",
@@ -113,7 +113,16 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.userService.RegisterUser(r.Context(), req); err != nil {
-		http.Error(w, "Failed to register user: "+err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, user.ErrRegistrationClosed):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, user.ErrInvitationMismatch), errors.Is(err, invitation.ErrInvalidInvitation):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, userrepo.ErrEmailTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to register user: "+err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -129,14 +138,12 @@ func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("refresh_token")
-	if err != nil {
+	refreshToken, ok := h.cookies.RefreshToken(r)
+	if !ok {
 		http.Error(w, "Refresh token not provided", http.StatusUnauthorized)
 		return
 	}
 
-	refreshToken := cookie.Value
-
 	newAccessToken, err := h.authService.RefreshAccessToken(r.Context(), refreshToken)
 	if err != nil {
 		http.Error(w, "Failed to refresh token: "+err.Error(), http.StatusUnauthorized)
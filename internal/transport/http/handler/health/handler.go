@@ -0,0 +1,39 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/razedwell/go-hand/internal/platform/health"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+// Handler serves the probes. /healthz only says the process is serving;
+// /readyz also checks the dependencies and fails during shutdown.
+type Handler struct {
+	checker *health.Checker
+}
+
+func NewHandler(checker *health.Checker) *Handler {
+	return &Handler{checker}
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	r.HandleFunc("GET /healthz", h.Live).Named("health.live")
+	r.HandleFunc("GET /readyz", h.Ready).Named("health.ready")
+}
+
+func (h *Handler) Live(w http.ResponseWriter, r *http.Request) {
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status": health.StatusUp,
+	})
+}
+
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Check(r.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	helpers.RespondWithJSON(w, status, report)
+}
@@ -0,0 +1,165 @@
+package invitation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/service/invitation"
+	"github.com/razedwell/go-hand/internal/tenant"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+	"github.com/razedwell/go-hand/internal/transport/http/middleware"
+)
+
+type Handler struct {
+	invitationService *invitation.Service
+	authMW            func(http.Handler) http.Handler
+	tenantMW          func(http.Handler) http.Handler
+}
+
+func NewHandler(invitationService *invitation.Service, authMW func(http.Handler) http.Handler, tenantMW func(http.Handler) http.Handler) *Handler {
+	return &Handler{invitationService, authMW, tenantMW}
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	protected := r.With(h.authMW)
+	scoped := r.With(h.authMW, h.tenantMW)
+
+	scoped.HandleFunc("POST /org/invitations", h.Create)
+	scoped.HandleFunc("GET /org/invitations", h.List)
+	scoped.HandleFunc("POST /org/invitations/{id}/resend", h.Resend)
+	scoped.HandleFunc("DELETE /org/invitations/{id}", h.Revoke)
+
+	protected.HandleFunc("POST /invitations/accept", h.Accept)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req invitation.CreateParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.invitationService.Create(r.Context(), req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, toJSON(inv))
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.invitationService.List(r.Context())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	res := make([]map[string]interface{}, 0, len(invitations))
+	for _, inv := range invitations {
+		res = append(res, toJSON(inv))
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"invitations": res,
+	})
+}
+
+func (h *Handler) Resend(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid invitation id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.invitationService.Resend(r.Context(), id); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Invitation resent",
+	})
+}
+
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid invitation id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.invitationService.Revoke(r.Context(), id); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Invitation revoked",
+	})
+}
+
+func (h *Handler) Accept(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.Claims(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req invitation.AcceptParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.invitationService.Accept(r.Context(), claims.UserID, req.Token)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Invitation accepted",
+		"org_id":  inv.OrgID,
+		"role":    inv.Role,
+	})
+}
+
+func toJSON(inv *model.Invitation) map[string]interface{} {
+	status := "pending"
+	switch {
+	case inv.AcceptedAt != nil:
+		status = "accepted"
+	case inv.RevokedAt != nil:
+		status = "revoked"
+	case !inv.Pending(helpers.GetCurrentTimeStampUTC()):
+		status = "expired"
+	}
+	return map[string]interface{}{
+		"id":         inv.ID,
+		"email":      inv.Email,
+		"role":       inv.Role,
+		"status":     status,
+		"expires_at": inv.ExpiresAt,
+		"sent_at":    inv.SentAt,
+		"created_at": inv.CreatedAt,
+	}
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, invitation.ErrInvalidInput), errors.Is(err, invitation.ErrInvalidInvitation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, invitation.ErrForbidden), errors.Is(err, invitation.ErrEmailMismatch), errors.Is(err, tenant.ErrNoTenant):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, invitation.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		logger.Log.ErrorContext(r.Context(), "Invitation request failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"github.com/razedwell/go-hand/internal/platform/metrics"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+)
+
+// Handler serves the Prometheus metrics. Mount it behind a token when the
+// port is reachable from outside the cluster.
+type Handler struct{}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	r.Handle("GET /metrics", metrics.Handler()).Named("metrics")
+}
@@ -0,0 +1,196 @@
+package organization
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	orgrepo "github.com/razedwell/go-hand/internal/repository/organization"
+	"github.com/razedwell/go-hand/internal/service/organization"
+	"github.com/razedwell/go-hand/internal/tenant"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+	"github.com/razedwell/go-hand/internal/transport/http/middleware"
+)
+
+type Handler struct {
+	orgService *organization.Service
+	authMW     func(http.Handler) http.Handler
+	tenantMW   func(http.Handler) http.Handler
+	cookies    *helpers.Cookies
+}
+
+func NewHandler(orgService *organization.Service, authMW func(http.Handler) http.Handler, tenantMW func(http.Handler) http.Handler, cookies *helpers.Cookies) *Handler {
+	return &Handler{orgService, authMW, tenantMW, cookies}
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	protected := r.With(h.authMW)
+	scoped := r.With(h.authMW, h.tenantMW)
+
+	protected.HandleFunc("POST /orgs", h.Create)
+	protected.HandleFunc("GET /orgs", h.List)
+	protected.HandleFunc("POST /orgs/switch", h.Switch)
+
+	scoped.HandleFunc("GET /org/members", h.ListMembers)
+	scoped.HandleFunc("PATCH /org/members/{userID}", h.UpdateMember)
+	scoped.HandleFunc("DELETE /org/members/{userID}", h.RemoveMember)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.Claims(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req organization.CreateParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.orgService.Create(r.Context(), claims.UserID, req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":   org.ID,
+		"name": org.Name,
+		"slug": org.Slug,
+	})
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.Claims(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	memberships, err := h.orgService.ListForUser(r.Context(), claims.UserID)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	orgs := make([]map[string]interface{}, 0, len(memberships))
+	for _, m := range memberships {
+		orgs = append(orgs, map[string]interface{}{
+			"id":     m.OrgID,
+			"name":   m.OrgName,
+			"role":   m.Role,
+			"active": m.OrgID == claims.OrgID,
+		})
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"organizations": orgs,
+	})
+}
+
+func (h *Handler) Switch(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.Claims(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req organization.SwitchParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	oldRefreshToken, _ := h.cookies.RefreshToken(r)
+
+	oldAccessToken, _ := r.Context().Value(middleware.AcsKey).(string)
+
+	accessToken, refreshToken, err := h.orgService.Switch(r.Context(), claims.UserID, req.OrgID, oldAccessToken, oldRefreshToken)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	h.cookies.RespondWithTokens(w, "Organization switched", accessToken, refreshToken)
+}
+
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := h.orgService.ListMembers(r.Context())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	res := make([]map[string]interface{}, 0, len(members))
+	for _, m := range members {
+		res = append(res, map[string]interface{}{
+			"user_id":   m.UserID,
+			"email":     m.UserEmail,
+			"role":      m.Role,
+			"joined_at": m.CreatedAt,
+		})
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"members": res,
+	})
+}
+
+func (h *Handler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("userID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req organization.UpdateMemberParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.orgService.UpdateMemberRole(r.Context(), userID, req.Role); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Member updated",
+	})
+}
+
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("userID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.orgService.RemoveMember(r.Context(), userID); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Member removed",
+	})
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, organization.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, orgrepo.ErrSlugTaken), errors.Is(err, organization.ErrLastOwner):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, organization.ErrForbidden), errors.Is(err, tenant.ErrNoTenant):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, organization.ErrNotMember):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		logger.Log.ErrorContext(r.Context(), "Organization request failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,107 @@
+package passwordless
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/service/auth"
+	"github.com/razedwell/go-hand/internal/service/passwordless"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+const bindingCookie = "magic_binding"
+
+type Handler struct {
+	passwordlessService *passwordless.Service
+	bindingTTL          time.Duration
+	cookies             *helpers.Cookies
+	bindingPath         string // full path of the verify route
+}
+
+func NewHandler(passwordlessService *passwordless.Service, bindingTTL time.Duration, cookies *helpers.Cookies) *Handler {
+	return &Handler{passwordlessService: passwordlessService, bindingTTL: bindingTTL, cookies: cookies}
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	r.HandleFunc("POST /login/magic", h.Start)
+	verify := r.HandleFunc("POST /login/magic/verify", h.Verify)
+	// The binding cookie is only needed by the verify route, wherever the
+	// handler is mounted.
+	h.bindingPath = verify.Pattern
+}
+
+func (h *Handler) Start(w http.ResponseWriter, r *http.Request) {
+	var req passwordless.StartParams
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	binding, err := h.passwordlessService.Start(r.Context(), req.Email, clientIP(r))
+	if errors.Is(err, passwordless.ErrRateLimited) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		logger.Log.ErrorContext(r.Context(), "Failed to start magic link login", "error", err)
+		http.Error(w, "Failed to send login link", http.StatusInternalServerError)
+		return
+	}
+
+	h.cookies.SetFlowCookie(w, bindingCookie, binding, h.bindingPath, http.SameSiteStrictMode, h.bindingTTL)
+
+	helpers.RespondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "If the account exists, a login link has been sent",
+	})
+}
+
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	var req passwordless.VerifyParams
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	binding, _ := h.cookies.FlowCookie(r, bindingCookie)
+
+	accessToken, refreshToken, err := h.passwordlessService.Verify(r.Context(), req, binding)
+	var secondFactorErr *auth.SecondFactorRequiredError
+	if errors.As(err, &secondFactorErr) {
+		// The code is spent either way; the login continues at /login/sms.
+		h.clearBinding(w)
+		helpers.RespondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+			"message":   "Second factor required",
+			"challenge": secondFactorErr.Challenge,
+			"method":    secondFactorErr.Method,
+		})
+		return
+	}
+	if err != nil {
+		logger.Log.ErrorContext(r.Context(), "Magic link verification failed", "error", err)
+		http.Error(w, "Invalid or expired code", http.StatusUnauthorized)
+		return
+	}
+
+	h.clearBinding(w)
+	h.cookies.RespondWithTokens(w, "Login successful", accessToken, refreshToken)
+}
+
+func (h *Handler) clearBinding(w http.ResponseWriter) {
+	h.cookies.ClearFlowCookie(w, bindingCookie, h.bindingPath, http.SameSiteStrictMode)
+}
+
+// clientIP is the peer address; proxy headers are not trusted.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -0,0 +1,197 @@
+package passwordless
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/memory"
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/mail"
+	"github.com/razedwell/go-hand/internal/repository/verification"
+	"github.com/razedwell/go-hand/internal/service/passwordless"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+// codeStore is a verification.Repository for the magic link codes.
+type codeStore struct {
+	verification.Repository
+	codes []*model.VerificationCode
+}
+
+func (s *codeStore) CreateVerificationCode(ctx context.Context, code *model.VerificationCode) error {
+	code.ID = int64(len(s.codes) + 1)
+	s.codes = append(s.codes, code)
+	return nil
+}
+
+func (s *codeStore) GetVerificationCode(ctx context.Context, codeHash string, codeType model.VerificationType) (*model.VerificationCode, error) {
+	for _, c := range s.codes {
+		if c.CodeHash == codeHash && c.Type == codeType && c.UsedAt == nil {
+			return c, nil
+		}
+	}
+	return nil, verification.ErrNotFound
+}
+
+func (s *codeStore) MarkVerificationCodeUsed(ctx context.Context, id int64) error {
+	c := s.codes[id-1]
+	if c.UsedAt != nil {
+		return verification.ErrNotFound
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	return nil
+}
+
+func (s *codeStore) InvalidateUserCodes(ctx context.Context, userID int64, codeTypes ...model.VerificationType) error {
+	now := time.Now()
+	for _, c := range s.codes {
+		if c.UserID == userID && c.UsedAt == nil {
+			c.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// mailbox keeps the messages it is asked to send.
+type mailbox struct {
+	sent []mail.Message
+}
+
+func (m *mailbox) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// issuer logs everyone in without a second factor.
+type issuer struct{}
+
+func (issuer) IssueTokens(ctx context.Context, u *model.User, method string) (string, string, error) {
+	return "access-" + strconv.FormatInt(u.ID, 10), "", nil
+}
+
+// unlimited lets every request through.
+type unlimited struct{}
+
+func (unlimited) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	return true, nil
+}
+
+var linkToken = regexp.MustCompile(`token=(\S+)`)
+
+func post(t *testing.T, client *http.Client, target string, body any) *http.Response {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Post(target, "application/json", strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func newClient(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Jar: jar}
+}
+
+// TestMagicLinkThroughRouter mounts the handler the way main does, so the
+// binding cookie has to find its way back to the verify route.
+func TestMagicLinkThroughRouter(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepo(nil)
+	u := &model.User{Email: "ada@example.com", Role: model.RoleUser, IsActive: true}
+	if err := users.CreateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	mailer := &mailbox{}
+	svc := passwordless.NewService(users, &codeStore{}, issuer{}, mailer, unlimited{}, passwordless.Config{
+		LinkURL:     "https://app.example.com/magic",
+		Expiry:      15 * time.Minute,
+		MaxAttempts: 5,
+	})
+	cookies, err := helpers.NewCookies(helpers.CookieConfig{Name: "refresh_token", CSRFSecret: "csrf-secret-for-tests"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(transporthttp.NewRouter(transporthttp.Mount("/v1", nil, NewHandler(svc, 15*time.Minute, cookies))))
+	t.Cleanup(srv.Close)
+
+	browser := newClient(t)
+	if resp := post(t, browser, srv.URL+"/v1/login/magic", passwordless.StartParams{Email: u.Email}); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("start: status %d", resp.StatusCode)
+	}
+	if len(mailer.sent) != 1 {
+		t.Fatalf("expected one email, got %d", len(mailer.sent))
+	}
+	match := linkToken.FindStringSubmatch(mailer.sent[0].Body)
+	if match == nil {
+		t.Fatalf("no link in %q", mailer.sent[0].Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A forwarded link is opened in a browser without the binding cookie.
+	if resp := post(t, newClient(t), srv.URL+"/v1/login/magic/verify", passwordless.VerifyParams{Token: token}); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("verify without binding: status %d", resp.StatusCode)
+	}
+
+	resp := post(t, browser, srv.URL+"/v1/login/magic/verify", passwordless.VerifyParams{Token: token})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("verify: status %d", resp.StatusCode)
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if want := "access-" + strconv.FormatInt(u.ID, 10); body.Token != want {
+		t.Fatalf("got token %q, want %q", body.Token, want)
+	}
+}
+
+// exhausted refuses every request.
+type exhausted struct{}
+
+func (exhausted) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	return false, nil
+}
+
+func TestStartRateLimited(t *testing.T) {
+	mailer := &mailbox{}
+	svc := passwordless.NewService(memory.NewUserRepo(nil), &codeStore{}, issuer{}, mailer, exhausted{}, passwordless.Config{Expiry: time.Minute})
+	cookies, err := helpers.NewCookies(helpers.CookieConfig{Name: "refresh_token", CSRFSecret: "csrf-secret-for-tests"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(transporthttp.NewRouter(NewHandler(svc, time.Minute, cookies)))
+	t.Cleanup(srv.Close)
+
+	resp := post(t, newClient(t), srv.URL+"/login/magic", passwordless.StartParams{Email: "ada@example.com"})
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if len(resp.Cookies()) != 0 || len(mailer.sent) != 0 {
+		t.Fatal("rate-limited request set a binding or sent mail")
+	}
+}
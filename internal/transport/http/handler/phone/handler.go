@@ -0,0 +1,88 @@
+package phone
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/platform/sms"
+	"github.com/razedwell/go-hand/internal/service/phone"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+	"github.com/razedwell/go-hand/internal/transport/http/middleware"
+)
+
+type Handler struct {
+	phoneService *phone.Service
+	authMW       func(http.Handler) http.Handler
+}
+
+func NewHandler(phoneService *phone.Service, authMW func(http.Handler) http.Handler) *Handler {
+	return &Handler{phoneService, authMW}
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	protected := r.With(h.authMW)
+
+	protected.HandleFunc("POST /me/phone/verify", h.StartVerification)
+	protected.HandleFunc("POST /me/phone/verify/confirm", h.ConfirmVerification)
+}
+
+func (h *Handler) StartVerification(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.Claims(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req phone.StartParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.phoneService.StartVerification(r.Context(), claims.UserID, req.Phone); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "Verification code sent",
+	})
+}
+
+func (h *Handler) ConfirmVerification(w http.ResponseWriter, r *http.Request) {
+	claims, ok := middleware.Claims(r.Context())
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req phone.ConfirmParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.phoneService.ConfirmVerification(r.Context(), claims.UserID, req); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Phone number verified",
+	})
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, sms.ErrInvalidPhone), errors.Is(err, phone.ErrInvalidCode):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, phone.ErrRateLimited):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		logger.Log.ErrorContext(r.Context(), "Phone verification failed", "error", err)
+		http.Error(w, "Failed to verify phone", http.StatusInternalServerError)
+	}
+}
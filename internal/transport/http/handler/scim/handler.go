@@ -0,0 +1,282 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/service/scim"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+)
+
+type Handler struct {
+	scimService *scim.Service
+	authMW      func(http.Handler) http.Handler
+}
+
+func NewHandler(scimService *scim.Service, authMW func(http.Handler) http.Handler) *Handler {
+	return &Handler{scimService, authMW}
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	protected := r.With(h.authMW)
+
+	protected.HandleFunc("GET /ServiceProviderConfig", h.ServiceProviderConfig)
+	protected.HandleFunc("GET /ResourceTypes", h.ResourceTypes)
+	protected.HandleFunc("GET /ResourceTypes/{id}", h.ResourceType)
+	protected.HandleFunc("GET /Schemas", h.Schemas)
+	protected.HandleFunc("GET /Schemas/{id}", h.Schema)
+
+	protected.HandleFunc("GET /Users", h.ListUsers)
+	protected.HandleFunc("POST /Users", h.CreateUser)
+	protected.HandleFunc("GET /Users/{id}", h.GetUser)
+	protected.HandleFunc("PUT /Users/{id}", h.ReplaceUser)
+	protected.HandleFunc("PATCH /Users/{id}", h.PatchUser)
+	protected.HandleFunc("DELETE /Users/{id}", h.DeleteUser)
+
+	protected.HandleFunc("GET /Groups", h.ListGroups)
+	protected.HandleFunc("POST /Groups", h.CreateGroup)
+	protected.HandleFunc("GET /Groups/{id}", h.GetGroup)
+	protected.HandleFunc("PUT /Groups/{id}", h.ReplaceGroup)
+	protected.HandleFunc("PATCH /Groups/{id}", h.PatchGroup)
+	protected.HandleFunc("DELETE /Groups/{id}", h.DeleteGroup)
+}
+
+func (h *Handler) ServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, h.scimService.ServiceProviderConfig())
+}
+
+func (h *Handler) ResourceTypes(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, listOf(h.scimService.ResourceTypes()))
+}
+
+func (h *Handler) ResourceType(w http.ResponseWriter, r *http.Request) {
+	rt := h.scimService.ResourceType(r.PathValue("id"))
+	if rt == nil {
+		respondError(w, r, scim.ErrNotFound)
+		return
+	}
+	respond(w, http.StatusOK, rt)
+}
+
+func (h *Handler) Schemas(w http.ResponseWriter, r *http.Request) {
+	respond(w, http.StatusOK, listOf(h.scimService.Schemas()))
+}
+
+func (h *Handler) Schema(w http.ResponseWriter, r *http.Request) {
+	schema := h.scimService.Schema(r.PathValue("id"))
+	if schema == nil {
+		respondError(w, r, scim.ErrNotFound)
+		return
+	}
+	respond(w, http.StatusOK, schema)
+}
+
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	startIndex, count := pagination(r)
+	res, err := h.scimService.ListUsers(r.Context(), r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respond(w, http.StatusOK, res)
+}
+
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	res, err := h.scimService.GetUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respond(w, http.StatusOK, res)
+}
+
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req scim.User
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, scim.ErrInvalidValue)
+		return
+	}
+	res, err := h.scimService.CreateUser(r.Context(), &req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", res.Meta.Location)
+	respond(w, http.StatusCreated, res)
+}
+
+func (h *Handler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	var req scim.User
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, scim.ErrInvalidValue)
+		return
+	}
+	res, err := h.scimService.ReplaceUser(r.Context(), r.PathValue("id"), &req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respond(w, http.StatusOK, res)
+}
+
+func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	var req scim.PatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, scim.ErrInvalidValue)
+		return
+	}
+	res, err := h.scimService.PatchUser(r.Context(), r.PathValue("id"), &req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respond(w, http.StatusOK, res)
+}
+
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.scimService.DeleteUser(r.Context(), r.PathValue("id")); err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	startIndex, count := pagination(r)
+	res, err := h.scimService.ListGroups(r.Context(), r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respond(w, http.StatusOK, res)
+}
+
+func (h *Handler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	res, err := h.scimService.GetGroup(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respond(w, http.StatusOK, res)
+}
+
+func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req scim.Group
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, scim.ErrInvalidValue)
+		return
+	}
+	res, err := h.scimService.CreateGroup(r.Context(), &req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", res.Meta.Location)
+	respond(w, http.StatusCreated, res)
+}
+
+func (h *Handler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	var req scim.Group
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, scim.ErrInvalidValue)
+		return
+	}
+	res, err := h.scimService.ReplaceGroup(r.Context(), r.PathValue("id"), &req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respond(w, http.StatusOK, res)
+}
+
+func (h *Handler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	var req scim.PatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, scim.ErrInvalidValue)
+		return
+	}
+	res, err := h.scimService.PatchGroup(r.Context(), r.PathValue("id"), &req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respond(w, http.StatusOK, res)
+}
+
+func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.scimService.DeleteGroup(r.Context(), r.PathValue("id")); err != nil {
+		respondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pagination reads startIndex and count. A missing count is reported as -1
+// so the service applies its default page size.
+func pagination(r *http.Request) (int, int) {
+	q := r.URL.Query()
+	startIndex, err := strconv.Atoi(q.Get("startIndex"))
+	if err != nil {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(q.Get("count"))
+	if err != nil {
+		count = -1
+	}
+	return startIndex, count
+}
+
+func listOf[T any](items []T) *scim.ListResponse {
+	resources := make([]any, len(items))
+	for i, item := range items {
+		resources[i] = item
+	}
+	return &scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(items),
+		StartIndex:   1,
+		ItemsPerPage: len(items),
+		Resources:    resources,
+	}
+}
+
+func respond(w http.ResponseWriter, statusCode int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// respondError writes an RFC 7644 error response.
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	status, scimType := http.StatusInternalServerError, ""
+	detail := err.Error()
+
+	switch {
+	case errors.Is(err, scim.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, scim.ErrConflict):
+		status, scimType = http.StatusConflict, "uniqueness"
+	case errors.Is(err, scim.ErrInvalidFilter):
+		status, scimType = http.StatusBadRequest, "invalidFilter"
+	case errors.Is(err, scim.ErrInvalidPath):
+		status, scimType = http.StatusBadRequest, "invalidPath"
+	case errors.Is(err, scim.ErrInvalidValue):
+		status, scimType = http.StatusBadRequest, "invalidValue"
+	default:
+		logger.Log.ErrorContext(r.Context(), "SCIM request failed", "error", err)
+		detail = "internal error"
+	}
+
+	body := map[string]interface{}{
+		"schemas": []string{scim.SchemaError},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	respond(w, status, body)
+}
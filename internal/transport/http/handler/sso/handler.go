@@ -0,0 +1,94 @@
+package sso
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/service/auth"
+	"github.com/razedwell/go-hand/internal/service/sso"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+const requestIDCookie = "saml_request_id"
+
+type Handler struct {
+	ssoService *sso.Service
+	cookies    *helpers.Cookies
+}
+
+func NewHandler(ssoService *sso.Service, cookies *helpers.Cookies) *Handler {
+	return &Handler{ssoService, cookies}
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	r.HandleFunc("GET /metadata", h.Metadata)
+	r.HandleFunc("GET /login", h.Login)
+	r.HandleFunc("POST /acs", h.ACS)
+}
+
+func (h *Handler) Metadata(w http.ResponseWriter, r *http.Request) {
+	metadata, err := h.ssoService.Metadata()
+	if err != nil {
+		http.Error(w, "Failed to build metadata", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write(metadata)
+}
+
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	redirectURL, requestID, err := h.ssoService.LoginURL(r.URL.Query().Get("relay_state"))
+	if err != nil {
+		logger.Log.ErrorContext(r.Context(), "Failed to create saml authn request", "error", err)
+		http.Error(w, "Failed to start SSO login", http.StatusInternalServerError)
+		return
+	}
+
+	// The IdP posts back cross-site, so the cookie must be SameSite=None.
+	h.cookies.SetFlowCookie(w, requestIDCookie, requestID, "/saml/acs", http.SameSiteNoneMode, 5*time.Minute)
+
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+func (h *Handler) ACS(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	samlResponse, err := base64.StdEncoding.DecodeString(r.PostForm.Get("SAMLResponse"))
+	if err != nil || len(samlResponse) == 0 {
+		http.Error(w, "Invalid SAML response", http.StatusBadRequest)
+		return
+	}
+
+	var requestIDs []string
+	if requestID, ok := h.cookies.FlowCookie(r, requestIDCookie); ok {
+		requestIDs = append(requestIDs, requestID)
+	}
+
+	accessToken, refreshToken, err := h.ssoService.ConsumeAssertion(r.Context(), samlResponse, requestIDs)
+	var secondFactorErr *auth.SecondFactorRequiredError
+	if errors.As(err, &secondFactorErr) {
+		helpers.RespondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+			"message":   "Second factor required",
+			"challenge": secondFactorErr.Challenge,
+			"method":    secondFactorErr.Method,
+		})
+		return
+	}
+	if err != nil {
+		logger.Log.ErrorContext(r.Context(), "SAML login failed", "error", err)
+		// The cause can be a validation or database error; keep it in the log.
+		http.Error(w, "SAML login failed", http.StatusUnauthorized)
+		return
+	}
+
+	h.cookies.ClearFlowCookie(w, requestIDCookie, "/saml/acs", http.SameSiteNoneMode)
+
+	h.cookies.RespondWithTokens(w, "Login successful", accessToken, refreshToken)
+}
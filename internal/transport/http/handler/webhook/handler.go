@@ -0,0 +1,222 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/razedwell/go-hand/internal/model"
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	webhookrepo "github.com/razedwell/go-hand/internal/repository/webhook"
+	"github.com/razedwell/go-hand/internal/service/webhook"
+	transporthttp "github.com/razedwell/go-hand/internal/transport/http"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+type Handler struct {
+	webhookService *webhook.Service
+}
+
+func NewHandler(webhookService *webhook.Service) *Handler {
+	return &Handler{webhookService}
+}
+
+func (h *Handler) RegisterRoutes(r *transporthttp.Router) {
+	r.HandleFunc("POST /webhooks", h.Create)
+	r.HandleFunc("GET /webhooks", h.List)
+	r.HandleFunc("GET /webhooks/{id}", h.Get)
+	r.HandleFunc("PATCH /webhooks/{id}", h.Update)
+	r.HandleFunc("DELETE /webhooks/{id}", h.Delete)
+	r.HandleFunc("POST /webhooks/{id}/rotate-secret", h.RotateSecret)
+
+	r.HandleFunc("GET /webhook-deliveries", h.ListDeliveries)
+	r.HandleFunc("POST /webhook-deliveries/{id}/redeliver", h.Redeliver)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var req webhook.CreateParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.webhookService.Create(r.Context(), req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, subscriptionJSON(sub, true))
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookService.List(r.Context())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	res := make([]map[string]interface{}, 0, len(subs))
+	for _, sub := range subs {
+		res = append(res, subscriptionJSON(sub, false))
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"webhooks": res,
+	})
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookService.Get(r.Context(), id)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, subscriptionJSON(sub, false))
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var req webhook.UpdateParams
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.webhookService.Update(r.Context(), id, req)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, subscriptionJSON(sub, false))
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Delete(r.Context(), id); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Webhook deleted",
+	})
+}
+
+func (h *Handler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookService.RotateSecret(r.Context(), id)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, subscriptionJSON(sub, true))
+}
+
+// ListDeliveries accepts status, offset and limit query parameters;
+// status=dead is the dead-letter view.
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	offset, _ := strconv.Atoi(values.Get("offset"))
+	limit, _ := strconv.Atoi(values.Get("limit"))
+
+	deliveries, total, err := h.webhookService.ListDeliveries(r.Context(), model.DeliveryStatus(values.Get("status")), max(offset, 0), limit)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	res := make([]map[string]interface{}, 0, len(deliveries))
+	for _, d := range deliveries {
+		res = append(res, map[string]interface{}{
+			"id":               d.ID,
+			"webhook_id":       d.SubscriptionID,
+			"event_id":         d.EventID,
+			"event_type":       d.EventType,
+			"payload":          json.RawMessage(d.Payload),
+			"status":           d.Status,
+			"attempts":         d.Attempts,
+			"next_attempt_at":  d.NextAttemptAt,
+			"last_error":       d.LastError,
+			"last_status_code": d.LastStatusCode,
+			"delivered_at":     d.DeliveredAt,
+			"created_at":       d.CreatedAt,
+		})
+	}
+	helpers.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"deliveries": res,
+		"total":      total,
+	})
+}
+
+func (h *Handler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Redeliver(r.Context(), id); err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "Delivery queued",
+	})
+}
+
+func subscriptionJSON(sub *model.WebhookSubscription, withSecret bool) map[string]interface{} {
+	res := map[string]interface{}{
+		"id":         sub.ID,
+		"url":        sub.URL,
+		"events":     sub.Events,
+		"is_active":  sub.IsActive,
+		"created_at": sub.CreatedAt,
+		"updated_at": sub.UpdatedAt,
+	}
+	if withSecret {
+		res["secret"] = sub.Secret
+	}
+	return res
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, webhookrepo.ErrNotFound), errors.Is(err, webhookrepo.ErrDeliveryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		logger.Log.ErrorContext(r.Context(), "Webhook request failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,175 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// CSRFHeader carries the CSRF token on requests authenticated by the
+// refresh token cookie.
+const CSRFHeader = "X-CSRF-Token"
+
+// hostPrefix makes browsers reject the cookie unless it is Secure, has
+// Path=/ and no Domain, so a sibling subdomain can't plant or shadow it.
+const hostPrefix = "__Host-"
+
+type CookieConfig struct {
+	Name       string // refresh token cookie; the CSRF cookie is Name+"_csrf"
+	Domain     string
+	Path       string
+	Secure     bool
+	HostPrefix bool // add the __Host- prefix to both names
+	MaxAge     time.Duration
+	CSRFSecret string
+}
+
+// Cookies issues, reads and clears the refresh token cookie and its CSRF
+// companion. All handlers go through it so the attributes always match.
+//
+// The CSRF token is an HMAC of the refresh token, readable by scripts on the
+// page, which must echo it in the X-CSRF-Token header. A cross-site request
+// gets the cookie attached but can't read the token, and a planted CSRF
+// cookie is useless without the secret.
+type Cookies struct {
+	cfg    CookieConfig
+	name   string
+	csrf   string
+	secret []byte
+}
+
+func NewCookies(cfg CookieConfig) (*Cookies, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("cookie name is required")
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.CSRFSecret == "" {
+		return nil, errors.New("csrf secret is required")
+	}
+
+	name, csrf := cfg.Name, cfg.Name+"_csrf"
+	if cfg.HostPrefix {
+		if !cfg.Secure || cfg.Domain != "" || cfg.Path != "/" {
+			return nil, errors.New("__Host- cookies must be Secure, with Path=/ and no Domain")
+		}
+		name, csrf = hostPrefix+name, hostPrefix+csrf
+	}
+	return &Cookies{cfg: cfg, name: name, csrf: csrf, secret: []byte(cfg.CSRFSecret)}, nil
+}
+
+// SetRefreshToken stores token in the refresh cookie and returns the CSRF
+// token that goes with it, which is also set as a script-readable cookie.
+func (c *Cookies) SetRefreshToken(w http.ResponseWriter, token string) string {
+	csrfToken := c.csrfToken(token)
+	http.SetCookie(w, c.cookie(c.name, token, true, int(c.cfg.MaxAge.Seconds())))
+	http.SetCookie(w, c.cookie(c.csrf, csrfToken, false, int(c.cfg.MaxAge.Seconds())))
+	return csrfToken
+}
+
+// RespondWithTokens sets the refresh cookie, if there is a refresh token,
+// and writes the access token and the CSRF token as JSON.
+func (c *Cookies) RespondWithTokens(w http.ResponseWriter, message string, accessToken string, refreshToken string) {
+	body := map[string]interface{}{
+		"message": message,
+		"token":   accessToken,
+	}
+	// Session mode issues no refresh token.
+	if refreshToken != "" {
+		body["csrf_token"] = c.SetRefreshToken(w, refreshToken)
+	}
+	RespondWithJSON(w, http.StatusOK, body)
+}
+
+// ClearRefreshToken deletes both cookies. The attributes must match the
+// ones they were set with or the browser keeps them.
+func (c *Cookies) ClearRefreshToken(w http.ResponseWriter) {
+	http.SetCookie(w, c.cookie(c.name, "", true, -1))
+	http.SetCookie(w, c.cookie(c.csrf, "", false, -1))
+}
+
+// RefreshToken returns the refresh token sent by the browser, if any.
+func (c *Cookies) RefreshToken(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(c.name)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// ValidCSRF reports whether the request echoes the CSRF token issued with
+// its refresh cookie.
+func (c *Cookies) ValidCSRF(r *http.Request) bool {
+	token, ok := c.RefreshToken(r)
+	if !ok {
+		return false
+	}
+	presented := r.Header.Get(CSRFHeader)
+	return presented != "" && hmac.Equal([]byte(presented), []byte(c.csrfToken(token)))
+}
+
+// SetFlowCookie sets a short-lived HttpOnly cookie that carries state
+// between the steps of a login, e.g. the magic link binding. It is Secure
+// whenever the refresh cookie is, or when sameSite is None, which browsers
+// require.
+func (c *Cookies) SetFlowCookie(w http.ResponseWriter, name string, value string, path string, sameSite http.SameSite, maxAge time.Duration) {
+	http.SetCookie(w, c.flowCookie(name, value, path, sameSite, int(maxAge.Seconds())))
+}
+
+// ClearFlowCookie deletes a cookie set by SetFlowCookie with the same
+// name, path and SameSite mode.
+func (c *Cookies) ClearFlowCookie(w http.ResponseWriter, name string, path string, sameSite http.SameSite) {
+	http.SetCookie(w, c.flowCookie(name, "", path, sameSite, -1))
+}
+
+// FlowCookie returns the value of a cookie set by SetFlowCookie, if any.
+func (c *Cookies) FlowCookie(r *http.Request, name string) (string, bool) {
+	cookie, err := r.Cookie(name)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+func (c *Cookies) flowCookie(name string, value string, path string, sameSite http.SameSite, maxAge int) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		HttpOnly: true,
+		Secure:   c.cfg.Secure || sameSite == http.SameSiteNoneMode,
+		SameSite: sameSite,
+		MaxAge:   maxAge,
+	}
+	if maxAge < 0 {
+		cookie.Expires = time.Unix(0, 0)
+	}
+	return cookie
+}
+
+func (c *Cookies) csrfToken(refreshToken string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(refreshToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Cookies) cookie(name string, value string, httpOnly bool, maxAge int) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   c.cfg.Domain,
+		Path:     c.cfg.Path,
+		HttpOnly: httpOnly,
+		Secure:   c.cfg.Secure,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   maxAge,
+	}
+	if maxAge < 0 {
+		cookie.Expires = time.Unix(0, 0)
+	}
+	return cookie
+}
@@ -0,0 +1,153 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "csrf-secret-for-tests"
+
+func newCookies(t *testing.T, cfg CookieConfig) *Cookies {
+	t.Helper()
+	if cfg.Name == "" {
+		cfg.Name = "refresh_token"
+	}
+	cfg.CSRFSecret = testSecret
+	c, err := NewCookies(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// issued returns the cookies SetRefreshToken sets, by name.
+func issued(c *Cookies, token string) (map[string]*http.Cookie, string) {
+	rec := httptest.NewRecorder()
+	csrf := c.SetRefreshToken(rec, token)
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range rec.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	return cookies, csrf
+}
+
+func TestHostPrefix(t *testing.T) {
+	c := newCookies(t, CookieConfig{Secure: true, HostPrefix: true, MaxAge: time.Hour})
+	cookies, _ := issued(c, "refresh")
+
+	for _, name := range []string{"__Host-refresh_token", "__Host-refresh_token_csrf"} {
+		cookie, ok := cookies[name]
+		if !ok {
+			t.Fatalf("no %s cookie in %v", name, cookies)
+		}
+		if cookie.Path != "/" || cookie.Domain != "" || !cookie.Secure {
+			t.Errorf("%s: path %q, domain %q, secure %v", name, cookie.Path, cookie.Domain, cookie.Secure)
+		}
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("expected 2 cookies, got %v", cookies)
+	}
+	if !cookies["__Host-refresh_token"].HttpOnly || cookies["__Host-refresh_token_csrf"].HttpOnly {
+		t.Error("only the refresh cookie should be HttpOnly")
+	}
+
+	// The prefixed name is the one read back.
+	r := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	r.AddCookie(&http.Cookie{Name: "refresh_token", Value: "planted"})
+	if _, ok := c.RefreshToken(r); ok {
+		t.Error("unprefixed cookie accepted")
+	}
+	r.AddCookie(&http.Cookie{Name: "__Host-refresh_token", Value: "refresh"})
+	if token, _ := c.RefreshToken(r); token != "refresh" {
+		t.Errorf("got %q", token)
+	}
+}
+
+func TestHostPrefixRequiresHostOnlyCookie(t *testing.T) {
+	for _, cfg := range []CookieConfig{
+		{HostPrefix: true},
+		{HostPrefix: true, Secure: true, Domain: "example.com"},
+		{HostPrefix: true, Secure: true, Path: "/v1"},
+	} {
+		cfg.Name, cfg.CSRFSecret = "refresh_token", testSecret
+		if _, err := NewCookies(cfg); err == nil {
+			t.Errorf("accepted %+v", cfg)
+		}
+	}
+}
+
+func TestValidCSRF(t *testing.T) {
+	c := newCookies(t, CookieConfig{})
+	_, csrf := issued(c, "refresh")
+	other := newCookies(t, CookieConfig{})
+	other.secret = []byte("another-secret")
+	_, forged := issued(other, "refresh")
+
+	request := func(refresh, header string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+		if refresh != "" {
+			r.AddCookie(&http.Cookie{Name: "refresh_token", Value: refresh})
+		}
+		if header != "" {
+			r.Header.Set(CSRFHeader, header)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		refresh string
+		header  string
+		want    bool
+	}{
+		{"matching token", "refresh", csrf, true},
+		{"no header", "refresh", "", false},
+		{"no refresh cookie", "", csrf, false},
+		{"token for another refresh token", "rotated", csrf, false},
+		{"token signed with another secret", "refresh", forged, false},
+		{"truncated token", "refresh", csrf[:len(csrf)-1], false},
+	}
+	for _, tt := range tests {
+		if got := c.ValidCSRF(request(tt.refresh, tt.header)); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// The CSRF cookie itself proves nothing; only the header counts.
+	r := request("refresh", "")
+	r.AddCookie(&http.Cookie{Name: "refresh_token_csrf", Value: csrf})
+	if c.ValidCSRF(r) {
+		t.Error("cookie accepted in place of the header")
+	}
+}
+
+func TestFlowCookieSecure(t *testing.T) {
+	tests := []struct {
+		secure   bool
+		sameSite http.SameSite
+		want     bool
+	}{
+		{false, http.SameSiteStrictMode, false},
+		{true, http.SameSiteStrictMode, true},
+		{false, http.SameSiteNoneMode, true},
+		{true, http.SameSiteNoneMode, true},
+	}
+	for _, tt := range tests {
+		c := newCookies(t, CookieConfig{Secure: tt.secure})
+		rec := httptest.NewRecorder()
+		c.SetFlowCookie(rec, "flow", "v", "/v1/flow", tt.sameSite, time.Minute)
+		cookie := rec.Result().Cookies()[0]
+		if cookie.Secure != tt.want || !cookie.HttpOnly || cookie.Path != "/v1/flow" {
+			t.Errorf("secure %v, SameSite %v: got %s", tt.secure, tt.sameSite, rec.Header().Get("Set-Cookie"))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	newCookies(t, CookieConfig{}).ClearFlowCookie(rec, "flow", "/v1/flow", http.SameSiteStrictMode)
+	if header := rec.Header().Get("Set-Cookie"); !strings.Contains(header, "Max-Age=0") || !strings.Contains(header, "Path=/v1/flow") {
+		t.Errorf("clear: %s", header)
+	}
+}
@@ -2,17 +2,22 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/razedwell/go-hand/internal/platform/logger"
 	"github.com/razedwell/go-hand/internal/security"
 )
 
 type ctxKey string
 
-const AcsKey ctxKey = "access_token"
+const (
+	AcsKey    ctxKey = "access_token"
+	ClaimsKey ctxKey = "claims"
+)
 
-func Auth(jwt *security.JWTManager) func(http.Handler) http.Handler {
+func Auth(tokens security.TokenManager) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			h := r.Header.Get("Authorization")
@@ -22,18 +27,26 @@ func Auth(jwt *security.JWTManager) func(http.Handler) http.Handler {
 			}
 			tokenStr := strings.TrimPrefix(h, "Bearer ")
 
-			if jwt.IsBlacklisted(r.Context(), tokenStr) {
+			claims, err := tokens.Authenticate(r.Context(), tokenStr)
+			if errors.Is(err, security.ErrTokenRevoked) {
 				http.Error(w, "token revoked", http.StatusUnauthorized)
 				return
 			}
-
-			_, err := jwt.Verify(tokenStr)
 			if err != nil {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
-			ctx := context.WithValue(r.Context(), AcsKey, tokenStr)
+			logUser(r.Context(), claims.UserID)
+			ctx := logger.With(r.Context(), "user_id", claims.UserID)
+			ctx = context.WithValue(ctx, AcsKey, tokenStr)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// Claims returns the verified access token claims stored by Auth.
+func Claims(ctx context.Context) (*security.JWTClaims, bool) {
+	claims, ok := ctx.Value(ClaimsKey).(*security.JWTClaims)
+	return claims, ok
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import "net/http"
+
+// MaxBodySize caps request bodies at n bytes. Reading past the limit fails,
+// so JSON decoding of an oversized body returns an error to the handler.
+func MaxBodySize(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	var readErr error
+	handler := MaxBodySize(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	tests := []struct {
+		name    string
+		body    string
+		chunked bool // no Content-Length, so only reading can tell
+		status  int
+		tooBig  bool
+	}{
+		{"under the limit", "1234567", false, http.StatusOK, false},
+		{"at the limit", "12345678", false, http.StatusOK, false},
+		{"declared too large", "123456789", false, http.StatusRequestEntityTooLarge, false},
+		{"chunked under the limit", "12345678", true, http.StatusOK, false},
+		{"chunked too large", "123456789", true, http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		readErr = nil
+		r := httptest.NewRequest(http.MethodPost, "/v1/login", strings.NewReader(tt.body))
+		if tt.chunked {
+			r.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+		var maxErr *http.MaxBytesError
+		if got := errors.As(readErr, &maxErr); got != tt.tooBig {
+			t.Errorf("%s: read error %v", tt.name, readErr)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package middleware
+
+import "net/http"
+
+// Chain composes middlewares into one; the first is the outermost.
+func Chain(middlewares ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
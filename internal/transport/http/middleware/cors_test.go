@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	handler := CORS(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	type want struct {
+		status      int
+		allowOrigin string // empty: no CORS headers at all
+		preflight   bool
+	}
+	tests := []struct {
+		name    string
+		method  string
+		origin  string
+		headers map[string]string
+		want    want
+	}{
+		{"same-origin request", http.MethodGet, "", nil, want{status: http.StatusTeapot}},
+		{"exact origin", http.MethodGet, "https://app.example.com", nil, want{http.StatusTeapot, "https://app.example.com", false}},
+		{"exact origin, other case", http.MethodGet, "https://APP.example.com", nil, want{http.StatusTeapot, "https://APP.example.com", false}},
+		{"exact origin, other scheme", http.MethodGet, "http://app.example.com", nil, want{status: http.StatusTeapot}},
+		{"exact origin, other port", http.MethodGet, "https://app.example.com:8443", nil, want{status: http.StatusTeapot}},
+		{"subdomain wildcard", http.MethodGet, "https://tenant.example.org", nil, want{http.StatusTeapot, "https://tenant.example.org", false}},
+		{"nested subdomain wildcard", http.MethodGet, "https://a.b.example.org", nil, want{http.StatusTeapot, "https://a.b.example.org", false}},
+		{"wildcard excludes the apex", http.MethodGet, "https://example.org", nil, want{status: http.StatusTeapot}},
+		{"wildcard excludes lookalike domains", http.MethodGet, "https://evil-example.org", nil, want{status: http.StatusTeapot}},
+		{"wildcard excludes suffixed domains", http.MethodGet, "https://tenant.example.org.evil.com", nil, want{status: http.StatusTeapot}},
+		{"wildcard keeps the scheme", http.MethodGet, "http://tenant.example.org", nil, want{status: http.StatusTeapot}},
+		{"wildcard needs a label", http.MethodGet, "https://.example.org", nil, want{status: http.StatusTeapot}},
+		{"unknown origin", http.MethodGet, "https://evil.com", nil, want{status: http.StatusTeapot}},
+		{
+			"preflight",
+			http.MethodOptions, "https://app.example.com",
+			map[string]string{"Access-Control-Request-Method": "DELETE", "Access-Control-Request-Headers": "authorization, content-type"},
+			want{http.StatusNoContent, "https://app.example.com", true},
+		},
+		{
+			"preflight without headers",
+			http.MethodOptions, "https://tenant.example.org",
+			map[string]string{"Access-Control-Request-Method": "POST"},
+			want{http.StatusNoContent, "https://tenant.example.org", true},
+		},
+		{
+			"preflight for a method that isn't allowed",
+			http.MethodOptions, "https://app.example.com",
+			map[string]string{"Access-Control-Request-Method": "PUT"},
+			want{status: http.StatusForbidden},
+		},
+		{
+			"preflight for a header that isn't allowed",
+			http.MethodOptions, "https://app.example.com",
+			map[string]string{"Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "content-type, x-admin"},
+			want{status: http.StatusForbidden},
+		},
+		{
+			"preflight from an unknown origin",
+			http.MethodOptions, "https://evil.com",
+			map[string]string{"Access-Control-Request-Method": "POST"},
+			want{status: http.StatusTeapot},
+		},
+		{"plain OPTIONS request", http.MethodOptions, "https://app.example.com", nil, want{http.StatusTeapot, "https://app.example.com", false}},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/v1/me", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		h := rec.Header()
+
+		if rec.Code != tt.want.status {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want.status)
+		}
+		// Responses differ by origin, so caches must key on it.
+		if !slices.Contains(h.Values("Vary"), "Origin") {
+			t.Errorf("%s: Vary = %v", tt.name, h.Values("Vary"))
+		}
+		if got := h.Get("Access-Control-Allow-Origin"); got != tt.want.allowOrigin {
+			t.Errorf("%s: Allow-Origin %q, want %q", tt.name, got, tt.want.allowOrigin)
+		}
+		if tt.want.allowOrigin == "" {
+			for _, name := range []string{"Access-Control-Allow-Credentials", "Access-Control-Allow-Methods", "Access-Control-Allow-Headers", "Access-Control-Expose-Headers"} {
+				if v := h.Get(name); v != "" {
+					t.Errorf("%s: %s = %q for a disallowed request", tt.name, name, v)
+				}
+			}
+			continue
+		}
+		if h.Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("%s: credentials not allowed", tt.name)
+		}
+		if tt.want.preflight {
+			if h.Get("Access-Control-Allow-Methods") != "GET, POST, DELETE" || h.Get("Access-Control-Allow-Headers") != "Authorization, Content-Type" || h.Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("%s: preflight headers %v", tt.name, h)
+			}
+		} else if h.Get("Access-Control-Expose-Headers") != RequestIDHeader {
+			t.Errorf("%s: Expose-Headers = %q", tt.name, h.Get("Access-Control-Expose-Headers"))
+		}
+	}
+}
+
+func TestCORSAnyOriginWithoutCredentials(t *testing.T) {
+	handler := CORS(CORSConfig{AllowedOrigins: []string{"*"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", "https://anywhere.example")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://anywhere.example" {
+		t.Errorf("Allow-Origin = %q", rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Error("credentials allowed without AllowCredentials")
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+// CSRF rejects requests that carry the refresh token cookie without echoing
+// its CSRF token. Requests without the cookie have no ambient credentials
+// to abuse and pass through.
+func CSRF(cookies *helpers.Cookies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := cookies.RefreshToken(r); ok && !cookies.ValidCSRF(r) {
+				http.Error(w, "invalid csrf token", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
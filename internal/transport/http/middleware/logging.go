@@ -1,17 +1,132 @@
 package middleware
 
 import (
+	"context"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/razedwell/go-hand/internal/platform/logger"
 )
 
-// LoggingMiddleware logs the details of each HTTP request.
+const requestInfoKey ctxKey = "request_info"
+
+// requestInfo collects what inner handlers learn about a request, such as
+// the matched route and the authenticated user, for the access log and
+// metrics recorded afterwards.
+type requestInfo struct {
+	route  string
+	userID int64
+}
+
+// withRequestInfo returns the request's requestInfo, adding one to its
+// context if an outer middleware has not already.
+func withRequestInfo(r *http.Request) (*http.Request, *requestInfo) {
+	if info, ok := r.Context().Value(requestInfoKey).(*requestInfo); ok {
+		return r, info
+	}
+	info := &requestInfo{}
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey, info)), info
+}
+
+// Route records the pattern a request matched; the router wraps every
+// route in it.
+func Route(pattern string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if info, ok := r.Context().Value(requestInfoKey).(*requestInfo); ok {
+				info.route = pattern
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LoggingMiddleware writes one access log record per request with the
+// status, response size, duration, client IP, user and request ID. The
+// query string is left out since it can carry tokens.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		logger.Log.Printf("%s %s %s", r.Method, r.RequestURI, time.Since(start))
+		rw := wrapResponseWriter(w)
+		r, info := withRequestInfo(r)
+
+		next.ServeHTTP(rw, r)
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rw.status),
+			slog.Int64("bytes", rw.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("ip", clientIP(r)),
+		}
+		if info.route != "" {
+			attrs = append(attrs, slog.String("route", info.route))
+		}
+		if info.userID != 0 {
+			attrs = append(attrs, slog.Int64("user_id", info.userID))
+		}
+		logger.Log.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
 	})
 }
+
+// logUser records the authenticated user for the access log.
+func logUser(ctx context.Context, userID int64) {
+	if info, ok := ctx.Value(requestInfoKey).(*requestInfo); ok {
+		info.userID = userID
+	}
+}
+
+// clientIP is the peer address; proxy headers are not trusted.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// wrapResponseWriter returns w if an outer middleware already wraps it.
+func wrapResponseWriter(w http.ResponseWriter) *responseWriter {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// responseWriter records the status and the number of body bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// 1xx responses are followed by the real one.
+	if !w.wroteHeader && status >= 200 {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach Flush, deadlines and hijacking
+// on the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+)
+
+// captureLogs points logger.Log at a buffer for the rest of the test and
+// returns a function that decodes the records written so far.
+func captureLogs(t *testing.T) func() []map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	saved := logger.Log
+	logger.Log = logger.New(&buf, logger.Config{Format: "json"})
+	t.Cleanup(func() { logger.Log = saved })
+
+	return func() []map[string]any {
+		var records []map[string]any
+		scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			var record map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatalf("bad log line %q: %v", scanner.Text(), err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+// accessLog returns the single access log record.
+func accessLog(t *testing.T, records []map[string]any) map[string]any {
+	t.Helper()
+	var found []map[string]any
+	for _, record := range records {
+		if record["msg"] == "request" {
+			found = append(found, record)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected one access log record, got %v", records)
+	}
+	return found[0]
+}
+
+func TestLoggingRecordsStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		bytes   int
+	}{
+		{"nothing written", func(w http.ResponseWriter, r *http.Request) {}, http.StatusOK, 0},
+		{"body only", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) }, http.StatusOK, 5},
+		{
+			"explicit status",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("{}"))
+			},
+			http.StatusCreated, 2,
+		},
+		{
+			"status after the body is ignored",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			http.StatusOK, 2,
+		},
+		{
+			"informational response first",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusNoContent)
+			},
+			http.StatusNoContent, 0,
+		},
+	}
+	for _, tt := range tests {
+		logs := captureLogs(t)
+		handler := LoggingMiddleware(tt.handler)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/me?token=secret", nil))
+
+		record := accessLog(t, logs())
+		if record["status"] != float64(tt.status) || record["bytes"] != float64(tt.bytes) {
+			t.Errorf("%s: status %v, bytes %v; want %d, %d", tt.name, record["status"], record["bytes"], tt.status, tt.bytes)
+		}
+		if record["path"] != "/v1/me" {
+			t.Errorf("%s: path %v; the query string must not be logged", tt.name, record["path"])
+		}
+	}
+}
+
+func TestLoggingRecordsRouteAndUser(t *testing.T) {
+	logs := captureLogs(t)
+	handler := LoggingMiddleware(Route("/v1/users/{id}")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logUser(r.Context(), 42)
+	})))
+	r := httptest.NewRequest(http.MethodGet, "/v1/users/42", nil)
+	r.RemoteAddr = "192.0.2.1:54321"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	record := accessLog(t, logs())
+	if record["route"] != "/v1/users/{id}" || record["user_id"] != float64(42) || record["ip"] != "192.0.2.1" {
+		t.Fatalf("record = %v", record)
+	}
+}
+
+func TestResponseWriterPassesThroughFlushAndUnwrap(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var rw *responseWriter
+	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw = w.(*responseWriter)
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("wrapper hides http.Flusher")
+		}
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush through ResponseController: %v", err)
+		}
+	}))
+	captureLogs(t)
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if !rec.Flushed {
+		t.Error("flush did not reach the underlying writer")
+	}
+	if rw.Unwrap() != rec {
+		t.Error("Unwrap does not return the underlying writer")
+	}
+	if !rw.wroteHeader {
+		t.Error("a flush sends the headers")
+	}
+}
+
+func TestWrapResponseWriterOnce(t *testing.T) {
+	rw := wrapResponseWriter(httptest.NewRecorder())
+	if wrapResponseWriter(rw) != rw {
+		t.Fatal("an already wrapped writer was wrapped again")
+	}
+}
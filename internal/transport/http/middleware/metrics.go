@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/razedwell/go-hand/internal/platform/metrics"
+)
+
+// Metrics counts requests and observes their latency, labelled with the
+// matched route pattern rather than the raw path to bound cardinality.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := wrapResponseWriter(w)
+		r, info := withRequestInfo(r)
+
+		next.ServeHTTP(rw, r)
+
+		route := info.route
+		if route == "" {
+			route = metrics.Unmatched
+		}
+		metrics.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(rw.status)).Inc()
+		metrics.HTTPDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
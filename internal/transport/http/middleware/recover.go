@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+// Recover turns a handler panic into a 500 problem response and logs the
+// stack. http.ErrAbortHandler is re-panicked so net/http aborts quietly.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			logger.Log.ErrorContext(r.Context(), "Panic serving request",
+				"method", r.Method, "path", r.URL.Path, "panic", p, "stack", string(debug.Stack()))
+
+			// Once the headers are out, the status cannot change; the
+			// client sees a truncated response instead.
+			if rw, ok := w.(*responseWriter); ok && rw.wroteHeader {
+				return
+			}
+			helpers.RespondWithProblem(w, helpers.Problem{
+				Status:    http.StatusInternalServerError,
+				RequestID: RequestIDFrom(r.Context()),
+			})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
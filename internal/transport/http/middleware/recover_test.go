@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/razedwell/go-hand/internal/transport/http/helpers"
+)
+
+// serveChain runs handler behind the server's request ID, logging and
+// recover middleware.
+func serveChain(handler http.HandlerFunc) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	Chain(RequestID, LoggingMiddleware, Recover)(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/login", nil))
+	return rec
+}
+
+// panicLog returns the single record of a recovered panic.
+func panicLog(t *testing.T, records []map[string]any) map[string]any {
+	t.Helper()
+	var found []map[string]any
+	for _, record := range records {
+		if record["msg"] == "Panic serving request" {
+			found = append(found, record)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected one panic record, got %v", records)
+	}
+	return found[0]
+}
+
+func TestRecoverRespondsWithProblem(t *testing.T) {
+	logs := captureLogs(t)
+	rec := serveChain(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Partial", "1")
+		panic("boom")
+	})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	var problem helpers.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	id := rec.Header().Get(RequestIDHeader)
+	if problem.Status != http.StatusInternalServerError || problem.Title != "Internal Server Error" || problem.RequestID != id || id == "" {
+		t.Fatalf("problem = %+v, request id %q", problem, id)
+	}
+	// Nothing about the panic reaches the client.
+	if strings.Contains(rec.Body.String(), "boom") {
+		t.Fatalf("body leaks the panic: %s", rec.Body)
+	}
+
+	records := logs()
+	record := panicLog(t, records)
+	if record["level"] != "ERROR" || record["panic"] != "boom" || record["request_id"] != id || record["path"] != "/v1/login" {
+		t.Fatalf("panic record = %v", record)
+	}
+	if stack, _ := record["stack"].(string); !strings.Contains(stack, "TestRecoverRespondsWithProblem") {
+		t.Fatalf("stack does not reach the handler: %q", stack)
+	}
+	if status := accessLog(t, records)["status"]; status != float64(http.StatusInternalServerError) {
+		t.Fatalf("access log status %v", status)
+	}
+}
+
+func TestRecoverAfterHeadersSent(t *testing.T) {
+	logs := captureLogs(t)
+	rec := serveChain(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"partial":`))
+		panic("boom")
+	})
+
+	// The status is already out; the client gets a truncated body rather
+	// than a problem appended to it.
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"partial":` {
+		t.Fatalf("status %d, body %q", rec.Code, rec.Body)
+	}
+	panicLog(t, logs())
+}
+
+func TestRecoverRepanicsAbortHandler(t *testing.T) {
+	logs := captureLogs(t)
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want http.ErrAbortHandler", p)
+		}
+		for _, record := range logs() {
+			if record["msg"] == "Panic serving request" {
+				t.Fatal("an aborted request was logged as a panic")
+			}
+		}
+	}()
+	Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
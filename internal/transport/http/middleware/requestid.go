@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+	"github.com/razedwell/go-hand/internal/security"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	RequestIDKey ctxKey = "request_id"
+)
+
+// RequestID takes the caller's X-Request-ID, or generates one, echoes it in
+// the response and stores it in the request context, where it is added to
+// every log record written with that context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			var err error
+			if id, err = security.RandomToken(12); err != nil {
+				logger.Log.ErrorContext(r.Context(), "Failed to generate request id", "error", err)
+				id = ""
+			}
+		}
+		if id != "" {
+			w.Header().Set(RequestIDHeader, id)
+		}
+		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		ctx = logger.With(ctx, "request_id", id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFrom returns the request ID stored by RequestID.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// validRequestID accepts short IDs of printable ASCII, so a caller cannot
+// inject line breaks or megabytes into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
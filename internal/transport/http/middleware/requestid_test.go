@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/razedwell/go-hand/internal/platform/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		echoed   bool // false: a fresh ID replaces it
+	}{
+		{"valid", "req-123_abc.DEF", true},
+		{"longest allowed", strings.Repeat("a", 128), true},
+		{"missing", "", false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"space", "req 123", false},
+		{"line break", "req\r\nX-Injected: 1", false},
+		{"control character", "req\x00", false},
+		{"non-ASCII", "réq", false},
+	}
+	for _, tt := range tests {
+		logs := captureLogs(t)
+		var fromContext string
+		handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fromContext = RequestIDFrom(r.Context())
+			logger.Log.InfoContext(r.Context(), "handled")
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.incoming != "" {
+			r.Header[http.CanonicalHeaderKey(RequestIDHeader)] = []string{tt.incoming} // unvalidated, as it arrives
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+
+		id := rec.Header().Get(RequestIDHeader)
+		if tt.echoed && id != tt.incoming {
+			t.Errorf("%s: echoed %q, want %q", tt.name, id, tt.incoming)
+		}
+		if !tt.echoed && (id == tt.incoming || !validRequestID(id)) {
+			t.Errorf("%s: got %q, want a fresh ID", tt.name, id)
+		}
+		if fromContext != id {
+			t.Errorf("%s: context has %q, response %q", tt.name, fromContext, id)
+		}
+		if records := logs(); len(records) != 1 || records[0]["request_id"] != id {
+			t.Errorf("%s: log records %v", tt.name, records)
+		}
+	}
+}
+
+func TestRequestIDsAreUnique(t *testing.T) {
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		id := rec.Header().Get(RequestIDHeader)
+		if seen[id] {
+			t.Fatalf("duplicate request id %q", id)
+		}
+		seen[id] = true
+	}
+}
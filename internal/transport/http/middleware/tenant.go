@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/razedwell/go-hand/internal/repository/organization"
+	"github.com/razedwell/go-hand/internal/tenant"
+)
+
+// Tenant resolves the active organization from the access token claims and
+// stores it in the request context for org-scoped repositories. It must run
+// after Auth. Membership is re-checked on every request, so removing a member
+// takes effect before their token expires.
+func Tenant(orgs organization.Repository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := Claims(r.Context())
+			if !ok {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if claims.OrgID == 0 {
+				http.Error(w, "no active organization", http.StatusForbidden)
+				return
+			}
+
+			m, err := orgs.FindMembership(r.Context(), claims.OrgID, claims.UserID)
+			if err != nil {
+				http.Error(w, "not a member of this organization", http.StatusForbidden)
+				return
+			}
+
+			ctx := tenant.WithTenant(r.Context(), tenant.Tenant{
+				OrgID:  m.OrgID,
+				UserID: m.UserID,
+				Role:   m.Role,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/razedwell/go-hand/internal/transport/http/middleware"
+)
+
+// Router registers routes on a ServeMux under a path prefix and wraps them
+// in the middleware of every group they were registered through.
+type Router struct {
+	mux         *http.ServeMux
+	prefix      string
+	middlewares []func(http.Handler) http.Handler
+	routes      *routeTable
+}
+
+// Route is a registered route. Naming it lets callers build its path with
+// Router.Path.
+type Route struct {
+	Name    string
+	Method  string // empty for routes matching any method
+	Pattern string // full path pattern, including group prefixes
+
+	routes *routeTable
+}
+
+type routeTable struct {
+	all   []*Route
+	named map[string]*Route
+}
+
+func NewRouter(registrars ...RouteRegistrar) *Router {
+	r := &Router{
+		mux:    http.NewServeMux(),
+		routes: &routeTable{named: map[string]*Route{}},
+	}
+	for _, registrar := range registrars {
+		registrar.RegisterRoutes(r)
+	}
+	return r
+}
+
+// Group returns a router whose routes live under prefix and run
+// middlewares after the ones of r. The first middleware is the outermost.
+func (r *Router) Group(prefix string, middlewares ...func(http.Handler) http.Handler) *Router {
+	return &Router{
+		mux:         r.mux,
+		prefix:      r.prefix + strings.TrimSuffix(prefix, "/"),
+		middlewares: append(append([]func(http.Handler) http.Handler{}, r.middlewares...), middlewares...),
+		routes:      r.routes,
+	}
+}
+
+// With is Group without a prefix, for middleware that only some of a
+// handler's routes need.
+func (r *Router) With(middlewares ...func(http.Handler) http.Handler) *Router {
+	return r.Group("", middlewares...)
+}
+
+// Handle registers handler for a ServeMux pattern such as "POST /login",
+// relative to the router's prefix. Like ServeMux, it panics on conflicts.
+func (r *Router) Handle(pattern string, handler http.Handler) *Route {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	route := &Route{Method: method, Pattern: r.prefix + path, routes: r.routes}
+
+	full := route.Pattern
+	if method != "" {
+		full = method + " " + full
+	}
+	// The route is recorded first so logs and metrics see it even when
+	// group middleware rejects the request.
+	r.mux.Handle(full, middleware.Chain(append([]func(http.Handler) http.Handler{middleware.Route(route.Pattern)}, r.middlewares...)...)(handler))
+	r.routes.all = append(r.routes.all, route)
+	return route
+}
+
+func (r *Router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	return r.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// Named sets the route's name, which must be unique across the router.
+func (rt *Route) Named(name string) *Route {
+	if _, ok := rt.routes.named[name]; ok {
+		panic(fmt.Sprintf("http: route name %q registered twice", name))
+	}
+	rt.Name = name
+	rt.routes.named[name] = rt
+	return rt
+}
+
+// Path builds the path of a named route, filling its wildcards from
+// key/value pairs: Path("admin.ban", "id", "42").
+func (r *Router) Path(name string, params ...string) (string, error) {
+	route, ok := r.routes.named[name]
+	if !ok {
+		return "", fmt.Errorf("unknown route %q", name)
+	}
+	path := strings.TrimSuffix(route.Pattern, "{$}")
+	for i := 0; i+1 < len(params); i += 2 {
+		path = strings.Replace(path, "{"+params[i]+"}", params[i+1], 1)
+		path = strings.Replace(path, "{"+params[i]+"...}", params[i+1], 1)
+	}
+	if strings.Contains(path, "{") {
+		return "", fmt.Errorf("missing parameters for route %q", name)
+	}
+	return path, nil
+}
+
+// Routes lists the registered routes ordered by pattern, for debugging.
+func (r *Router) Routes() []Route {
+	routes := make([]Route, 0, len(r.routes.all))
+	for _, route := range r.routes.all {
+		routes = append(routes, *route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
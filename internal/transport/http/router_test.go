@@ -0,0 +1,161 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// registrarFunc adapts a function to RouteRegistrar.
+type registrarFunc func(r *Router)
+
+func (f registrarFunc) RegisterRoutes(r *Router) { f(r) }
+
+// tag appends name to the X-Trace response header, so a response shows
+// which middleware ran, in order.
+func tag(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ok(w http.ResponseWriter, r *http.Request) {}
+
+func serve(router *Router, method string, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestMountedRoutesReportFullPattern(t *testing.T) {
+	var me, ban *Route
+	router := NewRouter(Mount("/v1", nil, registrarFunc(func(r *Router) {
+		me = r.HandleFunc("GET /me", ok).Named("me")
+		ban = r.Group("/admin/").HandleFunc("POST /users/{id}/ban", ok).Named("admin.ban")
+	})))
+
+	if me.Pattern != "/v1/me" || me.Method != http.MethodGet {
+		t.Errorf("me = %+v", me)
+	}
+	if ban.Pattern != "/v1/admin/users/{id}/ban" || ban.Method != http.MethodPost {
+		t.Errorf("ban = %+v", ban)
+	}
+	if rec := serve(router, http.MethodPost, "/v1/admin/users/42/ban"); rec.Code != http.StatusOK {
+		t.Errorf("POST /v1/admin/users/42/ban: status %d", rec.Code)
+	}
+	if rec := serve(router, http.MethodGet, "/me"); rec.Code != http.StatusNotFound {
+		t.Errorf("unprefixed path: status %d", rec.Code)
+	}
+
+	tests := []struct {
+		name   string
+		params []string
+		want   string
+	}{
+		{"me", nil, "/v1/me"},
+		{"admin.ban", []string{"id", "42"}, "/v1/admin/users/42/ban"},
+	}
+	for _, tt := range tests {
+		// Any router sharing the table can build paths, including the root.
+		got, err := router.Path(tt.name, tt.params...)
+		if err != nil || got != tt.want {
+			t.Errorf("Path(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	router := NewRouter()
+	router.HandleFunc("GET /files/{path...}", ok).Named("file")
+	router.HandleFunc("GET /{$}", ok).Named("home")
+	router.HandleFunc("GET /orgs/{org}/members/{user}", ok).Named("member")
+
+	tests := []struct {
+		name    string
+		params  []string
+		want    string
+		wantErr bool
+	}{
+		{"file", []string{"path", "a/b.txt"}, "/files/a/b.txt", false},
+		{"home", nil, "/", false},
+		{"member", []string{"user", "7", "org", "3"}, "/orgs/3/members/7", false},
+		{"member", []string{"org", "3"}, "", true},
+		{"member", []string{"org"}, "", true},
+		{"unknown", nil, "", true},
+	}
+	for _, tt := range tests {
+		got, err := router.Path(tt.name, tt.params...)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Path(%q, %v) = %q, %v", tt.name, tt.params, got, err)
+		}
+	}
+}
+
+func TestNamedTwicePanics(t *testing.T) {
+	router := NewRouter()
+	router.HandleFunc("GET /a", ok).Named("dup")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	router.Group("/v2").HandleFunc("GET /b", ok).Named("dup")
+}
+
+func TestGroupMiddleware(t *testing.T) {
+	router := NewRouter(
+		Mount("/v1", []func(http.Handler) http.Handler{tag("mount")}, registrarFunc(func(r *Router) {
+			r.HandleFunc("GET /public", ok)
+			r.With(tag("auth")).HandleFunc("GET /private", ok)
+			admin := r.Group("/admin", tag("admin"), tag("role"))
+			admin.HandleFunc("GET /users", ok)
+			admin.With(tag("audit")).HandleFunc("POST /users", ok)
+		})),
+		registrarFunc(func(r *Router) { r.HandleFunc("GET /health", ok) }),
+	)
+
+	tests := []struct {
+		method, path string
+		want         []string
+	}{
+		{http.MethodGet, "/v1/public", []string{"mount"}},
+		// With applies only to the routes registered through it...
+		{http.MethodGet, "/v1/private", []string{"mount", "auth"}},
+		// ...and groups nest, outermost first.
+		{http.MethodGet, "/v1/admin/users", []string{"mount", "admin", "role"}},
+		{http.MethodPost, "/v1/admin/users", []string{"mount", "admin", "role", "audit"}},
+		// Nothing leaks outside the mount.
+		{http.MethodGet, "/health", nil},
+	}
+	for _, tt := range tests {
+		rec := serve(router, tt.method, tt.path)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s %s: status %d", tt.method, tt.path, rec.Code)
+		}
+		if got := rec.Header().Values("X-Trace"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %s: middleware %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	router := NewRouter(Mount("/v1", nil, registrarFunc(func(r *Router) {
+		r.HandleFunc("POST /login", ok)
+		r.HandleFunc("GET /login", ok)
+		r.Group("/admin").HandleFunc("/users", ok)
+	})))
+
+	var got []string
+	for _, route := range router.Routes() {
+		got = append(got, strings.TrimSpace(route.Method+" "+route.Pattern))
+	}
+	want := []string{"/v1/admin/users", "GET /v1/login", "POST /v1/login"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
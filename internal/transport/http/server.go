@@ -2,25 +2,60 @@ package http
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/razedwell/go-hand/internal/transport/http/middleware"
 )
 
 type RouteRegistrar interface {
-	RegisterRoutes(mux *http.ServeMux)
+	RegisterRoutes(r *Router)
 }
 
-func NewServer(addr string, registrar ...RouteRegistrar) *http.Server {
-	mux := http.NewServeMux()
+// Mount registers the registrars' routes under prefix, wrapped in
+// middlewares on top of the server-wide middleware.
+func Mount(prefix string, middlewares []func(http.Handler) http.Handler, registrars ...RouteRegistrar) RouteRegistrar {
+	return &mount{prefix: prefix, middlewares: middlewares, registrars: registrars}
+}
+
+type mount struct {
+	prefix      string
+	middlewares []func(http.Handler) http.Handler
+	registrars  []RouteRegistrar
+}
 
-	for _, r := range registrar {
-		r.RegisterRoutes(mux)
+func (m *mount) RegisterRoutes(r *Router) {
+	group := r.Group(m.prefix, m.middlewares...)
+	for _, registrar := range m.registrars {
+		registrar.RegisterRoutes(group)
 	}
+}
+
+type ServerConfig struct {
+	Addr              string
+	ReadTimeout       time.Duration // whole request, including the body
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration // keep-alive connections
+}
 
-	handler := middleware.LoggingMiddleware(mux)
+// NewServer serves the router. middlewares wrap all of it, so they also see
+// requests that match no route, such as CORS preflights.
+func NewServer(cfg ServerConfig, router *Router, middlewares []func(http.Handler) http.Handler) *http.Server {
+	handler := middleware.Chain(
+		middleware.RequestID,
+		middleware.Tracing,
+		middleware.LoggingMiddleware,
+		middleware.Metrics,
+		middleware.Recover,
+		middleware.AuditRequest,
+	)(middleware.Chain(middlewares...)(router))
 
 	return &http.Server{
-		Addr:    addr,
-		Handler: handler,
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
 }
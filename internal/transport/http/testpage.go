@@ -0,0 +1,17 @@
+package http
+
+import "net/http"
+
+// TestPage serves index.html, a small browser client for the auth routes,
+// at /test.
+var TestPage RouteRegistrar = testPage{}
+
+type testPage struct{}
+
+func (testPage) RegisterRoutes(r *Router) {
+	r.HandleFunc("GET /test", func(w http.ResponseWriter, r *http.Request) {
+		// The test page uses inline script and style.
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; frame-ancestors 'none'")
+		http.ServeFile(w, r, "internal/transport/http/index.html")
+	}).Named("test")
+}
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS update_groups_updated_at ON groups;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
@@ -0,0 +1,15 @@
+-- One-time codes for email/phone verification, password reset and passwordless login
+CREATE TABLE IF NOT EXISTS verification_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(255) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    binding_hash VARCHAR(255),
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_verification_codes_code_hash ON verification_codes(code_hash);
+CREATE INDEX idx_verification_codes_user_type ON verification_codes(user_id, type) WHERE used_at IS NULL;
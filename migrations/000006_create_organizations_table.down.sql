@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS update_organizations_updated_at ON organizations;
+DROP TABLE IF EXISTS org_memberships;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,27 @@
+-- Organizations (tenants) and per-organization memberships
+CREATE TABLE IF NOT EXISTS organizations (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT slug_format CHECK (slug ~ '^[a-z0-9][a-z0-9-]*$')
+);
+
+CREATE TABLE IF NOT EXISTS org_memberships (
+    org_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(32) NOT NULL DEFAULT 'member',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id),
+
+    CONSTRAINT org_role_valid CHECK (role IN ('owner', 'admin', 'member'))
+);
+
+CREATE INDEX idx_org_memberships_user_id ON org_memberships(user_id);
+
+CREATE TRIGGER update_organizations_updated_at
+    BEFORE UPDATE ON organizations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,26 @@
+-- Hash chaining for audit_events, in per-day segments
+ALTER TABLE audit_events
+    ADD COLUMN segment DATE DEFAULT NULL,
+    ADD COLUMN seq BIGINT DEFAULT NULL,
+    ADD COLUMN prev_hash VARCHAR(64) DEFAULT NULL,
+    ADD COLUMN hash VARCHAR(64) DEFAULT NULL;
+
+CREATE UNIQUE INDEX idx_audit_events_segment_seq ON audit_events(segment, seq);
+
+-- Signed segment heads
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id BIGSERIAL PRIMARY KEY,
+    segment DATE NOT NULL,
+    seq BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    key_id VARCHAR(64) NOT NULL,
+    signature VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (segment, seq)
+);
+
+CREATE TRIGGER audit_checkpoints_append_only
+    BEFORE UPDATE OR DELETE ON audit_checkpoints
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_audit_event_change();
@@ -0,0 +1,27 @@
+-- Transactional outbox for domain events
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    subject_type VARCHAR(32) DEFAULT NULL,
+    subject_id VARCHAR(255) DEFAULT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT DEFAULT NULL,
+    dispatched_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+);
+
+CREATE INDEX idx_outbox_pending ON outbox(next_attempt_at) WHERE dispatched_at IS NULL;
+
+-- Events each subscriber has already handled, for idempotent handlers
+CREATE TABLE IF NOT EXISTS processed_events (
+    handler VARCHAR(64) NOT NULL,
+    event_id BIGINT NOT NULL,
+    processed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (handler, event_id)
+);
+
+-- Webhook deliveries are keyed on the outbox event, so redispatching an
+-- event doesn't queue it twice.
+CREATE UNIQUE INDEX idx_webhook_deliveries_event ON webhook_deliveries(subscription_id, event_id);
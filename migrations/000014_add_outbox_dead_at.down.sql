@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX idx_outbox_pending ON outbox(next_attempt_at) WHERE dispatched_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS dead_at;
@@ -0,0 +1,5 @@
+-- Events that failed every attempt stop being retried and stay for inspection
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX idx_outbox_pending ON outbox(next_attempt_at) WHERE dispatched_at IS NULL AND dead_at IS NULL;